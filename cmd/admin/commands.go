@@ -0,0 +1,254 @@
+package main
+
+import (
+	"CloudStorageProject-FileServer/internal/provisioning"
+	"CloudStorageProject-FileServer/pkg/models"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func runCreate(adm *admin, args []string) error {
+	flags := subcommand(usageCreate)
+	email := flags.String("email", "", "account owner email")
+	permissions := flags.String("permissions", models.DefaultPermissions, "read or read,write")
+	quota := flags.Int64("quota", 0, "storage quota in MB, 0 - BUCKET_QUOTA_MB")
+	_ = flags.Parse(args)
+	if *email == "" || flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	account, err := adm.provisioning.Create(*email, *permissions, *quota)
+	if err != nil && account == nil {
+		return err
+	}
+	// аккаунт создан, ключ выводим в любом случае - второй раз его не показать
+	printAccount(account)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "admin: warning: account storage is not fully set up:", err)
+	}
+	return nil
+}
+
+func runList(adm *admin, args []string) error {
+	flags := subcommand(usageList)
+	status := flags.String("status", "", "only accounts with this status")
+	email := flags.String("email", "", "only accounts whose email contains this substring")
+	_ = flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	accounts, err := adm.postgres.Accounts(models.AccountFilter{Status: *status, Email: *email})
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKEY\tEMAIL\tSTATUS\tPERMISSIONS\tQUOTA\tSTORAGE\tLAST LOGIN")
+	for _, account := range accounts {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", account.Id, keyPrefix(&account), account.Email,
+			account.Status, account.Permissions, formatQuota(account.QuotaMB), account.StorageId,
+			account.LastLogin.Format(time.DateTime))
+	}
+	return w.Flush()
+}
+
+func runRevoke(adm *admin, args []string) error {
+	flags := subcommand(usageRevoke)
+	_ = flags.Parse(args)
+	id, err := accountId(flags, 1)
+	if err != nil {
+		return err
+	}
+	account, err := adm.provisioning.Revoke(id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("account %d revoked\n", account.Id)
+	return nil
+}
+
+func runSuspend(adm *admin, args []string) error {
+	flags := subcommand(usageSuspend)
+	_ = flags.Parse(args)
+	id, err := accountId(flags, 1)
+	if err != nil {
+		return err
+	}
+	account, err := adm.provisioning.Suspend(id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("account %d suspended\n", account.Id)
+	return nil
+}
+
+func runResume(adm *admin, args []string) error {
+	flags := subcommand(usageResume)
+	_ = flags.Parse(args)
+	id, err := accountId(flags, 1)
+	if err != nil {
+		return err
+	}
+	account, err := adm.provisioning.Resume(id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("account %d is active\n", account.Id)
+	return nil
+}
+
+func runRotate(adm *admin, args []string) error {
+	flags := subcommand(usageRotate)
+	_ = flags.Parse(args)
+	id, err := accountId(flags, 1)
+	if err != nil {
+		return err
+	}
+	account, err := adm.provisioning.Rotate(id)
+	if err != nil {
+		return err
+	}
+	printAccount(account)
+	return nil
+}
+
+func runQuota(adm *admin, args []string) error {
+	flags := subcommand(usageQuota)
+	_ = flags.Parse(args)
+	id, err := accountId(flags, 2)
+	if err != nil {
+		return err
+	}
+	var quota int64
+	if _, err = fmt.Sscan(flags.Arg(1), &quota); err != nil || quota < 0 {
+		return fmt.Errorf("invalid quota %q", flags.Arg(1))
+	}
+	account, err := adm.provisioning.SetQuota(id, quota)
+	if errors.Is(err, provisioning.ErrQuotaNotSupported) {
+		fmt.Fprintln(os.Stderr, "admin: warning:", err)
+	} else if err != nil {
+		return err
+	}
+	fmt.Printf("account %d quota: %s\n", account.Id, formatQuota(account.QuotaMB))
+	return nil
+}
+
+func runPermissions(adm *admin, args []string) error {
+	flags := subcommand(usagePermissions)
+	_ = flags.Parse(args)
+	id, err := accountId(flags, 2)
+	if err != nil {
+		return err
+	}
+	account, err := adm.provisioning.SetPermissions(id, flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("account %d permissions: %s\n", account.Id, account.Permissions)
+	return nil
+}
+
+func runUsage(adm *admin, args []string) error {
+	flags := subcommand(usageUsage)
+	_ = flags.Parse(args)
+	id, err := accountId(flags, 1)
+	if err != nil {
+		return err
+	}
+	usage, err := adm.provisioning.Usage(id)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "account:\t%d (%s)\n", usage.Account.Id, usage.Account.Email)
+	fmt.Fprintf(w, "status:\t%s\n", usage.Account.Status)
+	fmt.Fprintf(w, "files:\t%d, %s\n", usage.Files, formatSize(usage.Bytes))
+	fmt.Fprintf(w, "trash:\t%d, %s\n", usage.TrashFiles, formatSize(usage.TrashBytes))
+	fmt.Fprintf(w, "quota:\t%s\n", formatQuota(usage.Account.QuotaMB))
+	fmt.Fprintf(w, "last login:\t%s\n", usage.Account.LastLogin.Format(time.DateTime))
+	return w.Flush()
+}
+
+func runPurge(adm *admin, args []string) error {
+	flags := subcommand(usagePurge)
+	yes := flags.Bool("yes", false, "confirm that files and the account are deleted permanently")
+	_ = flags.Parse(args)
+	id, err := accountId(flags, 1)
+	if err != nil {
+		return err
+	}
+	if !*yes {
+		return errors.New("purge deletes all files of the account permanently, add -yes to confirm")
+	}
+	if err = adm.provisioning.Purge(id); err != nil {
+		return err
+	}
+	fmt.Printf("account %d purged\n", id)
+	return nil
+}
+
+func runHashKeys(adm *admin, args []string) error {
+	flags := subcommand(usageHashKeys)
+	_ = flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	cleared, err := adm.provisioning.ClearPlaintextKeys()
+	var legacy *provisioning.LegacyAccountsError
+	if errors.As(err, &legacy) {
+		// у таких аккаунтов значение ключа по-прежнему лежит в storage_id и в имени бакета
+		for _, id := range legacy.Ids {
+			fmt.Fprintf(os.Stderr, "admin: account %d storage is named after its api key\n", id)
+		}
+		fmt.Fprintln(os.Stderr, "admin: nothing cleared, run cmd/migrate-storage and then hash-keys again")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("plaintext keys cleared: %d\n", cleared)
+	return nil
+}
+
+// keyPrefix - префикс ключа для списка; у ключей старого формата префикса нет
+func keyPrefix(account *models.APIPGS) string {
+	if account.KeyPrefix == "" {
+		return "legacy"
+	}
+	return account.KeyPrefix
+}
+
+// printAccount - аккаунт после создания или смены ключа; ключ показывается целиком
+func printAccount(account *models.APIPGS) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "id:\t%d\n", account.Id)
+	fmt.Fprintf(w, "key:\t%s\n", account.Key)
+	fmt.Fprintf(w, "email:\t%s\n", account.Email)
+	fmt.Fprintf(w, "storage:\t%s\n", account.StorageId)
+	fmt.Fprintf(w, "permissions:\t%s\n", account.Permissions)
+	fmt.Fprintf(w, "quota:\t%s\n", formatQuota(account.QuotaMB))
+	_ = w.Flush()
+}
+
+func formatQuota(quotaMB int64) string {
+	if quotaMB == 0 {
+		return "default"
+	}
+	return formatSize(quotaMB * 1024 * 1024)
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exp])
+}
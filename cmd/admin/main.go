@@ -0,0 +1,168 @@
+package main
+
+import (
+	"CloudStorageProject-FileServer/internal/app"
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/metrics"
+	"CloudStorageProject-FileServer/internal/provisioning"
+	"CloudStorageProject-FileServer/pkg/config"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+Управление API-ключами и аккаунтами.
+
+	go run ./cmd/admin create -email user@example.com [-permissions read] [-quota 1024]
+	go run ./cmd/admin list [-status revoked] [-email example.com]
+	go run ./cmd/admin revoke <id>
+	go run ./cmd/admin suspend <id>             # resume <id> возвращает ключ в работу
+	go run ./cmd/admin rotate <id>              # меняет и ключи S3, SSH-ключи аккаунта удаляются
+	go run ./cmd/admin quota <id> <MB>          # 0 - квота по умолчанию BUCKET_QUOTA_MB
+	go run ./cmd/admin permissions <id> <read|read,write>
+	go run ./cmd/admin usage <id>
+	go run ./cmd/admin purge -yes <id>          # файлы, хранилище и аккаунт удаляются безвозвратно
+	go run ./cmd/admin hash-keys                # стирает открытые значения ключей старого формата,
+	                                            # работает только после cmd/migrate-storage
+
+Аккаунт указывается по id из list. Использует тот же .env, что и fileserver: Postgres, Redis
+и драйвер хранилища. Отзыв, смена ключа и прав сразу удаляют ключ из кэша Redis.
+*/
+
+// command - подкоманда admin
+type command struct {
+	usage string
+	run   func(adm *admin, args []string) error
+}
+
+// Строки использования подкоманд
+const (
+	usageCreate      = "create -email <email> [-permissions read,write] [-quota MB]"
+	usageList        = "list [-status active|suspended|revoked] [-email substring]"
+	usageRevoke      = "revoke <id>"
+	usageSuspend     = "suspend <id>"
+	usageResume      = "resume <id>"
+	usageRotate      = "rotate <id>"
+	usageQuota       = "quota <id> <MB>"
+	usagePermissions = "permissions <id> <read|read,write>"
+	usageUsage       = "usage <id>"
+	usagePurge       = "purge -yes <id>"
+	usageHashKeys    = "hash-keys"
+)
+
+var commands = map[string]command{
+	"create":      {usageCreate, runCreate},
+	"list":        {usageList, runList},
+	"revoke":      {usageRevoke, runRevoke},
+	"suspend":     {usageSuspend, runSuspend},
+	"resume":      {usageResume, runResume},
+	"rotate":      {usageRotate, runRotate},
+	"quota":       {usageQuota, runQuota},
+	"permissions": {usagePermissions, runPermissions},
+	"usage":       {usageUsage, runUsage},
+	"purge":       {usagePurge, runPurge},
+	"hash-keys":   {usageHashKeys, runHashKeys},
+}
+
+// order - порядок команд в справке
+var order = []string{
+	"create", "list", "revoke", "suspend", "resume", "rotate", "quota", "permissions", "usage", "purge",
+	"hash-keys",
+}
+
+// admin - зависимости команд
+type admin struct {
+	postgres     *postgres.Postgres
+	provisioning *provisioning.Provisioning
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "admin: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	conf, err := config.Load(config.ConfPath)
+	if err != nil {
+		fatal(err)
+	}
+	// вывод команд идет в stdout, лог - в stderr и только предупреждения
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	}))
+	ctx = context.WithValue(ctx, "logger", logger)
+	ctx = context.WithValue(ctx, "config", conf)
+
+	metric := metrics.NewCollector("CloudStorage")
+	st, err := app.NewStorage(ctx, conf, metric)
+	if err != nil {
+		fatal(fmt.Errorf("storage init error: %w", err))
+	}
+	pgs, err := postgres.InitPostgres(ctx, metric.Postgres)
+	if err != nil {
+		fatal(fmt.Errorf("postgres init error: %w", err))
+	}
+	rds, err := redis.NewRedis(ctx, metric.Redis)
+	if err != nil {
+		fatal(fmt.Errorf("redis init error: %w", err))
+	}
+
+	adm := &admin{postgres: pgs, provisioning: provisioning.NewProvisioning(ctx, pgs, rds, st)}
+	err = cmd.run(adm, os.Args[2:])
+	_ = rds.CloseConnection(ctx)
+	_ = pgs.CloseConnection(ctx)
+	_ = st.CloseConnection(ctx)
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: admin <command> [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, name := range order {
+		fmt.Fprintln(os.Stderr, "  "+commands[name].usage)
+	}
+}
+
+// subcommand - флаги подкоманды; usage - ее строка из справки, первое слово - имя
+func subcommand(usage string) *flag.FlagSet {
+	name, _, _ := strings.Cut(usage, " ")
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: admin "+usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// accountId - id аккаунта из первого аргумента; want - сколько всего аргументов ждет команда
+func accountId(flags *flag.FlagSet, want int) (int, error) {
+	if flags.NArg() != want {
+		flags.Usage()
+		os.Exit(2)
+	}
+	id, err := strconv.Atoi(flags.Arg(0))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid account id %q", flags.Arg(0))
+	}
+	return id, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "admin:", err)
+	os.Exit(1)
+}
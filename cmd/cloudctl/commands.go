@@ -0,0 +1,329 @@
+package main
+
+import (
+	"CloudStorageProject-FileServer/pkg/client"
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// localFile - локальный файл и ключ, под которым он лежит в хранилище
+type localFile struct {
+	path string
+	key  string
+	size int64
+}
+
+func runList(ctx context.Context, app *cli, args []string) error {
+	flags := subcommand(usageList)
+	recursive := flags.Bool("r", false, "list the whole subtree")
+	_ = flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	files, err := app.client.List(ctx, flags.Arg(0), *recursive)
+	if err != nil {
+		return err
+	}
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, file := range files {
+		if file.IsDir {
+			fmt.Fprintf(out, "%s\t%s\t%s\n", "-", "", file.Name)
+			continue
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\n", formatSize(file.Size), file.LastModified, file.Name)
+	}
+	return out.Flush()
+}
+
+func runPut(ctx context.Context, app *cli, args []string) error {
+	flags := subcommand(usagePut)
+	jobs := flags.Int("j", defaultJobs, "parallel uploads")
+	_ = flags.Parse(args)
+	sources, remote := flags.Args(), ""
+	if len(sources) > 1 {
+		sources, remote = sources[:len(sources)-1], sources[len(sources)-1]
+	}
+	if len(sources) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var files []localFile
+	var total int64
+	for _, source := range sources {
+		found, err := collect(source, remote, len(sources) == 1)
+		if err != nil {
+			return err
+		}
+		for _, file := range found {
+			total += file.size
+		}
+		files = append(files, found...)
+	}
+
+	bar := newProgress(app, "put", total, len(files))
+	err := parallel(ctx, bar, *jobs, len(files), func(i int) string { return files[i].path }, func(i int) error {
+		sum, err := hashFile(files[i].path)
+		if err != nil {
+			return err
+		}
+		return app.uploadFile(ctx, bar, files[i].path, files[i].key, func(r io.Reader, size int64) error {
+			_, errUpload := app.client.Upload(ctx, files[i].key, r, &client.UploadOptions{Size: size, Sha256: sum})
+			return errUpload
+		})
+	})
+	bar.finish()
+	return err
+}
+
+// collect - файлы для загрузки из source. Каталог загружается в remote/<имя каталога>/, файл - в remote/<имя файла>;
+// если источник один и remote не заканчивается на "/", remote - имя файла в хранилище
+func collect(source, remote string, single bool) ([]localFile, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	prefix := remote
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if !info.IsDir() {
+		key := prefix + filepath.Base(source)
+		if single && remote != "" && !strings.HasSuffix(remote, "/") {
+			key = remote
+		}
+		return []localFile{{path: source, key: key, size: info.Size()}}, nil
+	}
+	return walk(source, prefix+filepath.Base(filepath.Clean(source))+"/")
+}
+
+// walk - все обычные файлы каталога с ключами prefix + путь относительно root
+func walk(root, prefix string) ([]localFile, error) {
+	var files []localFile
+	err := filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		files = append(files, localFile{path: name, key: prefix + filepath.ToSlash(relative), size: info.Size()})
+		return nil
+	})
+	return files, err
+}
+
+func runGet(ctx context.Context, app *cli, args []string) error {
+	flags := subcommand(usageGet)
+	jobs := flags.Int("j", defaultJobs, "parallel downloads")
+	_ = flags.Parse(args)
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	remote, local := flags.Arg(0), flags.Arg(1)
+
+	var files []localFile
+	var total int64
+	if strings.HasSuffix(remote, "/") {
+		// каталог целиком, структура папок сохраняется
+		if local == "" {
+			local = "."
+		}
+		listed, err := app.client.List(ctx, remote, true)
+		if err != nil {
+			return err
+		}
+		for _, file := range listed {
+			if file.IsDir {
+				continue
+			}
+			target := filepath.Join(local, filepath.FromSlash(strings.TrimPrefix(file.Name, remote)))
+			files = append(files, localFile{path: target, key: file.Name, size: file.Size})
+			total += file.Size
+		}
+	} else {
+		info, err := app.client.Stat(ctx, remote)
+		if err != nil {
+			return err
+		}
+		if local == "" {
+			local = path.Base(remote)
+		} else if stat, errStat := os.Stat(local); errStat == nil && stat.IsDir() {
+			local = filepath.Join(local, path.Base(remote))
+		}
+		files = append(files, localFile{path: local, key: remote, size: info.Size})
+		total = info.Size
+	}
+
+	bar := newProgress(app, "get", total, len(files))
+	err := parallel(ctx, bar, *jobs, len(files), func(i int) string { return files[i].key }, func(i int) error {
+		if err := os.MkdirAll(filepath.Dir(files[i].path), 0o755); err != nil {
+			return err
+		}
+		return app.downloadFile(ctx, bar, files[i].key, files[i].path)
+	})
+	bar.finish()
+	return err
+}
+
+func runRemove(ctx context.Context, app *cli, args []string) error {
+	flags := subcommand(usageRemove)
+	recursive := flags.Bool("r", false, "remove every file under the prefix")
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	names := flags.Args()
+	if *recursive {
+		names = nil
+		for _, prefix := range flags.Args() {
+			if !strings.HasSuffix(prefix, "/") {
+				prefix += "/"
+			}
+			listed, err := app.client.List(ctx, prefix, true)
+			if err != nil {
+				return err
+			}
+			for _, file := range listed {
+				if !file.IsDir {
+					names = append(names, file.Name)
+				}
+			}
+		}
+	}
+	bar := newProgress(app, "rm", 0, len(names))
+	err := parallel(ctx, bar, defaultJobs, len(names), func(i int) string { return names[i] }, func(i int) error {
+		if errDelete := app.client.Delete(ctx, names[i]); errDelete != nil {
+			return errDelete
+		}
+		bar.fileDone()
+		return nil
+	})
+	bar.finish()
+	return err
+}
+
+func runMove(ctx context.Context, app *cli, args []string) error {
+	flags := subcommand(usageMove)
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	_, err := app.client.RenameFile(ctx, flags.Arg(0), flags.Arg(1))
+	return err
+}
+
+func runShare(ctx context.Context, app *cli, args []string) error {
+	flags := subcommand(usageShare)
+	expires := flags.Duration("expires", 0, "link lifetime, 24h by default, 168h at most")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	link, err := app.client.Share(ctx, flags.Arg(0), *expires)
+	if err != nil {
+		return err
+	}
+	fmt.Println(link.Url)
+	fmt.Fprintf(os.Stderr, "expires at %s\n", link.ExpiresAt.Local().Format(time.DateTime))
+	return nil
+}
+
+func runSync(ctx context.Context, app *cli, args []string) error {
+	flags := subcommand(usageSync)
+	jobs := flags.Int("j", defaultJobs, "parallel uploads")
+	remove := flags.Bool("delete", false, "remove files that are not in the local directory")
+	dryRun := flags.Bool("n", false, "only print what would be done")
+	_ = flags.Parse(args)
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	root := flags.Arg(0)
+	prefix := flags.Arg(1)
+	if prefix == "" {
+		prefix = filepath.Base(filepath.Clean(root))
+	}
+	prefix = strings.Trim(prefix, "/") + "/"
+
+	files, err := walk(root, "")
+	if err != nil {
+		return err
+	}
+	local := make(map[string]localFile, len(files))
+	request := models.SyncRequest{Prefix: prefix, Files: make([]models.ManifestEntry, 0, len(files)), Presign: !*dryRun}
+	for _, file := range files {
+		sum, errHash := hashFile(file.path)
+		if errHash != nil {
+			return errHash
+		}
+		local[file.key] = file
+		request.Files = append(request.Files, models.ManifestEntry{Path: file.key, Size: file.size, Sha256: sum})
+	}
+	plan, err := app.client.Sync(ctx, request)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		for _, item := range plan.Upload {
+			fmt.Printf("upload %s%s (%s)\n", prefix, item.Path, item.Reason)
+		}
+		if *remove {
+			for _, name := range plan.Delete {
+				fmt.Printf("delete %s%s\n", prefix, name)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%d to upload, %d unchanged, %d not in %s\n", len(plan.Upload), len(plan.Unchanged),
+			len(plan.Delete), root)
+		return nil
+	}
+
+	var total int64
+	for _, item := range plan.Upload {
+		total += local[item.Path].size
+	}
+	bar := newProgress(app, "sync", total, len(plan.Upload))
+	err = parallel(ctx, bar, *jobs, len(plan.Upload), func(i int) string { return plan.Upload[i].Path }, func(i int) error {
+		item := plan.Upload[i]
+		return app.uploadFile(ctx, bar, local[item.Path].path, item.Path, func(r io.Reader, size int64) error {
+			_, errUpload := app.client.UploadSigned(ctx, item, r, size)
+			return errUpload
+		})
+	})
+	bar.finish()
+	deleted := 0
+	if *remove && err == nil {
+		for _, name := range plan.Delete {
+			if errDelete := app.client.Delete(ctx, prefix+name); errDelete != nil {
+				err = errors.Join(err, fmt.Errorf("%s%s: %w", prefix, name, errDelete))
+				continue
+			}
+			deleted++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d uploaded, %d unchanged, %d deleted\n", len(plan.Upload), len(plan.Unchanged), deleted)
+	return err
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"CloudStorageProject-FileServer/pkg/client"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+/*
+cloudctl - консольный клиент файлового сервера.
+
+	cloudctl ls [-r] [prefix]
+	cloudctl put [-j 4] <local>... [remote]  # файлы и каталоги, параллельно
+	cloudctl get [-j 4] <remote> [local]      # remote с "/" на конце - весь каталог
+	cloudctl rm [-r] <remote>...
+	cloudctl mv <remote> <new remote>
+	cloudctl share [-expires 24h] <remote>
+	cloudctl sync [-delete] [-n] [-j 4] <dir> [remote prefix]
+
+Адрес сервера и ключ берутся из CLOUDCTL_URL и CLOUDCTL_API_KEY или из профиля
+в ~/.config/cloudctl/config.json (см. loadProfile). Профиль выбирается флагом -profile или CLOUDCTL_PROFILE.
+*/
+
+// command - подкоманда cloudctl
+type command struct {
+	usage string
+	run   func(ctx context.Context, app *cli, args []string) error
+}
+
+// Строки использования подкоманд
+const (
+	usageList   = "ls [-r] [prefix]"
+	usagePut    = "put [-j N] <local>... [remote]"
+	usageGet    = "get [-j N] <remote> [local]"
+	usageRemove = "rm [-r] <remote>..."
+	usageMove   = "mv <remote> <new remote>"
+	usageShare  = "share [-expires 24h] <remote>"
+	usageSync   = "sync [-delete] [-n] [-j N] <dir> [remote prefix]"
+)
+
+var commands = map[string]command{
+	"ls":    {usageList, runList},
+	"put":   {usagePut, runPut},
+	"get":   {usageGet, runGet},
+	"rm":    {usageRemove, runRemove},
+	"mv":    {usageMove, runMove},
+	"share": {usageShare, runShare},
+	"sync":  {usageSync, runSync},
+}
+
+// order - порядок команд в справке
+var order = []string{"ls", "put", "get", "rm", "mv", "share", "sync"}
+
+// cli - общие настройки всех команд
+type cli struct {
+	client  *client.Client
+	retries int
+	quiet   bool
+}
+
+func main() {
+	flags := flag.NewFlagSet("cloudctl", flag.ExitOnError)
+	profileName := flags.String("profile", os.Getenv("CLOUDCTL_PROFILE"), "profile from the config file")
+	configPath := flags.String("config", "", "config file (default ~/.config/cloudctl/config.json)")
+	serverURL := flags.String("url", "", "server address, overrides the profile")
+	retries := flags.Int("retries", 3, "retries of a failed transfer")
+	quiet := flags.Bool("q", false, "no progress output")
+	flags.Usage = usage(flags)
+	_ = flags.Parse(os.Args[1:])
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cloudctl: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	profile, err := loadProfile(*configPath, *profileName)
+	if err != nil {
+		fatal(err)
+	}
+	if *serverURL != "" {
+		profile.URL = *serverURL
+	}
+	if profile.APIKey == "" {
+		fatal(errors.New("API key is not set: use CLOUDCTL_API_KEY or a config profile"))
+	}
+	app := &cli{
+		client:  client.New(profile.URL, profile.APIKey, client.WithRetries(*retries, time.Second)),
+		retries: *retries,
+		quiet:   *quiet,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = cmd.run(ctx, app, flags.Args()[1:])
+	stop()
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func usage(flags *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintln(os.Stderr, "usage: cloudctl [flags] <command> [args]")
+		fmt.Fprintln(os.Stderr, "\ncommands:")
+		for _, name := range order {
+			fmt.Fprintln(os.Stderr, "  "+commands[name].usage)
+		}
+		fmt.Fprintln(os.Stderr, "\nflags:")
+		flags.PrintDefaults()
+	}
+}
+
+// subcommand - флаги подкоманды; usage - ее строка из справки, первое слово - имя
+func subcommand(usage string) *flag.FlagSet {
+	name, _, _ := strings.Cut(usage, " ")
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: cloudctl "+usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "cloudctl:", err)
+	os.Exit(1)
+}
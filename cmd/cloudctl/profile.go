@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// defaultURL - адрес сервера по умолчанию (SERVER_PORT из .env)
+const defaultURL = "http://localhost:11682"
+
+// profile - адрес сервера и ключ хранилища
+type profile struct {
+	URL    string `json:"url"`
+	APIKey string `json:"api_key"`
+}
+
+// loadProfile - профиль из файла конфигурации, поверх него - переменные окружения.
+// Файл - JSON с профилями по имени:
+//
+//	{"default": {"url": "http://localhost:11682", "api_key": "..."}, "prod": {...}}
+//
+// Файла может не быть, если ключ задан в CLOUDCTL_API_KEY
+func loadProfile(path, name string) (*profile, error) {
+	explicit := path != ""
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			path = filepath.Join(dir, "cloudctl", "config.json")
+		}
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	result := &profile{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		profiles := map[string]profile{}
+		if err = json.Unmarshal(data, &profiles); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		found, ok := profiles[name]
+		if !ok && name != "default" {
+			return nil, fmt.Errorf("profile %q not found in %s", name, path)
+		}
+		*result = found
+	case explicit || !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if value := os.Getenv("CLOUDCTL_URL"); value != "" {
+		result.URL = value
+	}
+	if value := os.Getenv("CLOUDCTL_API_KEY"); value != "" {
+		result.APIKey = value
+	}
+	if result.URL == "" {
+		result.URL = defaultURL
+	}
+	return result, nil
+}
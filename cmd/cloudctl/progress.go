@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressWidth - ширина полосы в символах
+const progressWidth = 30
+
+// progress - общая полоса прогресса для всех файлов команды, перерисовывается в stderr.
+// Без терминала (перенаправленный вывод) и с -q ничего не рисует
+type progress struct {
+	label     string
+	total     int64
+	files     int64
+	done      atomic.Int64
+	filesDone atomic.Int64
+	started   time.Time
+	enabled   bool
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+}
+
+func newProgress(app *cli, label string, total int64, files int) *progress {
+	p := &progress{
+		label:   label,
+		total:   total,
+		files:   int64(files),
+		started: time.Now(),
+		enabled: !app.quiet && isTerminal(os.Stderr),
+		stop:    make(chan struct{}),
+	}
+	if p.enabled {
+		p.wg.Add(1)
+		go p.loop()
+	}
+	return p
+}
+
+func (p *progress) loop() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.draw()
+		}
+	}
+}
+
+func (p *progress) draw() {
+	done := p.done.Load()
+	bar := strings.Repeat(".", progressWidth)
+	if p.total > 0 {
+		filled := int(min(done, p.total) * progressWidth / p.total)
+		bar = strings.Repeat("#", filled) + bar[filled:]
+	}
+	speed := float64(done) / max(time.Since(p.started).Seconds(), 0.001)
+	p.mu.Lock()
+	fmt.Fprintf(os.Stderr, "\r%s %d/%d [%s] %s / %s %s/s\033[K", p.label, p.filesDone.Load(), p.files, bar,
+		formatSize(done), formatSize(p.total), formatSize(int64(speed)))
+	p.mu.Unlock()
+}
+
+// printf - сообщение поверх полосы, полоса перерисуется на следующем тике
+func (p *progress) printf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.enabled {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+// fileDone - файл передан целиком
+func (p *progress) fileDone() {
+	p.filesDone.Add(1)
+}
+
+// finish - последняя отрисовка и перевод строки
+func (p *progress) finish() {
+	if !p.enabled {
+		return
+	}
+	close(p.stop)
+	p.wg.Wait()
+	p.draw()
+	fmt.Fprintln(os.Stderr)
+}
+
+// reader - учитывает прочитанные байты в полосе. rollback возвращает их, если передачу придется повторить
+func (p *progress) reader(r io.Reader) *countingReader {
+	return &countingReader{reader: r, progress: p}
+}
+
+type countingReader struct {
+	reader   io.Reader
+	progress *progress
+	count    int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.count += int64(n)
+	r.progress.done.Add(int64(n))
+	return n, err
+}
+
+func (r *countingReader) rollback() {
+	r.progress.done.Add(-r.count)
+	r.count = 0
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatSize - размер в двоичных единицах: 512 B, 1.5 MiB
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exp])
+}
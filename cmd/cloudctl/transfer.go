@@ -0,0 +1,154 @@
+package main
+
+import (
+	"CloudStorageProject-FileServer/pkg/client"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultJobs - сколько файлов передается одновременно
+const defaultJobs = 4
+
+// retry - повторяет передачу после временной ошибки: сеть или 5xx/429 от сервера.
+// Ошибки запроса (4xx) не повторяются: повтор даст тот же ответ
+func (app *cli) retry(ctx context.Context, bar *progress, name string, transfer func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := transfer()
+		if err == nil || ctx.Err() != nil || attempt >= app.retries || !temporary(err) {
+			return err
+		}
+		bar.printf("%s: %v, retrying", name, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second << attempt):
+		}
+	}
+}
+
+func temporary(err error) bool {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// parallel - вызывает job для каждого из n элементов не больше чем в jobs потоков.
+// Ошибки выводятся по мере появления, результат - сколько элементов не удалось обработать
+func parallel(ctx context.Context, bar *progress, jobs, n int, name func(i int) string, job func(i int) error) error {
+	indexes := make(chan int)
+	var failed sync.Map
+	var wg sync.WaitGroup
+	for range max(jobs, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := job(i); err != nil {
+					failed.Store(i, err)
+					bar.printf("%s: %v", name(i), err)
+				}
+			}
+		}()
+	}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	count := 0
+	failed.Range(func(_, _ any) bool {
+		count++
+		return true
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if count > 0 {
+		return fmt.Errorf("%d of %d files failed", count, n)
+	}
+	return nil
+}
+
+// hashFile - sha256 файла в hex, сервер сверяет его с загруженным содержимым
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+	h := sha256.New()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// uploadFile - загрузка одного локального файла с повторами. upload получает тело и его размер
+func (app *cli) uploadFile(ctx context.Context, bar *progress, local, name string,
+	upload func(r io.Reader, size int64) error) error {
+	err := app.retry(ctx, bar, name, func() error {
+		file, err := os.Open(local)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		body := bar.reader(file)
+		if err = upload(body, info.Size()); err != nil {
+			body.rollback()
+		}
+		return err
+	})
+	if err == nil {
+		bar.fileDone()
+	}
+	return err
+}
+
+// downloadFile - скачивание во временный файл рядом с local; после обрыва докачивает с того же места
+func (app *cli) downloadFile(ctx context.Context, bar *progress, name, local string) error {
+	partial := local + ".part"
+	file, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	err = app.retry(ctx, bar, name, func() error {
+		offset, errSeek := file.Seek(0, io.SeekEnd)
+		if errSeek != nil {
+			return errSeek
+		}
+		body, _, errGet := app.client.OpenRange(ctx, name, offset, 0)
+		if errGet != nil {
+			return errGet
+		}
+		defer func() { _ = body.Close() }()
+		_, errGet = io.Copy(file, bar.reader(body))
+		return errGet
+	})
+	errClose := file.Close()
+	if err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(partial, local)
+	}
+	if err != nil {
+		_ = os.Remove(partial)
+		return err
+	}
+	bar.fileDone()
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"CloudStorageProject-FileServer/internal/app"
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/metrics"
+	"CloudStorageProject-FileServer/internal/provisioning"
+	"CloudStorageProject-FileServer/pkg/config"
+	"context"
+	"log"
+	"log/slog"
+	"os"
+)
+
+/*
+Перенос хранилищ, названных по значению API-ключа, на неизменяемые storage_id (u-<uuid>).
+Файлы копируются в новое хранилище, аккаунт и его правила жизненного цикла переключаются
+одной транзакцией, после чего старое хранилище очищается.
+
+	go run ./cmd/migrate-storage
+
+Использует тот же .env, что и fileserver. Запускать лучше при остановленном fileserver:
+файлы, загруженные в старое хранилище во время переноса, останутся в нем.
+*/
+func main() {
+	ctx := context.Background()
+	conf, err := config.Load(config.ConfPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+	ctx = context.WithValue(ctx, "logger", logger)
+	ctx = context.WithValue(ctx, "config", conf)
+
+	metric := metrics.NewCollector("CloudStorage")
+	st, err := app.NewStorage(ctx, conf, metric)
+	if err != nil {
+		logger.Error("storage init error", "error", err)
+		os.Exit(1)
+	}
+	pgs, err := postgres.InitPostgres(ctx, metric.Postgres)
+	if err != nil {
+		logger.Error("postgres init error", "error", err)
+		os.Exit(1)
+	}
+	rds, err := redis.NewRedis(ctx, metric.Redis)
+	if err != nil {
+		logger.Error("redis init error", "error", err)
+		os.Exit(1)
+	}
+
+	results, err := provisioning.NewProvisioning(ctx, pgs, rds, st).MigrateLegacyStorage()
+	_ = rds.CloseConnection(ctx)
+	_ = pgs.CloseConnection(ctx)
+	for _, result := range results {
+		logger.Info("storage migrated", "account", result.AccountId, "storage_id", result.NewStorageId,
+			"moved", result.Moved, "left", result.Left)
+	}
+	if err != nil {
+		logger.Error("storage migration finished with errors", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("storage migration done", "accounts", len(results))
+}
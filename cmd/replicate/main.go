@@ -0,0 +1,93 @@
+package main
+
+import (
+	"CloudStorageProject-FileServer/internal/app"
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/metrics"
+	"CloudStorageProject-FileServer/internal/replication"
+	"CloudStorageProject-FileServer/pkg/config"
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+)
+
+/*
+Догоняющая репликация: сверяет хранилища пользователей с репликой (REPLICA_ENDPOINT)
+и копирует всё, что не успело или не смогло реплицироваться через очередь fileserver.
+
+	go run ./cmd/replicate              # все хранилища из minio_keys
+	go run ./cmd/replicate -bucket xxx  # одно хранилище
+
+Использует тот же .env, что и fileserver.
+*/
+func main() {
+	bucket := flag.String("bucket", "", "backfill only this storage")
+	flag.Parse()
+
+	ctx := context.Background()
+	conf, err := config.Load(config.ConfPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+	ctx = context.WithValue(ctx, "logger", logger)
+	ctx = context.WithValue(ctx, "config", conf)
+
+	if conf.ReplicaEndpoint == "" {
+		logger.Error("REPLICA_ENDPOINT is not set")
+		os.Exit(1)
+	}
+
+	metric := metrics.NewCollector("CloudStorage")
+	st, err := app.NewStorage(ctx, conf, metric)
+	if err != nil {
+		logger.Error("storage init error", "error", err)
+		os.Exit(1)
+	}
+	replicator, err := replication.NewReplicator(ctx, st, metric.Replication)
+	if err != nil {
+		logger.Error("replication init error", "error", err)
+		os.Exit(1)
+	}
+
+	buckets := []string{*bucket}
+	if *bucket == "" {
+		pgs, errPgs := postgres.InitPostgres(ctx, metric.Postgres)
+		if errPgs != nil {
+			logger.Error("postgres init error", "error", errPgs)
+			os.Exit(1)
+		}
+		keys, errKeys := pgs.APIKeys()
+		_ = pgs.CloseConnection(ctx)
+		if errKeys != nil {
+			logger.Error("api keys loading error", "error", errKeys)
+			os.Exit(1)
+		}
+		buckets = buckets[:0]
+		for _, key := range keys {
+			buckets = append(buckets, key.StorageId)
+		}
+	}
+
+	failed := false
+	for _, name := range buckets {
+		result, errBackfill := replicator.Backfill(name)
+		if errBackfill != nil {
+			failed = true
+			logger.Error("backfill error", "bucket", name, "error", errBackfill)
+			continue
+		}
+		if result.Failed > 0 {
+			failed = true
+		}
+		logger.Info("backfill done", "bucket", name, "checked", result.Checked, "copied", result.Copied,
+			"deleted", result.Deleted, "failed", result.Failed)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -58,8 +58,31 @@ import (
 // @title CloudStorage
 // @version 1.0
 // @description MinIO-base data storage
+// @description
+// @description Every error is returned as JSON: {"error":{"code":"...","message":"...","request_id":"...","details":{}}}.
+// @description request_id matches the X-Request-Id response header and the server logs.
+// @description Codes: bad_request, invalid_name, checksum_mismatch (400); api_key_required, api_key_invalid, unauthorized (401);
+// @description forbidden, object_locked (403); not_found, storage_not_found (404); method_not_allowed (405);
+// @description conflict, locking_not_supported (409); cursor_expired (410); too_large (413); quota_exceeded (507); internal (500);
+// @description storage_unavailable, database_unavailable, cache_unavailable, service_unavailable (503); timeout (504).
 // @BasePath /client/api/v1
 // @schemes http
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Account API key
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Account API key in the form "Bearer <key>"
+// @securityDefinitions.apikey ApiKeyQuery
+// @in query
+// @name api
+// @description Account API key in the query string. Deprecated: leaks into logs and browser history, disabled by API_KEY_IN_QUERY=false
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Admin API token (ADMIN_TOKEN) in the form "Bearer <token>"
 func main() {
 	ctx := context.Background()
 
@@ -15,93 +15,136 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/client/api/v1/delete-file": {
-            "delete": {
-                "description": "Delete file by user apikey and filename",
-                "consumes": [
-                    "application/json"
+        "/admin/api/v1/accounts": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Accounts in creation order. Keys are not returned",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "admin"
                 ],
-                "summary": "Delete a file by api",
+                "summary": "List accounts",
                 "parameters": [
                     {
+                        "enum": [
+                            "active",
+                            "suspended",
+                            "revoked"
+                        ],
                         "type": "string",
-                        "example": "60601fee-2bf1-4721-ae6f-7636e79a0cba",
-                        "description": "APIKEY (UUID)",
-                        "name": "api",
-                        "in": "query",
-                        "required": true
+                        "description": "Only accounts with this status",
+                        "name": "status",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "example": "alohadance.png",
-                        "description": "File name",
-                        "name": "filename",
-                        "in": "query",
-                        "required": true
+                        "example": "example.com",
+                        "description": "Only accounts whose email contains this substring",
+                        "name": "email",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.FileResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Account"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
+                    "401": {
+                        "description": "Admin token is invalid",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Register a new API key and provision its storage. The key is returned only in this response.\nIf the account is created but its quota could not be applied, the response lists it in warnings",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create account",
+                "parameters": [
+                    {
+                        "description": "Account",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.AccountKey"
                         }
                     },
-                    "405": {
-                        "description": "Method not allowed",
+                    "400": {
+                        "description": "Bad request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Admin token is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/client/api/v1/get-file": {
+        "/admin/api/v1/accounts/{id}": {
             "get": {
-                "description": "Get file by user apikey and filename",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "admin"
                 ],
-                "summary": "Get a file by api",
+                "summary": "Get account",
                 "parameters": [
                     {
-                        "type": "string",
-                        "example": "60601fee-2bf1-4721-ae6f-7636e79a0cba",
-                        "description": "APIKEY (UUID)",
-                        "name": "apikey",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "example": "alohadance.png",
-                        "description": "File name",
-                        "name": "filename",
-                        "in": "query",
+                        "type": "integer",
+                        "example": 12,
+                        "description": "Account id",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -109,44 +152,45 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.FileInfo"
+                            "$ref": "#/definitions/models.Account"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Admin token is invalid",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "405": {
-                        "description": "Method not allowed",
+                    "404": {
+                        "description": "Not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/client/api/v1/get-files-list": {
-            "get": {
-                "description": "Get user files by his apikey",
-                "consumes": [
-                    "application/json"
+        "/admin/api/v1/accounts/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "admin"
                 ],
-                "summary": "Get user file list by api",
+                "summary": "Resume API key",
                 "parameters": [
                     {
-                        "type": "string",
-                        "example": "60601fee-2bf1-4721-ae6f-7636e79a0cba",
-                        "description": "APIKEY (UUID)",
-                        "name": "api",
-                        "in": "query",
+                        "type": "integer",
+                        "example": 12,
+                        "description": "Account id",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -154,83 +198,152 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.FileWebResponse"
+                            "$ref": "#/definitions/models.Account"
+                        }
+                    },
+                    "401": {
+                        "description": "Admin token is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "405": {
-                        "description": "Method not allowed",
+                    "409": {
+                        "description": "Key is revoked",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/client/api/v1/storage/": {
-            "get": {
-                "description": "Page with user files",
+        "/admin/api/v1/accounts/{id}/revoke": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "The key stops working immediately on every protocol and cannot be restored. Files are kept",
                 "produces": [
-                    "text/html"
+                    "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "admin"
+                ],
+                "summary": "Revoke API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 12,
+                        "description": "Account id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Storage page",
                 "responses": {
                     "200": {
-                        "description": "Storage page",
+                        "description": "OK",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/models.Account"
                         }
                     },
-                    "302": {
-                        "description": "Found",
+                    "401": {
+                        "description": "Admin token is invalid",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "405": {
-                        "description": "Method not allowed",
+                    "404": {
+                        "description": "Not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/client/api/v1/upload-files": {
+        "/admin/api/v1/accounts/{id}/rotate": {
             "post": {
-                "description": "Upload file by user apikey and files from query body",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Issue a new key for the account, the old one stops working immediately. S3 credentials\nchange with the key and SSH keys of the account are deleted, files are kept.\nThe new key is returned only in this response",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "admin"
                 ],
-                "summary": "Upload a file",
+                "summary": "Rotate API key",
                 "parameters": [
                     {
-                        "type": "string",
-                        "example": "60601fee-2bf1-4721-ae6f-7636e79a0cba",
-                        "description": "APIKEY (UUID)",
-                        "name": "api",
-                        "in": "query",
+                        "type": "integer",
+                        "example": 12,
+                        "description": "Account id",
+                        "name": "id",
+                        "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AccountKey"
+                        }
                     },
+                    "401": {
+                        "description": "Admin token is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Key is revoked or storage is not migrated",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/api/v1/accounts/{id}/suspend": {
+            "post": {
+                "security": [
                     {
-                        "type": "file",
-                        "description": "File to upload",
-                        "name": "file",
-                        "in": "formData",
+                        "AdminToken": []
+                    }
+                ],
+                "description": "The key stops working immediately until the account is resumed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Suspend API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 12,
+                        "description": "Account id",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -238,89 +351,2437 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.FileResponse"
+                            "$ref": "#/definitions/models.Account"
                         }
                     },
-                    "405": {
-                        "description": "Method not allowed",
+                    "401": {
+                        "description": "Admin token is invalid",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "404": {
+                        "description": "Not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Key is revoked",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/health": {
+        "/api/v2/changes": {
             "get": {
-                "description": "Check if API is running",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Ordered create, overwrite, delete and move events after the cursor. Without cursor returns the\ncurrent cursor: list files once, then poll from it. With wait the request blocks until\na change appears or wait seconds pass. 410 cursor_expired means the journal was compacted\npast the cursor and the client must do a full resync",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "files"
+                ],
+                "summary": "Storage change feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "1042",
+                        "description": "Cursor from the previous response",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 30,
+                        "description": "Long-polling timeout in seconds, up to 60",
+                        "name": "wait",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1000,
+                        "description": "Max changes per response, up to 1000",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.HealthResponse"
+                            "$ref": "#/definitions/models.ChangeFeed"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad cursor",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Cursor expired, full resync required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/index": {
+        "/api/v2/files": {
             "get": {
-                "description": "Page to enter user apikey to get access for his storage",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Files and folders under the prefix. Folders are returned with is_dir unless recursive=true",
                 "produces": [
-                    "text/html"
+                    "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "v2"
+                ],
+                "summary": "List files",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "photos/",
+                        "description": "Folder prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "List the whole subtree",
+                        "name": "recursive",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Page to login",
                 "responses": {
                     "200": {
-                        "description": "Login page",
+                        "description": "OK",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/models.FileList"
                         }
                     },
-                    "302": {
-                        "description": "Found",
+                    "400": {
+                        "description": "Invalid prefix",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "405": {
-                        "description": "Method not allowed",
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.FileInfo": {
-            "type": "object",
-            "properties": {
-                "content_type": {
-                    "type": "string",
-                    "example": "image/png"
-                },
-                "etag": {
+        },
+        "/api/v2/files/{path}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "File content with ETag and Last-Modified. Supports Range, If-None-Match and If-Modified-Since",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Download a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "photos/alohadance.png",
+                        "description": "File path",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Stream the request body into the user storage under the given path. Send X-Checksum-Sha256 to verify the content",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Upload a file from the raw body",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "photos/alohadance.png",
+                        "description": "File path",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expected sha256 of the body in hex",
+                        "name": "X-Checksum-Sha256",
+                        "in": "header"
+                    },
+                    {
+                        "description": "File content",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File overwritten",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileInfo"
+                        }
+                    },
+                    "201": {
+                        "description": "File created",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or checksum mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "File is protected by retention or legal hold",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Delete a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "photos/alohadance.png",
+                        "description": "File path",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted"
+                    },
+                    "403": {
+                        "description": "File is protected by retention or legal hold",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Size, ETag, Content-Type, Last-Modified and sha256 of the file in response headers",
+                "tags": [
+                    "files"
+                ],
+                "summary": "File metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "photos/alohadance.png",
+                        "description": "File path",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Entity tag"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "Last modification time"
+                            },
+                            "Repr-Digest": {
+                                "type": "string",
+                                "description": "sha256 of the content (RFC 9530), if known"
+                            },
+                            "X-Checksum-Sha256": {
+                                "type": "string",
+                                "description": "sha256 of the content in hex, if known"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/files/{path}:copy": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Server-side copy of the file to the destination path inside the same storage",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Copy a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "photos/alohadance.png",
+                        "description": "Source file path",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Destination",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CopyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Destination is protected by retention or legal hold",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/sync": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Takes (path, size, sha256) of local files under prefix and returns which of them must be uploaded,\nwhich stored files are absent from the manifest (delete) and which are unchanged. With presign\nevery file to upload gets a signed PUT link that works without the API key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Compare a manifest with the storage",
+                "parameters": [
+                    {
+                        "description": "Manifest",
+                        "name": "manifest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad manifest",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Manifest is too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/changes": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Ordered create, overwrite, delete and move events after the cursor. Without cursor returns the\ncurrent cursor: list files once, then poll from it. With wait the request blocks until\na change appears or wait seconds pass. 410 cursor_expired means the journal was compacted\npast the cursor and the client must do a full resync",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Storage change feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "1042",
+                        "description": "Cursor from the previous response",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 30,
+                        "description": "Long-polling timeout in seconds, up to 60",
+                        "name": "wait",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1000,
+                        "description": "Max changes per response, up to 1000",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeFeed"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad cursor",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Cursor expired, full resync required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/delete-file": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Delete file by user apikey and filename",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Delete a file by api",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "alohadance.png",
+                        "description": "File name",
+                        "name": "filename",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "File is protected by retention or legal hold",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/events": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Server-Sent Events stream with upload, delete and rename events of the user storage",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Storage events stream",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.StorageEvent"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/files/{path}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Stream the request body into the user storage under the given path. Send X-Checksum-Sha256 to verify the content",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Upload a file from the raw body",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "photos/alohadance.png",
+                        "description": "File path",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expected sha256 of the body in hex",
+                        "name": "X-Checksum-Sha256",
+                        "in": "header"
+                    },
+                    {
+                        "description": "File content",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File overwritten",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileInfo"
+                        }
+                    },
+                    "201": {
+                        "description": "File created",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or checksum mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "File is protected by retention or legal hold",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Size, ETag, Content-Type, Last-Modified and sha256 of the file in response headers",
+                "tags": [
+                    "files"
+                ],
+                "summary": "File metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "photos/alohadance.png",
+                        "description": "File path",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Entity tag"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "Last modification time"
+                            },
+                            "Repr-Digest": {
+                                "type": "string",
+                                "description": "sha256 of the content (RFC 9530), if known"
+                            },
+                            "X-Checksum-Sha256": {
+                                "type": "string",
+                                "description": "sha256 of the content in hex, if known"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/get-file": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Get file by user apikey and filename",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Get a file by api",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "alohadance.png",
+                        "description": "File name",
+                        "name": "filename",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/get-files-list": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Get user files by his apikey",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Get user file list by api",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileWebResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/legal-hold": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Enable or disable legal hold: the file can not be deleted or overwritten while it is enabled",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "retention"
+                ],
+                "summary": "Set file legal hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "alohadance.png",
+                        "description": "File name",
+                        "name": "filename",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Legal hold status",
+                        "name": "legal_hold",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LegalHoldRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectProtection"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Object locking is not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/lifecycle-rules": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Get lifecycle rules of the user storage",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lifecycle"
+                ],
+                "summary": "List lifecycle rules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LifecycleRule"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Replace prefix, tag, action and days of the lifecycle rule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lifecycle"
+                ],
+                "summary": "Update lifecycle rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Rule id",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Lifecycle rule",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LifecycleRule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LifecycleRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Files matching prefix (and tag, if set) are deleted or moved to trash after N days",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lifecycle"
+                ],
+                "summary": "Create lifecycle rule",
+                "parameters": [
+                    {
+                        "description": "Lifecycle rule",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LifecycleRule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.LifecycleRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Delete lifecycle rule of the user storage",
+                "tags": [
+                    "lifecycle"
+                ],
+                "summary": "Delete lifecycle rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Rule id",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/rename-file": {
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Rename file by user apikey, current filename and new filename",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Rename a file by api",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "alohadance.png",
+                        "description": "File name",
+                        "name": "filename",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "alohadance2.png",
+                        "description": "New file name",
+                        "name": "new_name",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "File is protected by retention or legal hold",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/retention": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Get retention mode, retain-until date and legal hold of the file",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "retention"
+                ],
+                "summary": "Get file retention",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "alohadance.png",
+                        "description": "File name",
+                        "name": "filename",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectProtection"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Protect the file from deletion and overwrite until retain_until (WORM). Requires storage with object locking",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "retention"
+                ],
+                "summary": "Set file retention",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "alohadance.png",
+                        "description": "File name",
+                        "name": "filename",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Retention mode and date",
+                        "name": "retention",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RetentionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectProtection"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Retention can not be shortened",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Object locking is not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/s3-credentials": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Access key, secret key and bucket for S3 clients (aws cli, rclone, SDK). Keys are derived\nfrom the account storage and API key: rotating the API key replaces the secret key, revoking or\nsuspending it disables the S3 keys too. Requests must use path-style addressing and SigV4\nwith the returned region",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "S3 gateway credentials",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.S3Credentials"
+                        }
+                    },
+                    "404": {
+                        "description": "S3 gateway is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Storage must be migrated with cmd/migrate-storage first",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/share": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Signed link that downloads the file without the API key until it expires (24 hours by default,\n7 days at most). The link follows the path: a file uploaded later under the same name is served",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Share a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "photos/alohadance.png",
+                        "description": "File name",
+                        "name": "filename",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "example": 3600,
+                        "description": "Link lifetime in seconds",
+                        "name": "expires_in",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ShareLink"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/ssh-keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Public keys that can log in to the SFTP server instead of the API key",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sftp"
+                ],
+                "summary": "List SSH keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SSHKey"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Register a public key in authorized_keys format for SFTP login. A key can belong to one account only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sftp"
+                ],
+                "summary": "Add SSH key",
+                "parameters": [
+                    {
+                        "description": "Public key",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SSHKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SSHKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Key is already registered",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Revoke SFTP login with the public key. Open sessions are not interrupted",
+                "tags": [
+                    "sftp"
+                ],
+                "summary": "Delete SSH key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Key id",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/storage/": {
+            "get": {
+                "description": "Page with user files",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Storage page",
+                "responses": {
+                    "200": {
+                        "description": "Storage page",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "302": {
+                        "description": "Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/sync": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Takes (path, size, sha256) of local files under prefix and returns which of them must be uploaded,\nwhich stored files are absent from the manifest (delete) and which are unchanged. With presign\nevery file to upload gets a signed PUT link that works without the API key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Compare a manifest with the storage",
+                "parameters": [
+                    {
+                        "description": "Manifest",
+                        "name": "manifest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad manifest",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Manifest is too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/upload-files": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Upload file by user apikey and files from query body",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Upload a file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "File to upload",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "overwrite",
+                            "rename",
+                            "fail"
+                        ],
+                        "type": "string",
+                        "default": "overwrite",
+                        "description": "What to do if the file already exists. Best-effort: concurrent uploads of the same name are not serialized",
+                        "name": "on_conflict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Some files are protected by retention or legal hold or already exist",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/upload-jobs/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "Status and downloaded bytes of the job. Jobs are kept for 24 hours",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Upload from URL progress",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FetchJob"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/client/api/v1/upload-url": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "The server downloads the file itself and saves it to the storage. Private and loopback addresses\nare not allowed unless listed in FETCH_ALLOWED_NETWORKS. Progress is polled by the returned job id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Upload a file from URL",
+                "parameters": [
+                    {
+                        "description": "Source URL and target path",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.FetchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.FetchJob"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Job status URL"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Address is not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many jobs",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/download/{token}": {
+            "get": {
+                "description": "Downloads the shared file without the API key. Supports Range and conditional requests",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download by a shared link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Signed download token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Link is invalid or expired",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Check if API is running",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/index": {
+            "get": {
+                "description": "Page to enter user apikey to get access for his storage",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Page to login",
+                "responses": {
+                    "200": {
+                        "description": "Login page",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "302": {
+                        "description": "Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/upload/{token}": {
+            "put": {
+                "description": "Uploads one file from the sync response without the API key. Size and sha256 of the body must match\nthe manifest entry the link was issued for",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Upload by a signed link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Signed upload token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "File content",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File overwritten",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileInfo"
+                        }
+                    },
+                    "201": {
+                        "description": "File created",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Size or checksum mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Link is invalid or expired",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.APIError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "enum": [
+                        "bad_request",
+                        "invalid_name",
+                        "checksum_mismatch",
+                        "api_key_required",
+                        "api_key_invalid",
+                        "unauthorized",
+                        "forbidden",
+                        "object_locked",
+                        "not_found",
+                        "storage_not_found",
+                        "method_not_allowed",
+                        "conflict",
+                        "locking_not_supported",
+                        "cursor_expired",
+                        "too_large",
+                        "quota_exceeded",
+                        "internal",
+                        "storage_unavailable",
+                        "database_unavailable",
+                        "cache_unavailable",
+                        "service_unavailable",
+                        "timeout"
+                    ],
+                    "example": "not_found"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "message": {
+                    "type": "string",
+                    "example": "file not found"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "0f8e6c1a9b7d4e52"
+                }
+            }
+        },
+        "models.Account": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "key_prefix": {
+                    "description": "KeyPrefix - открытая часть ключа; пусто у ключей старого формата",
+                    "type": "string",
+                    "example": "3f1c2b0a9d8e"
+                },
+                "last_login": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "string",
+                    "example": "read,write"
+                },
+                "quota_mb": {
+                    "type": "integer",
+                    "example": 10240
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "suspended",
+                        "revoked"
+                    ],
+                    "example": "active"
+                },
+                "storage_id": {
+                    "type": "string",
+                    "example": "u-3f1c2b0a9d8e4f7a8b6c5d4e3f2a1b0c"
+                }
+            }
+        },
+        "models.AccountKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "key": {
+                    "type": "string",
+                    "example": "3f1c2b0a9d8e.9c4f0d3e5b2a41f7a8c6e1d0b3f5a7c9e2d4f6a8b0c1e3d5f7a9c2e4b6d8f0a1"
+                },
+                "key_prefix": {
+                    "description": "KeyPrefix - открытая часть ключа; пусто у ключей старого формата",
+                    "type": "string",
+                    "example": "3f1c2b0a9d8e"
+                },
+                "last_login": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "string",
+                    "example": "read,write"
+                },
+                "quota_mb": {
+                    "type": "integer",
+                    "example": 10240
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "suspended",
+                        "revoked"
+                    ],
+                    "example": "active"
+                },
+                "storage_id": {
+                    "type": "string",
+                    "example": "u-3f1c2b0a9d8e4f7a8b6c5d4e3f2a1b0c"
+                },
+                "warnings": {
+                    "description": "Warnings - аккаунт создан, но не все настройки применены, например квота хранилища",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "storage driver does not support quotas"
+                    ]
+                }
+            }
+        },
+        "models.Change": {
+            "type": "object",
+            "properties": {
+                "etag": {
+                    "type": "string",
+                    "example": "\"33a64df551425fcc55e4d42a148795d9\""
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1042
+                },
+                "new_path": {
+                    "type": "string",
+                    "example": "photos/2024/alohadance.png"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "photos/alohadance.png"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "time": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "overwrite",
+                        "delete",
+                        "move"
+                    ],
+                    "example": "move"
+                }
+            }
+        },
+        "models.ChangeFeed": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Change"
+                    }
+                },
+                "cursor": {
+                    "type": "string",
+                    "example": "1042"
+                },
+                "has_more": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.CopyRequest": {
+            "type": "object",
+            "properties": {
+                "destination": {
+                    "type": "string",
+                    "example": "photos/alohadance-copy.png"
+                }
+            }
+        },
+        "models.CreateAccountRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "permissions": {
+                    "description": "Permissions - \"read\" или \"read,write\"; пусто - read,write",
+                    "type": "string",
+                    "example": "read,write"
+                },
+                "quota_mb": {
+                    "description": "QuotaMB - квота хранилища, 0 - BUCKET_QUOTA_MB",
+                    "type": "integer",
+                    "example": 10240
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/models.APIError"
+                }
+            }
+        },
+        "models.FetchJob": {
+            "type": "object",
+            "properties": {
+                "bytes_done": {
+                    "type": "integer",
+                    "example": 1048576
+                },
+                "bytes_total": {
+                    "description": "-1, если сервер не прислал Content-Length",
+                    "type": "integer",
+                    "example": 4194304
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5f0c3b8e2d9a4c7f"
+                },
+                "on_conflict": {
+                    "type": "string",
+                    "example": "rename"
+                },
+                "path": {
+                    "description": "после on_conflict=rename - новое имя",
+                    "type": "string",
+                    "example": "docs/report.pdf"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "queued",
+                        "running",
+                        "done",
+                        "failed"
+                    ],
+                    "example": "running"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/files/report.pdf"
+                }
+            }
+        },
+        "models.FetchRequest": {
+            "type": "object",
+            "properties": {
+                "on_conflict": {
+                    "description": "проверяется перед записью, одновременную загрузку того же имени не исключает",
+                    "type": "string",
+                    "enum": [
+                        "overwrite",
+                        "rename",
+                        "fail"
+                    ],
+                    "example": "rename"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "docs/report.pdf"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/files/report.pdf"
+                }
+            }
+        },
+        "models.FileInfo": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string",
+                    "example": "image/png"
+                },
+                "etag": {
                     "type": "string",
                     "example": "\"33a64df551425fcc55e4d42a148795d9f25f89d4\""
                 },
+                "is_dir": {
+                    "type": "boolean"
+                },
                 "last_modified": {
                     "type": "string",
                     "example": "2024-01-01T12:00:00Z"
@@ -329,15 +2790,40 @@ const docTemplate = `{
                     "type": "string",
                     "example": "alohadance.png"
                 },
+                "sha256": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
                 "size": {
                     "type": "integer",
                     "example": 1024
                 }
             }
         },
+        "models.FileList": {
+            "type": "object",
+            "properties": {
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FileInfo"
+                    }
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "photos/"
+                }
+            }
+        },
         "models.FileResponse": {
             "type": "object",
             "properties": {
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UploadResult"
+                    }
+                },
                 "message": {
                     "type": "string"
                 },
@@ -347,6 +2833,10 @@ const docTemplate = `{
                         "$ref": "#/definitions/models.FileWebResponse"
                     }
                 },
+                "on_conflict": {
+                    "type": "string",
+                    "example": "rename"
+                },
                 "status": {
                     "type": "integer"
                 },
@@ -364,6 +2854,10 @@ const docTemplate = `{
                 "create_date": {
                     "type": "string"
                 },
+                "expires_at": {
+                    "description": "ближайшее срабатывание правила жизненного цикла",
+                    "type": "string"
+                },
                 "file_name": {
                     "type": "string"
                 },
@@ -391,6 +2885,328 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.LegalHoldRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "models.LifecycleRule": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "delete"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "days": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "exports/"
+                },
+                "tag_key": {
+                    "type": "string",
+                    "example": "temporary"
+                },
+                "tag_value": {
+                    "type": "string",
+                    "example": "true"
+                }
+            }
+        },
+        "models.ManifestEntry": {
+            "type": "object",
+            "properties": {
+                "path": {
+                    "type": "string",
+                    "example": "photos/alohadance.png"
+                },
+                "sha256": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 1024
+                }
+            }
+        },
+        "models.ObjectProtection": {
+            "type": "object",
+            "properties": {
+                "legal_hold": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "mode": {
+                    "type": "string",
+                    "example": "COMPLIANCE"
+                },
+                "retain_until": {
+                    "type": "string",
+                    "example": "2030-01-01T00:00:00Z"
+                }
+            }
+        },
+        "models.RetentionRequest": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "type": "string",
+                    "example": "GOVERNANCE"
+                },
+                "retain_until": {
+                    "type": "string",
+                    "example": "2030-01-01T00:00:00Z"
+                }
+            }
+        },
+        "models.S3Credentials": {
+            "type": "object",
+            "properties": {
+                "access_key": {
+                    "type": "string",
+                    "example": "u-3f1c2b7a9d8e4f60a1b2c3d4e5f60718"
+                },
+                "bucket": {
+                    "type": "string",
+                    "example": "u-3f1c2b7a9d8e4f60a1b2c3d4e5f60718"
+                },
+                "region": {
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "secret_key": {
+                    "type": "string",
+                    "example": "Zk3v9QeT0bJmX1aR7pWc2LhN5sUyD8oGiKfE4tVq"
+                }
+            }
+        },
+        "models.SSHKey": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string",
+                    "example": "partner@backup-host"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "fingerprint": {
+                    "type": "string",
+                    "example": "SHA256:ZsbWkbeK0dnAIoUm/uZAAZ3UsNmPjkx2RrH+6eRrV7U"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "public_key": {
+                    "type": "string",
+                    "example": "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBeQk0Tq7Qb1Ge1Cq8Ei7oZ7X8bqT0v7oXyY9cKqgHkP"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "ssh-ed25519"
+                }
+            }
+        },
+        "models.SSHKeyRequest": {
+            "type": "object",
+            "properties": {
+                "public_key": {
+                    "type": "string",
+                    "example": "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBeQk0Tq7Qb1Ge1Cq8Ei7oZ7X8bqT0v7oXyY9cKqgHkP partner@backup-host"
+                }
+            }
+        },
+        "models.ShareLink": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string",
+                    "example": "photos/alohadance.png"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "http://localhost:11682/download/7b2262223a22...e1f0"
+                }
+            }
+        },
+        "models.StorageEvent": {
+            "type": "object",
+            "properties": {
+                "file_name": {
+                    "type": "string",
+                    "example": "alohadance.png"
+                },
+                "new_name": {
+                    "type": "string",
+                    "example": "alohadance2.png"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "upload"
+                }
+            }
+        },
+        "models.SyncRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "срок действия ссылок в секундах",
+                    "type": "integer",
+                    "example": 3600
+                },
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ManifestEntry"
+                    }
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "backup/"
+                },
+                "presign": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.SyncResponse": {
+            "type": "object",
+            "properties": {
+                "delete": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "backup/"
+                },
+                "unchanged": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "upload": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SyncUpload"
+                    }
+                }
+            }
+        },
+        "models.SyncUpload": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "method": {
+                    "type": "string",
+                    "example": "PUT"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "photos/alohadance.png"
+                },
+                "reason": {
+                    "type": "string",
+                    "enum": [
+                        "missing",
+                        "size",
+                        "checksum",
+                        "checksum_unknown"
+                    ],
+                    "example": "missing"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UploadResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "alohadance.png"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "created",
+                        "overwritten",
+                        "renamed",
+                        "conflict",
+                        "locked",
+                        "invalid_name",
+                        "failed"
+                    ],
+                    "example": "renamed"
+                },
+                "stored_name": {
+                    "type": "string",
+                    "example": "alohadance (1).png"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Admin API token (ADMIN_TOKEN) in the form \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        },
+        "ApiKeyAuth": {
+            "description": "Account API key",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "ApiKeyQuery": {
+            "description": "Account API key in the query string. Deprecated: leaks into logs and browser history, disabled by API_KEY_IN_QUERY=false",
+            "type": "apiKey",
+            "name": "api",
+            "in": "query"
+        },
+        "BearerAuth": {
+            "description": "Account API key in the form \"Bearer \u003ckey\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -402,7 +3218,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/client/api/v1",
 	Schemes:          []string{"http"},
 	Title:            "CloudStorage",
-	Description:      "MinIO-base data storage",
+	Description:      "MinIO-base data storage\n\nEvery error is returned as JSON: {\"error\":{\"code\":\"...\",\"message\":\"...\",\"request_id\":\"...\",\"details\":{}}}.\nrequest_id matches the X-Request-Id response header and the server logs.\nCodes: bad_request, invalid_name, checksum_mismatch (400); api_key_required, api_key_invalid, unauthorized (401);\nforbidden, object_locked (403); not_found, storage_not_found (404); method_not_allowed (405);\nconflict, locking_not_supported (409); cursor_expired (410); too_large (413); quota_exceeded (507); internal (500);\nstorage_unavailable, database_unavailable, cache_unavailable, service_unavailable (503); timeout (504).",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
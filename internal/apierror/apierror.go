@@ -0,0 +1,185 @@
+package apierror
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/provisioning"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/minio/minio-go/v7"
+	"github.com/redis/go-redis/v9"
+)
+
+// Error - ошибка API: HTTP-статус, машиночитаемый код и текст, который можно показать клиенту.
+// Исходная ошибка хранится в Err и попадает только в лог
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details map[string]any
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// BadRequest - самая частая ошибка обработчиков: не хватает параметра или тело не разбирается
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, models.ErrCodeBadRequest, message)
+}
+
+// WithDetails - копия ошибки с дополнительным полем details
+func (e *Error) WithDetails(key string, value any) *Error {
+	copied := *e
+	copied.Details = maps.Clone(e.Details)
+	if copied.Details == nil {
+		copied.Details = map[string]any{}
+	}
+	copied.Details[key] = value
+	return &copied
+}
+
+// WithStatus - копия ошибки с другим статусом, когда тот же код значит разное для разных ручек
+func (e *Error) WithStatus(status int) *Error {
+	copied := *e
+	copied.Status = status
+	return &copied
+}
+
+// From - переводит ошибки хранилища, MinIO, Postgres и Redis в ошибку API
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	wrap := func(status int, code string, message string) *Error {
+		return &Error{Status: status, Code: code, Message: message, Err: err}
+	}
+
+	var nameErr *storage.NameError
+	var minioErr minio.ErrorResponse
+	isMinio := errors.As(err, &minioErr)
+	switch {
+	case isMinio && minioErr.Code == "NoSuchBucket":
+		return wrap(http.StatusNotFound, models.ErrCodeStorageNotFound, "storage is not provisioned")
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, pgx.ErrNoRows), errors.Is(err, redis.Nil),
+		errors.Is(err, postgres.ErrRuleNotFound), errors.Is(err, postgres.ErrSSHKeyNotFound),
+		errors.Is(err, postgres.ErrAccountNotFound):
+		return wrap(http.StatusNotFound, models.ErrCodeNotFound, "not found")
+	case errors.As(err, &nameErr):
+		// причина отказа сформулирована нами, ее можно показать клиенту
+		return wrap(http.StatusBadRequest, models.ErrCodeInvalidName, nameErr.Error())
+	case errors.Is(err, storage.ErrInvalidObjectName):
+		return wrap(http.StatusBadRequest, models.ErrCodeInvalidName, "invalid file name")
+	case errors.Is(err, storage.ErrObjectExists):
+		return wrap(http.StatusConflict, models.ErrCodeConflict, "file already exists")
+	case errors.Is(err, storage.ErrInvalidRetention):
+		return wrap(http.StatusBadRequest, models.ErrCodeBadRequest, err.Error())
+	case errors.Is(err, storage.ErrObjectLocked):
+		return wrap(http.StatusForbidden, models.ErrCodeObjectLocked, "file is protected by retention or legal hold")
+	case errors.Is(err, provisioning.ErrInvalidPermissions), errors.Is(err, provisioning.ErrInvalidQuota):
+		return wrap(http.StatusBadRequest, models.ErrCodeBadRequest, err.Error())
+	case errors.Is(err, provisioning.ErrAccountRevoked), errors.Is(err, provisioning.ErrLegacyStorage):
+		return wrap(http.StatusConflict, models.ErrCodeConflict, err.Error())
+	case errors.Is(err, storage.ErrLockingNotSupported):
+		return wrap(http.StatusConflict, models.ErrCodeLockingNotSupported, "storage does not support object locking")
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return wrap(http.StatusGatewayTimeout, models.ErrCodeTimeout, "upstream timeout")
+	case isMinio:
+		return fromMinio(minioErr, wrap)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// класс 23 - нарушение ограничений: уникальность, внешние ключи
+		if strings.HasPrefix(pgErr.Code, "23") {
+			return wrap(http.StatusConflict, models.ErrCodeConflict, "conflicts with existing data")
+		}
+		return wrap(http.StatusInternalServerError, models.ErrCodeInternal, "Internal server error")
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return wrap(http.StatusServiceUnavailable, models.ErrCodeDatabaseUnavailable, "database is unavailable")
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return wrap(http.StatusGatewayTimeout, models.ErrCodeTimeout, "upstream timeout")
+		}
+		return wrap(http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable, "upstream service is unavailable")
+	}
+	if strings.HasPrefix(err.Error(), "redis: ") {
+		return wrap(http.StatusServiceUnavailable, models.ErrCodeCacheUnavailable, "cache is unavailable")
+	}
+	return wrap(http.StatusInternalServerError, models.ErrCodeInternal, "Internal server error")
+}
+
+func fromMinio(minioErr minio.ErrorResponse, wrap func(int, string, string) *Error) *Error {
+	switch minioErr.Code {
+	case "AccessDenied":
+		return wrap(http.StatusForbidden, models.ErrCodeForbidden, "access denied by storage")
+	case "InvalidBucketName", "XMinioInvalidObjectName", "InvalidObjectName", "KeyTooLongError":
+		return wrap(http.StatusBadRequest, models.ErrCodeInvalidName, "invalid file name")
+	case "EntityTooLarge":
+		return wrap(http.StatusRequestEntityTooLarge, models.ErrCodeTooLarge, "file is too large")
+	case "XMinioAdminBucketQuotaExceeded":
+		return wrap(http.StatusInsufficientStorage, models.ErrCodeQuotaExceeded, "storage quota exceeded")
+	case "RequestTimeout", "RequestTimeTooSkewed":
+		return wrap(http.StatusGatewayTimeout, models.ErrCodeTimeout, "storage timeout")
+	case "SlowDown", "ServiceUnavailable", "XMinioServerNotInitialized", "XMinioStorageFull":
+		return wrap(http.StatusServiceUnavailable, models.ErrCodeStorageUnavailable, "storage is unavailable")
+	}
+	return wrap(http.StatusInternalServerError, models.ErrCodeInternal, "Internal server error")
+}
+
+// RequestID - идентификатор запроса, который middleware положил в контекст
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value("requestId").(string)
+	return id
+}
+
+// Write - отвечает конвертом models.ErrorResponse. Ошибки 5xx пишутся в лог вместе с исходной ошибкой
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := From(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		if logger, ok := r.Context().Value("logger").(*slog.Logger); ok {
+			logger.Error("request failed", "error", err.Error(), "code", apiErr.Code, "request_id", RequestID(r),
+				"method", r.Method, "path", r.URL.Path, "place", tools.GetPlace())
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: models.APIError{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		RequestId: RequestID(r),
+		Details:   apiErr.Details,
+	}})
+}
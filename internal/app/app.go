@@ -2,10 +2,22 @@ package app
 
 import (
 	"CloudStorageProject-FileServer/internal/app/server"
+	"CloudStorageProject-FileServer/internal/changes"
 	"CloudStorageProject-FileServer/internal/database/postgres"
 	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/fetch"
+	"CloudStorageProject-FileServer/internal/gateway"
+	"CloudStorageProject-FileServer/internal/lifecycle"
 	"CloudStorageProject-FileServer/internal/metrics"
 	minioClient "CloudStorageProject-FileServer/internal/minio"
+	"CloudStorageProject-FileServer/internal/provisioning"
+	"CloudStorageProject-FileServer/internal/replication"
+	"CloudStorageProject-FileServer/internal/rpc"
+	"CloudStorageProject-FileServer/internal/sftpd"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/internal/storage/local"
+	"CloudStorageProject-FileServer/internal/storage/memory"
+	"CloudStorageProject-FileServer/internal/upload"
 	"CloudStorageProject-FileServer/pkg/closer"
 	"CloudStorageProject-FileServer/pkg/config"
 	"context"
@@ -18,7 +30,13 @@ import (
 
 type App struct {
 	fileServer   *server.Server
+	s3Gateway    *gateway.Server
+	sftpServer   *sftpd.Server
+	grpcServer   *rpc.Server
 	metricServer *metrics.MetricsServer
+	sweeper      *lifecycle.Sweeper
+	compactor    *changes.Compactor
+	replicator   *replication.Replicator
 	ctxCloser    *closer.Closer
 	logger       *slog.Logger
 	conf         *config.Config
@@ -28,15 +46,29 @@ func NewApp(ctx context.Context) (*App, error) {
 	logger := ctx.Value("logger").(*slog.Logger)
 	conf := ctx.Value("config").(*config.Config)
 
+	// без постоянного секрета ключи S3 менялись бы при каждом перезапуске
+	if conf.S3GatewayPort != "" && conf.S3GatewaySecret == "" {
+		return nil, fmt.Errorf("S3_GATEWAY_SECRET is required when S3_GATEWAY_PORT is set")
+	}
+
 	ctxCloser := closer.NewCloser(logger)
 
 	metric := metrics.NewCollector("CloudStorage")
 
 	metricServer := metrics.NewMetricsServer(ctx)
 
-	minio := minioClient.NewMinioClient(ctx, metric.Minio)
-	if err := minio.Init(); err != nil {
-		return nil, fmt.Errorf("minio init error: %w", err)
+	st, err := NewStorage(ctx, conf, metric)
+	if err != nil {
+		return nil, err
+	}
+	// репликация во вторичное хранилище оборачивает основное
+	var replicator *replication.Replicator
+	if conf.ReplicaEndpoint != "" {
+		replicator, err = replication.NewReplicator(ctx, st, metric.Replication)
+		if err != nil {
+			return nil, fmt.Errorf("replication init error: %w", err)
+		}
+		st = replicator
 	}
 
 	pgs, err := postgres.InitPostgres(ctx, metric.Postgres)
@@ -49,28 +81,119 @@ func NewApp(ctx context.Context) (*App, error) {
 		return nil, fmt.Errorf("redis init error: %w", err)
 	}
 
-	fileServer := server.NewServer(conf, logger, pgs, rds, minio, metric.HTTP)
+	// все изменения файлов пишутся в журнал для клиентов синхронизации
+	st = changes.NewJournal(ctx, st, pgs)
+	compactor := changes.NewCompactor(ctx, pgs)
+
+	// у каждого ключа из minio_keys должно быть хранилище
+	prov := provisioning.NewProvisioning(ctx, pgs, rds, st)
+	report, err := prov.Reconcile()
+	if err != nil {
+		logger.Error("storage reconcile failed", "error", err.Error())
+	} else {
+		logger.Info("storage reconciled", "created", len(report.Created), "failed", len(report.Failed),
+			"orphaned", len(report.Orphaned), "legacy", len(report.Legacy))
+	}
 
-	ctxCloser.Add("minio", minio.CloseConnection)
+	spool := upload.NewSpooler(conf, metric.Upload)
+	fetcher, err := fetch.NewFetcher(ctx, rds, st)
+	if err != nil {
+		return nil, fmt.Errorf("fetcher init error: %w", err)
+	}
+	signer, generated := upload.NewSigner(conf.PresignSecret)
+	if generated {
+		logger.Warn("PRESIGN_SECRET is not set, signed upload links work only until restart and only on this instance")
+	}
+	// S3-шлюз: ключи нужны и ему, и основному серверу, который выдает их аккаунтам
+	var s3keys *gateway.Keys
+	var s3Gateway *gateway.Server
+	if conf.S3GatewayPort != "" {
+		s3keys = gateway.NewKeys(conf.S3GatewaySecret, conf.S3GatewayRegion)
+		s3Gateway = gateway.NewServer(conf, logger, pgs, st, s3keys, metric.HTTP)
+	}
+	var sftpServer *sftpd.Server
+	if conf.SFTPPort != "" {
+		var generated bool
+		sftpServer, generated, err = sftpd.NewServer(conf, logger, pgs, rds, st)
+		if err != nil {
+			return nil, fmt.Errorf("sftp init error: %w", err)
+		}
+		if generated && conf.SFTPHostKey == "" {
+			logger.Warn("SFTP_HOST_KEY is not set, sftp clients will see a new host key after every restart")
+		} else if generated {
+			logger.Info("sftp host key generated", "path", conf.SFTPHostKey)
+		}
+	}
+	var grpcServer *rpc.Server
+	if conf.GRPCPort != "" {
+		grpcServer = rpc.NewServer(conf, logger, pgs, rds, st, spool)
+	}
+	fileServer := server.NewServer(conf, logger, pgs, rds, st, spool, fetcher, signer, s3keys, prov,
+		metric.HTTP)
+
+	sweeper := lifecycle.NewSweeper(ctx, pgs, rds, st)
+
+	// хуки выполняются по порядку: сначала серверы перестают принимать запросы и дожидаются текущих,
+	// потом фоновые задачи, и только затем закрываются хранилище, postgres и redis, которыми они пользуются
+	ctxCloser.Add("server", fileServer.Shutdown)
+	if s3Gateway != nil {
+		ctxCloser.Add("s3gateway", s3Gateway.Shutdown)
+	}
+	if sftpServer != nil {
+		ctxCloser.Add("sftp", sftpServer.Shutdown)
+	}
+	if grpcServer != nil {
+		ctxCloser.Add("grpc", grpcServer.Shutdown)
+	}
+	ctxCloser.Add("lifecycle", sweeper.Close)
+	ctxCloser.Add("changes", compactor.Close)
+	ctxCloser.Add("fetch", fetcher.Close)
+	ctxCloser.Add("storage", st.CloseConnection)
 	ctxCloser.Add("metrics", metricServer.Close)
 	ctxCloser.Add("postgres", pgs.CloseConnection)
 	ctxCloser.Add("redis", rds.CloseConnection)
-	ctxCloser.Add("server", fileServer.Shutdown)
 	return &App{
 		fileServer:   fileServer,
+		s3Gateway:    s3Gateway,
+		sftpServer:   sftpServer,
+		grpcServer:   grpcServer,
 		metricServer: metricServer,
+		sweeper:      sweeper,
+		compactor:    compactor,
+		replicator:   replicator,
 		ctxCloser:    ctxCloser,
 		logger:       logger,
 		conf:         conf,
 	}, nil
 }
 
+// NewStorage - создает драйвер хранилища, выбранный в STORAGE_DRIVER
+func NewStorage(ctx context.Context, conf *config.Config, metric *metrics.Collector) (storage.Storage, error) {
+	switch conf.StorageDriver {
+	case storage.DriverMinio, "":
+		minio := minioClient.NewMinioClient(ctx, metric.Minio)
+		if err := minio.Init(); err != nil {
+			return nil, fmt.Errorf("minio init error: %w", err)
+		}
+		return minio, nil
+	case storage.DriverLocal:
+		st, err := local.NewLocal(conf.StorageLocalDir)
+		if err != nil {
+			return nil, fmt.Errorf("local storage init error: %w", err)
+		}
+		return st, nil
+	case storage.DriverMemory:
+		return memory.NewMemory(), nil
+	}
+	return nil, fmt.Errorf("unknown storage driver: %s", conf.StorageDriver)
+}
+
 func (app *App) Start() error {
 	if app == nil || app.fileServer == nil || app.metricServer == nil || app.ctxCloser == nil {
 		return fmt.Errorf("application is nil")
 	}
 
-	errCh := make(chan error, 2)
+	errCh := make(chan error, 5)
 
 	go func() {
 		app.logger.Info("starting server", "port", app.fileServer.Port)
@@ -82,6 +205,43 @@ func (app *App) Start() error {
 		errCh <- app.metricServer.StartMetricsServer()
 	}()
 
+	if app.s3Gateway != nil {
+		go func() {
+			app.logger.Info("starting s3 gateway", "port", app.s3Gateway.Port, "region", app.conf.S3GatewayRegion)
+			errCh <- app.s3Gateway.Run()
+		}()
+	}
+
+	if app.sftpServer != nil {
+		go func() {
+			app.logger.Info("starting sftp", "port", app.sftpServer.Port)
+			errCh <- app.sftpServer.Run()
+		}()
+	}
+
+	if app.grpcServer != nil {
+		go func() {
+			app.logger.Info("starting grpc", "port", app.grpcServer.Port)
+			errCh <- app.grpcServer.Run()
+		}()
+	}
+
+	if app.replicator != nil {
+		app.logger.Info("starting replication", "endpoint", app.conf.ReplicaEndpoint, "workers", app.conf.ReplicaWorkers)
+		app.replicator.Start()
+	}
+
+	go func() {
+		app.logger.Info("starting lifecycle sweeper", "interval_minutes", app.conf.LifecycleSweepInterval)
+		app.sweeper.Run()
+	}()
+
+	go func() {
+		app.logger.Info("starting changes compaction", "retention_hours", app.conf.ChangesRetentionHours,
+			"max_per_account", app.conf.ChangesMaxPerAccount)
+		app.compactor.Run()
+	}()
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL)
 
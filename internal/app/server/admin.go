@@ -0,0 +1,241 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/middleware"
+	"CloudStorageProject-FileServer/internal/provisioning"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+)
+
+// adminHandler - ручки API администратора за проверкой токена ADMIN_TOKEN
+func adminHandler(token string, logs *slog.Logger) http.Handler {
+	admin := http.NewServeMux()
+	admin.HandleFunc("POST /admin/api/v1/accounts", createAccountFunc)
+	admin.HandleFunc("GET /admin/api/v1/accounts", listAccountsFunc)
+	admin.HandleFunc("GET /admin/api/v1/accounts/{id}", getAccountFunc)
+	admin.HandleFunc("POST /admin/api/v1/accounts/{id}/revoke", revokeAccountFunc)
+	admin.HandleFunc("POST /admin/api/v1/accounts/{id}/suspend", suspendAccountFunc)
+	admin.HandleFunc("POST /admin/api/v1/accounts/{id}/resume", resumeAccountFunc)
+	admin.HandleFunc("POST /admin/api/v1/accounts/{id}/rotate", rotateAccountFunc)
+	return middleware.ValidateAdmin(admin, token, logs)
+}
+
+// createAccountFunc - create account with a new api key: POST /admin/api/v1/accounts
+// createAccountFunc godoc
+// @Summary Create account
+// @Description Register a new API key and provision its storage. The key is returned only in this response.
+// @Description If the account is created but its quota could not be applied, the response lists it in warnings
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param account body models.CreateAccountRequest true "Account"
+// @Success 201 {object} models.AccountKey
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Admin token is invalid"
+// @Router /admin/api/v1/accounts [post]
+func createAccountFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	prov := r.Context().Value("provisioning").(*provisioning.Provisioning)
+	req := models.CreateAccountRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest("invalid request body"))
+		return
+	}
+	// email хранится в VARCHAR(100)
+	if _, err := mail.ParseAddress(req.Email); err != nil || len(req.Email) > 100 {
+		apierror.Write(w, r, apierror.BadRequest("email is invalid"))
+		return
+	}
+	account, err := prov.Create(req.Email, req.Permissions, req.QuotaMB)
+	if err != nil && account == nil {
+		logger.Error("create account error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	var warnings []string
+	if err != nil {
+		// аккаунт уже создан, а ключ показывается только сейчас - отдаем его вместе с предупреждением
+		logger.Warn("account storage is not fully set up", "account", account.Id, "error", err.Error(),
+			"place", tools.GetPlace())
+		warnings = append(warnings, "account storage is not fully set up: "+err.Error())
+	}
+	logger.Info("admin created account", "account", account.Id, "request_id", apierror.RequestID(r))
+	writeAccountKey(w, http.StatusCreated, account, warnings...)
+}
+
+// listAccountsFunc - list accounts: GET /admin/api/v1/accounts?status=active&email=example.com
+// listAccountsFunc godoc
+// @Summary List accounts
+// @Description Accounts in creation order. Keys are not returned
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param status query string false "Only accounts with this status" Enums(active, suspended, revoked)
+// @Param email query string false "Only accounts whose email contains this substring" example(example.com)
+// @Success 200 {array} models.Account
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Admin token is invalid"
+// @Router /admin/api/v1/accounts [get]
+func listAccountsFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	filter := models.AccountFilter{Status: r.URL.Query().Get("status"), Email: r.URL.Query().Get("email")}
+	switch filter.Status {
+	case "", models.KeyStatusActive, models.KeyStatusSuspended, models.KeyStatusRevoked:
+	default:
+		apierror.Write(w, r, apierror.BadRequest("status must be active, suspended or revoked"))
+		return
+	}
+	accounts, err := pgs.Accounts(filter)
+	if err != nil {
+		logger.Error("list accounts error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	result := make([]models.Account, 0, len(accounts))
+	for i := range accounts {
+		result = append(result, models.NewAccount(&accounts[i]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// getAccountFunc - get account by id: GET /admin/api/v1/accounts/{id}
+// getAccountFunc godoc
+// @Summary Get account
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Account id" example(12)
+// @Success 200 {object} models.Account
+// @Failure 401 {object} models.ErrorResponse "Admin token is invalid"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /admin/api/v1/accounts/{id} [get]
+func getAccountFunc(w http.ResponseWriter, r *http.Request) {
+	accountAction(w, r, "", func(prov *provisioning.Provisioning, id int) (*models.APIPGS, error) {
+		return prov.Account(id)
+	})
+}
+
+// revokeAccountFunc - revoke api key: POST /admin/api/v1/accounts/{id}/revoke
+// revokeAccountFunc godoc
+// @Summary Revoke API key
+// @Description The key stops working immediately on every protocol and cannot be restored. Files are kept
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Account id" example(12)
+// @Success 200 {object} models.Account
+// @Failure 401 {object} models.ErrorResponse "Admin token is invalid"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /admin/api/v1/accounts/{id}/revoke [post]
+func revokeAccountFunc(w http.ResponseWriter, r *http.Request) {
+	accountAction(w, r, "admin revoked account", (*provisioning.Provisioning).Revoke)
+}
+
+// suspendAccountFunc - suspend api key: POST /admin/api/v1/accounts/{id}/suspend
+// suspendAccountFunc godoc
+// @Summary Suspend API key
+// @Description The key stops working immediately until the account is resumed
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Account id" example(12)
+// @Success 200 {object} models.Account
+// @Failure 401 {object} models.ErrorResponse "Admin token is invalid"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Key is revoked"
+// @Router /admin/api/v1/accounts/{id}/suspend [post]
+func suspendAccountFunc(w http.ResponseWriter, r *http.Request) {
+	accountAction(w, r, "admin suspended account", (*provisioning.Provisioning).Suspend)
+}
+
+// resumeAccountFunc - resume suspended api key: POST /admin/api/v1/accounts/{id}/resume
+// resumeAccountFunc godoc
+// @Summary Resume API key
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Account id" example(12)
+// @Success 200 {object} models.Account
+// @Failure 401 {object} models.ErrorResponse "Admin token is invalid"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Key is revoked"
+// @Router /admin/api/v1/accounts/{id}/resume [post]
+func resumeAccountFunc(w http.ResponseWriter, r *http.Request) {
+	accountAction(w, r, "admin resumed account", (*provisioning.Provisioning).Resume)
+}
+
+// rotateAccountFunc - replace api key: POST /admin/api/v1/accounts/{id}/rotate
+// rotateAccountFunc godoc
+// @Summary Rotate API key
+// @Description Issue a new key for the account, the old one stops working immediately. S3 credentials
+// @Description change with the key and SSH keys of the account are deleted, files are kept.
+// @Description The new key is returned only in this response
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Account id" example(12)
+// @Success 200 {object} models.AccountKey
+// @Failure 401 {object} models.ErrorResponse "Admin token is invalid"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Key is revoked or storage is not migrated"
+// @Router /admin/api/v1/accounts/{id}/rotate [post]
+func rotateAccountFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	prov := r.Context().Value("provisioning").(*provisioning.Provisioning)
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("id must be a number"))
+		return
+	}
+	account, err := prov.Rotate(id)
+	if err != nil {
+		logger.Error("rotate api key error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	logger.Info("admin rotated api key", "account", account.Id, "request_id", apierror.RequestID(r))
+	writeAccountKey(w, http.StatusOK, account)
+}
+
+// accountAction - общая часть ручек над одним аккаунтом: id из пути, действие и аккаунт в ответе.
+// audit - запись в лог об изменении, пустая для чтения
+func accountAction(w http.ResponseWriter, r *http.Request, audit string,
+	action func(prov *provisioning.Provisioning, id int) (*models.APIPGS, error)) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	prov := r.Context().Value("provisioning").(*provisioning.Provisioning)
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("id must be a number"))
+		return
+	}
+	account, err := action(prov, id)
+	if err != nil {
+		logger.Error("account action error", "error", err.Error(), "account", id, "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	if audit != "" {
+		logger.Info(audit, "account", account.Id, "request_id", apierror.RequestID(r))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(models.NewAccount(account))
+}
+
+// writeAccountKey - ответ с ключом; ответ не должен оседать в кэшах прокси и браузера
+func writeAccountKey(w http.ResponseWriter, status int, account *models.APIPGS, warnings ...string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(models.AccountKey{Account: models.NewAccount(account), Key: account.Key,
+		Warnings: warnings})
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/metrics"
+	"CloudStorageProject-FileServer/internal/middleware"
+	"CloudStorageProject-FileServer/internal/provisioning"
+	"CloudStorageProject-FileServer/internal/storage/memory"
+	"CloudStorageProject-FileServer/pkg/config"
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const testAdminToken = "admin-secret"
+
+// testAccounts - записи аккаунтов вместо Postgres: для Provisioning и для проверки ключа в middleware
+type testAccounts struct {
+	provisioning.AccountStore
+	mu       sync.Mutex
+	accounts map[int]*models.APIPGS
+}
+
+func newTestAccounts(keys map[int]string) *testAccounts {
+	store := &testAccounts{accounts: map[int]*models.APIPGS{}}
+	for id, key := range keys {
+		store.accounts[id] = &models.APIPGS{Id: id, KeyHash: models.HashAPIKey(key),
+			StorageId: fmt.Sprintf("u-%d", id), Status: models.KeyStatusActive, Permissions: models.DefaultPermissions}
+	}
+	return store
+}
+
+func (a *testAccounts) AccountById(id int) (*models.APIPGS, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	account, ok := a.accounts[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *account
+	return &copied, nil
+}
+
+func (a *testAccounts) SetAccountStatus(id int, status string) (*models.APIPGS, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	account, ok := a.accounts[id]
+	if !ok {
+		return nil, postgres.ErrAccountNotFound
+	}
+	account.Status = status
+	copied := *account
+	return &copied, nil
+}
+
+func (a *testAccounts) RotateAPIKey(id int, newKey string) (*models.APIPGS, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	account, ok := a.accounts[id]
+	if !ok {
+		return nil, postgres.ErrAccountNotFound
+	}
+	account.KeyHash = models.HashAPIKey(newKey)
+	copied := *account
+	copied.Key = newKey
+	return &copied, nil
+}
+
+func (a *testAccounts) CheckApiExists(api string) *models.APIPGS {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, account := range a.accounts {
+		if account.KeyHash == models.HashAPIKey(api) {
+			copied := *account
+			return &copied
+		}
+	}
+	return nil
+}
+
+func (a *testAccounts) UpdateLastLogin(accountId int) error {
+	return nil
+}
+
+func testRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+	server := miniredis.RunT(t)
+	ctx := context.WithValue(context.Background(), "config", &config.Config{RedisHost: server.Host(),
+		RedisPort: server.Port()})
+	rds, err := redis.NewRedis(ctx, &metrics.RedisMetrics{
+		QueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"operation"}),
+		QueryTotal:    prometheus.NewCounterVec(prometheus.CounterOpts{Name: "total"}, []string{"operation", "status"}),
+		ErrorsTotal:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: "errors"}, []string{"operation"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rds
+}
+
+// testAdmin - API администратора с зависимостями в контексте, как в NewServer
+func testAdmin(t *testing.T, store *testAccounts) (http.Handler, *redis.Redis, *slog.Logger) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rds := testRedis(t)
+	ctx := context.WithValue(context.Background(), "config", &config.Config{})
+	ctx = context.WithValue(ctx, "logger", logger)
+	prov := provisioning.NewProvisioning(ctx, store, rds, memory.NewMemory())
+	handler := middleware.WithValue(adminHandler(testAdminToken, logger), "provisioning", prov)
+	return middleware.WithValue(handler, "logger", logger), rds, logger
+}
+
+// adminRequest - запрос к API администратора, в account - тело ответа с аккаунтом
+func adminRequest(handler http.Handler, method string, target string, token string,
+	account any) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if account != nil && w.Code < http.StatusBadRequest {
+		_ = json.NewDecoder(w.Body).Decode(account)
+	}
+	return w
+}
+
+func TestValidateAdmin(t *testing.T) {
+	handler, _, _ := testAdmin(t, newTestAccounts(map[int]string{1: "key-1"}))
+	for _, token := range []string{"", "wrong", testAdminToken + "x", "Bearer"} {
+		w := adminRequest(handler, http.MethodGet, "/admin/api/v1/accounts/1", token, nil)
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("token %q: %d", token, w.Code)
+		}
+	}
+	// заголовок без схемы Bearer не принимается даже с верным токеном
+	r := httptest.NewRequest(http.MethodGet, "/admin/api/v1/accounts/1", nil)
+	r.Header.Set("Authorization", testAdminToken)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("token without scheme: %d", w.Code)
+	}
+
+	account := models.Account{}
+	if w = adminRequest(handler, http.MethodGet, "/admin/api/v1/accounts/1", testAdminToken, &account); w.Code != http.StatusOK || account.Id != 1 {
+		t.Errorf("valid token: %d, %+v", w.Code, account)
+	}
+}
+
+func TestAdminAccountActions(t *testing.T) {
+	store := newTestAccounts(map[int]string{1: "key-1"})
+	handler, _, _ := testAdmin(t, store)
+	steps := []struct {
+		action string
+		status int
+		want   string
+	}{
+		{"suspend", http.StatusOK, models.KeyStatusSuspended},
+		{"suspend", http.StatusOK, models.KeyStatusSuspended},
+		{"resume", http.StatusOK, models.KeyStatusActive},
+		{"resume", http.StatusOK, models.KeyStatusActive},
+		{"revoke", http.StatusOK, models.KeyStatusRevoked},
+		// отозванный ключ не вернуть
+		{"resume", http.StatusConflict, ""},
+		{"suspend", http.StatusConflict, ""},
+		{"rotate", http.StatusConflict, ""},
+	}
+	for _, step := range steps {
+		account := models.Account{}
+		w := adminRequest(handler, http.MethodPost, "/admin/api/v1/accounts/1/"+step.action, testAdminToken, &account)
+		if w.Code != step.status {
+			t.Fatalf("%s: %d; want %d", step.action, w.Code, step.status)
+		}
+		if step.want != "" && account.Status != step.want {
+			t.Fatalf("%s: status %q; want %q", step.action, account.Status, step.want)
+		}
+	}
+	if w := adminRequest(handler, http.MethodPost, "/admin/api/v1/accounts/2/revoke", testAdminToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown account: %d", w.Code)
+	}
+	if w := adminRequest(handler, http.MethodPost, "/admin/api/v1/accounts/abc/revoke", testAdminToken, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("bad id: %d", w.Code)
+	}
+}
+
+func TestAdminRotate(t *testing.T) {
+	store := newTestAccounts(map[int]string{1: "key-1"})
+	handler, _, logger := testAdmin(t, store)
+	rotated := models.AccountKey{}
+	w := adminRequest(handler, http.MethodPost, "/admin/api/v1/accounts/1/rotate", testAdminToken, &rotated)
+	if w.Code != http.StatusOK || rotated.Key == "" || rotated.Key == "key-1" {
+		t.Fatalf("rotate: %d, %+v", w.Code, rotated)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Error("response with the key may be cached")
+	}
+	if account := middleware.Authenticate(rotated.Key, store, testRedis(t), logger); account == nil || account.Id != 1 {
+		t.Errorf("new key: %+v", account)
+	}
+	if account := middleware.Authenticate("key-1", store, testRedis(t), logger); account != nil {
+		t.Error("old key still works after rotation")
+	}
+}
+
+// TestAdminDropsCachedKey - ключ перестает работать сразу, хотя ValidateAPI уже закэшировал его в Redis
+func TestAdminDropsCachedKey(t *testing.T) {
+	for _, action := range []string{"revoke", "suspend", "rotate"} {
+		t.Run(action, func(t *testing.T) {
+			store := newTestAccounts(map[int]string{1: "key-1"})
+			handler, rds, logger := testAdmin(t, store)
+			keyHash := models.HashAPIKey("key-1")
+			if account := middleware.Authenticate("key-1", store, rds, logger); account == nil {
+				t.Fatal("active key is rejected")
+			}
+			cached, err := rds.GetAPIField(keyHash)
+			if err != nil || cached == nil {
+				t.Fatalf("key is not cached: %v", err)
+			}
+			// параллельный Authenticate уже прочитал базу до изменения и собирается записать кэш
+			version, err := rds.APIFieldVersion(keyHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if w := adminRequest(handler, http.MethodPost, "/admin/api/v1/accounts/1/"+action, testAdminToken, nil); w.Code != http.StatusOK {
+				t.Fatalf("%s: %d", action, w.Code)
+			}
+			if cached, _ = rds.GetAPIField(keyHash); cached != nil {
+				t.Errorf("cache entry survived %s", action)
+			}
+			if err = rds.SetAPIField(staleAccount(keyHash), version); !errors.Is(err, redis.ErrAPIFieldChanged) {
+				t.Errorf("stale cache write after %s: %v; want ErrAPIFieldChanged", action, err)
+			}
+			if account := middleware.Authenticate("key-1", store, rds, logger); account != nil {
+				t.Errorf("key works after %s: %+v", action, account)
+			}
+		})
+	}
+}
+
+// staleAccount - состояние аккаунта, прочитанное из базы до изменения
+func staleAccount(keyHash string) *models.APIPGS {
+	return &models.APIPGS{Id: 1, KeyHash: keyHash, StorageId: "u-1", Status: models.KeyStatusActive,
+		Permissions: models.DefaultPermissions}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// changesLimit - сколько изменений отдается за один запрос
+	changesLimit = 1000
+	// changesMaxWait - предел long-polling, в секундах
+	changesMaxWait = 60
+	// changesRecheck - как часто перечитывать журнал, если событие redis потерялось
+	changesRecheck = 5 * time.Second
+)
+
+// changesFunc - change feed by apikey: GET /changes?api=xxx&cursor=N&wait=30
+// changesFunc godoc
+// @Summary Storage change feed
+// @Description Ordered create, overwrite, delete and move events after the cursor. Without cursor returns the
+// @Description current cursor: list files once, then poll from it. With wait the request blocks until
+// @Description a change appears or wait seconds pass. 410 cursor_expired means the journal was compacted
+// @Description past the cursor and the client must do a full resync
+// @Tags files
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param cursor query string false "Cursor from the previous response" example(1042)
+// @Param wait query int false "Long-polling timeout in seconds, up to 60" example(30)
+// @Param limit query int false "Max changes per response, up to 1000" example(1000)
+// @Success 200 {object} models.ChangeFeed
+// @Failure 400 {object} models.ErrorResponse "Bad cursor"
+// @Failure 410 {object} models.ErrorResponse "Cursor expired, full resync required"
+// @Router /client/api/v1/changes [get]
+// @Router /api/v2/changes [get]
+func changesFunc(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	query := r.URL.Query()
+	limit := changesLimit
+	if val := query.Get("limit"); val != "" {
+		num, err := strconv.Atoi(val)
+		if err != nil || num <= 0 {
+			apierror.Write(w, r, apierror.BadRequest("limit must be a positive number"))
+			return
+		}
+		limit = min(num, changesLimit)
+	}
+	wait := 0
+	if val := query.Get("wait"); val != "" {
+		num, err := strconv.Atoi(val)
+		if err != nil || num < 0 {
+			apierror.Write(w, r, apierror.BadRequest("wait must be a number of seconds"))
+			return
+		}
+		wait = min(num, changesMaxWait)
+	}
+
+	head, compacted, err := pgs.ChangesState(bucket)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	feed := models.ChangeFeed{Changes: []models.Change{}, Cursor: strconv.FormatInt(head, 10)}
+	// без курсора - стартовая точка: клиент читает полный список файлов и дальше идет от head
+	if query.Get("cursor") == "" {
+		writeChangeFeed(w, feed)
+		return
+	}
+	cursor, err := strconv.ParseInt(query.Get("cursor"), 10, 64)
+	if err != nil || cursor < 0 || cursor > head {
+		apierror.Write(w, r, apierror.BadRequest("invalid cursor"))
+		return
+	}
+	if cursor < compacted {
+		apierror.Write(w, r, apierror.New(http.StatusGone, models.ErrCodeCursorExpired,
+			"cursor is too old, list all files and continue from the new cursor").
+			WithDetails("cursor", feed.Cursor))
+		return
+	}
+
+	// на один больше лимита, чтобы знать, осталось ли что-то еще
+	changes, err := pgs.Changes(bucket, cursor, limit+1)
+	if err == nil && len(changes) == 0 && wait > 0 {
+		changes, err = waitChanges(r, bucket, cursor, limit+1, time.Duration(wait)*time.Second)
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if len(changes) > limit {
+		changes = changes[:limit]
+		feed.HasMore = true
+	}
+	feed.Changes = changes
+	feed.Cursor = strconv.FormatInt(cursor, 10)
+	if len(changes) > 0 {
+		feed.Cursor = strconv.FormatInt(changes[len(changes)-1].Id, 10)
+	}
+	writeChangeFeed(w, feed)
+}
+
+// waitChanges - long-polling: ждет события хранилища из redis и перечитывает журнал.
+// Redis только будит запрос, источник правды - журнал, поэтому он перечитывается и по таймеру
+func waitChanges(r *http.Request, bucket string, cursor int64, limit int, wait time.Duration) ([]models.Change, error) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	rds := r.Context().Value("redis").(*redis.Redis)
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+
+	events, err := rds.SubscribeEvents(ctx, bucket)
+	if err != nil {
+		logger.Warn("subscribe storage events error, falling back to polling", "error", err.Error(),
+			"place", tools.GetPlace())
+	}
+	ticker := time.NewTicker(changesRecheck)
+	defer ticker.Stop()
+	for {
+		// подписка уже есть, поэтому изменение между запросом и ожиданием не потеряется
+		changes, errChanges := pgs.Changes(bucket, cursor, limit)
+		if errChanges != nil || len(changes) > 0 {
+			return changes, errChanges
+		}
+		select {
+		case <-ctx.Done():
+			return changes, nil
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+func writeChangeFeed(w http.ResponseWriter, feed models.ChangeFeed) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(feed)
+}
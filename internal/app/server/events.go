@@ -0,0 +1,93 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// sseHeartbeat - как часто отправлять комментарий-пинг, чтобы прокси не рвали соединение
+const sseHeartbeat = 25 * time.Second
+
+// publishEvent - рассылает событие хранилища через redis pub/sub, ошибка только логируется
+func publishEvent(r *http.Request, bucket string, eventType string, fileName string, newName string) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	rds := r.Context().Value("redis").(*redis.Redis)
+	event := models.StorageEvent{
+		Type:     eventType,
+		FileName: fileName,
+		NewName:  newName,
+		Time:     time.Now(),
+	}
+	if err := rds.PublishEvent(bucket, event); err != nil {
+		logger.Error("publish storage event error", "error", err.Error(), "event", eventType,
+			"place", tools.GetPlace())
+	}
+}
+
+// eventsFunc - поток событий хранилища пользователя: GET /events?api=xxx
+// eventsFunc godoc
+// @Summary Storage events stream
+// @Description Server-Sent Events stream with upload, delete and rename events of the user storage
+// @Tags files
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Success 200 {object} models.StorageEvent
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /client/api/v1/events [get]
+func eventsFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	bucket := r.Context().Value("bucket").(string)
+	rds := r.Context().Value("redis").(*redis.Redis)
+
+	events, err := rds.SubscribeEvents(r.Context(), bucket)
+	if err != nil {
+		logger.Error("subscribe storage events error", "error", err.Error(), "client", r.RemoteAddr,
+			"place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err = controller.Flush(); err != nil {
+		logger.Error("sse flush is not supported", "error", err.Error(), "place", tools.GetPlace())
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err = fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(event)
+			if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+		}
+		if err = controller.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/fetch"
+	"CloudStorageProject-FileServer/pkg/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// uploadURLFunc - save a file from URL by apikey: POST /upload-url?api=xxx
+// uploadURLFunc godoc
+// @Summary Upload a file from URL
+// @Description The server downloads the file itself and saves it to the storage. Private and loopback addresses
+// @Description are not allowed unless listed in FETCH_ALLOWED_NETWORKS. Progress is polled by the returned job id
+// @Tags files
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param request body models.FetchRequest true "Source URL and target path"
+// @Success 202 {object} models.FetchJob
+// @Header 202 {string} Location "Job status URL"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 403 {object} models.ErrorResponse "Address is not allowed"
+// @Failure 503 {object} models.ErrorResponse "Too many jobs"
+// @Router /client/api/v1/upload-url [post]
+func uploadURLFunc(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	fetcher := r.Context().Value("fetcher").(*fetch.Fetcher)
+	var request models.FetchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Url == "" {
+		apierror.Write(w, r, apierror.BadRequest("url is required"))
+		return
+	}
+
+	job, err := fetcher.Submit(bucket, request)
+	switch {
+	case errors.Is(err, fetch.ErrInvalidRequest):
+		apierror.Write(w, r, apierror.BadRequest(err.Error()))
+		return
+	case errors.Is(err, fetch.ErrForbiddenAddress):
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, models.ErrCodeForbidden, err.Error()))
+		return
+	case errors.Is(err, fetch.ErrQueueFull):
+		apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable, err.Error()))
+		return
+	case err != nil:
+		apierror.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/client/api/v1/upload-jobs/"+job.Id)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// uploadJobFunc - upload from URL progress by apikey: GET /upload-jobs/{id}?api=xxx
+// uploadJobFunc godoc
+// @Summary Upload from URL progress
+// @Description Status and downloaded bytes of the job. Jobs are kept for 24 hours
+// @Tags files
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param id path string true "Job id"
+// @Success 200 {object} models.FetchJob
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /client/api/v1/upload-jobs/{id} [get]
+func uploadJobFunc(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	rds := r.Context().Value("redis").(*redis.Redis)
+	job, err := rds.GetFetchJob(bucket, r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if job == nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, models.ErrCodeNotFound, "job not found"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}
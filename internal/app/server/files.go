@@ -0,0 +1,178 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/internal/upload"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checksumHeader - sha256 содержимого в hex: клиент может прислать его в PUT для проверки, HEAD его возвращает
+const checksumHeader = "X-Checksum-Sha256"
+
+// fileInfo - метаданные файла в JSON-форме
+func fileInfo(info *models.ObjectInfo) models.FileInfo {
+	file := models.FileInfo{
+		Name:        info.Key,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ETag:        quoteETag(info.ETag),
+		Sha256:      info.Checksum,
+		IsDir:       info.IsDir,
+	}
+	// у "папок" нет времени изменения
+	if !info.LastModified.IsZero() {
+		file.LastModified = info.LastModified.UTC().Format(time.RFC3339)
+	}
+	return file
+}
+
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// setFileHeaders - метаданные файла в заголовках ответа
+func setFileHeaders(w http.ResponseWriter, info *models.ObjectInfo) {
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	if info.ETag != "" {
+		w.Header().Set("ETag", quoteETag(info.ETag))
+	}
+	if info.Checksum != "" {
+		w.Header().Set(checksumHeader, info.Checksum)
+		// RFC 9530: дайджест в base64
+		if sum, err := hex.DecodeString(info.Checksum); err == nil {
+			w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum)+":")
+		}
+	}
+}
+
+// putFile - загружает тело запроса в хранилище как один файл и проверяет контрольную сумму.
+// true - файл был создан, false - перезаписан
+func putFile(r *http.Request, st storage.Storage, bucket string, name string) (bool, error) {
+	spool := r.Context().Value("spooler").(*upload.Spooler)
+	return spool.Store(st, bucket, models.FileMinio{
+		FileName:    name,
+		Reader:      r.Body,
+		Size:        r.ContentLength,
+		ContentType: r.Header.Get("Content-Type"),
+		Checksum:    r.Header.Get(checksumHeader),
+	})
+}
+
+// putFileFunc - upload raw request body as a file: PUT /files/{path}?api=xxx
+// putFileFunc godoc
+// @Summary Upload a file from the raw body
+// @Description Stream the request body into the user storage under the given path. Send X-Checksum-Sha256 to verify the content
+// @Tags files
+// @Accept octet-stream
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param path path string true "File path" example(photos/alohadance.png)
+// @Param X-Checksum-Sha256 header string false "Expected sha256 of the body in hex"
+// @Param file body string true "File content"
+// @Success 200 {object} models.FileInfo "File overwritten"
+// @Success 201 {object} models.FileInfo "File created"
+// @Failure 400 {object} models.ErrorResponse "Bad request or checksum mismatch"
+// @Failure 409 {object} models.ErrorResponse "File is protected by retention or legal hold"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /client/api/v1/files/{path} [put]
+// @Router /api/v2/files/{path} [put]
+func putFileFunc(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
+	name := r.PathValue("path")
+	if name == "" {
+		apierror.Write(w, r, apierror.BadRequest("path is required"))
+		return
+	}
+	name, err := storage.SanitizeObjectName(name)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	servePut(w, r, st, bucket, name)
+}
+
+// servePut - загружает тело запроса в файл и отвечает его метаданными: 201 для нового файла, 200 для перезаписи
+func servePut(w http.ResponseWriter, r *http.Request, st storage.Storage, bucket string, name string) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	created, err := putFile(r, st, bucket, name)
+	switch {
+	case errors.Is(err, upload.ErrChecksumMismatch):
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, models.ErrCodeChecksumMismatch, err.Error()))
+		return
+	case errors.Is(err, storage.ErrObjectLocked):
+		apierror.Write(w, r, apierror.From(err).WithStatus(http.StatusConflict))
+		return
+	case err != nil:
+		logger.Error("put file error", "error", err.Error(), "client", r.RemoteAddr, "file", name,
+			"place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	publishEvent(r, bucket, models.EventUpload, name, "")
+
+	info, err := st.Stat(bucket, name)
+	if err != nil {
+		logger.Error("stat uploaded file error", "error", err.Error(), "file", name, "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", quoteETag(info.ETag))
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	_ = json.NewEncoder(w).Encode(fileInfo(info))
+}
+
+// headFileFunc - file metadata without the body: HEAD /files/{path}?api=xxx
+// headFileFunc godoc
+// @Summary File metadata
+// @Description Size, ETag, Content-Type, Last-Modified and sha256 of the file in response headers
+// @Tags files
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param path path string true "File path" example(photos/alohadance.png)
+// @Success 200 "OK"
+// @Header 200 {string} ETag "Entity tag"
+// @Header 200 {string} Last-Modified "Last modification time"
+// @Header 200 {string} X-Checksum-Sha256 "sha256 of the content in hex, if known"
+// @Header 200 {string} Repr-Digest "sha256 of the content (RFC 9530), if known"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /client/api/v1/files/{path} [head]
+// @Router /api/v2/files/{path} [head]
+func headFileFunc(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
+
+	info, err := st.Stat(bucket, r.PathValue("path"))
+	switch {
+	case errors.Is(err, storage.ErrInvalidObjectName):
+		apierror.Write(w, r, apierror.From(storage.ErrNotFound))
+		return
+	case err != nil:
+		apierror.Write(w, r, err)
+		return
+	}
+	setFileHeaders(w, info)
+	w.WriteHeader(http.StatusOK)
+}
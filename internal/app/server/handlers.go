@@ -1,15 +1,19 @@
 package server
 
 import (
-	minioClient "CloudStorageProject-FileServer/internal/minio"
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/internal/upload"
 	"CloudStorageProject-FileServer/pkg/models"
 	"CloudStorageProject-FileServer/pkg/tools"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 )
 
@@ -20,55 +24,48 @@ import (
 // @Tags files
 // @Accept json
 // @Produce json
-// @Param apikey query string true "APIKEY (UUID)" example(60601fee-2bf1-4721-ae6f-7636e79a0cba)
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
 // @Param filename query string true "File name" example(alohadance.png)
 // @Success 200 {object} models.FileInfo
-// @Failure 400 {object} string "Bad Request"
-// @Failure 405 {object} string "Method not allowed"
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 405 {object} models.ErrorResponse "Method not allowed"
 // @Router /client/api/v1/get-file [get]
 func getFileFunc(w http.ResponseWriter, r *http.Request) {
 	var logger = r.Context().Value("logger").(*slog.Logger)
 	// Если метод не тот
 	if r.Method != "GET" {
-		logger.Warn(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
-			r.RemoteAddr, r.URL, r.Method, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		logger.Warn("user uses not allowed method", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
+			"place", tools.GetPlace())
+		apierror.Write(w, r, apierror.New(http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed, "method not allowed"))
 		return
 	}
 	// Получаем нужные параметры строки запроса
-	api := r.URL.Query().Get("api")
+	bucket := r.Context().Value("bucket").(string)
 	filename := r.URL.Query().Get("filename")
 	// Если названия файла в параметре строки нет
 	if filename == "" {
-		logger.Warn(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: bad filename parameter",
-			r.RemoteAddr, r.URL, r.Method, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "filename is required", http.StatusBadRequest)
+		logger.Warn("bad filename parameter", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
+			"place", tools.GetPlace())
+		apierror.Write(w, r, apierror.BadRequest("filename is required"))
 		return
 	}
-	// Достаем minio-пул из контекста
-	Minio := r.Context().Value("minio").(*minioClient.MinioClient)
+	// Достаем хранилище из контекста
+	st := r.Context().Value("storage").(storage.Storage)
 
-	// Получаем запрошенный файл из minio
-	fileMinio, err := Minio.GetOne(api, filename)
+	// Получаем запрошенный файл из хранилища
+	file, stat, err := st.GetOne(bucket, filename)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: get minio-file error:%v",
-			r.RemoteAddr, r.URL, r.Method, err, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	// получаем характеристики файла
-	stat, errStat := fileMinio.Stat()
-	if errStat != nil {
-		logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: minio-file error:%v",
-			r.RemoteAddr, r.URL, r.Method, errStat, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, errStat.Error(), http.StatusBadRequest)
+		apierror.Write(w, r, err)
 		return
 	}
+	defer func() { _ = file.Close() }()
 	// Устанавливаем необходимые заголовки и возвращаем результат
 	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", stat.Size))
-	_, _ = io.Copy(w, fileMinio)
+	_, _ = io.Copy(w, file)
 	return
 }
 
@@ -79,36 +76,49 @@ func getFileFunc(w http.ResponseWriter, r *http.Request) {
 // @Tags files
 // @Accept json
 // @Produce json
-// @Param api query string true "APIKEY (UUID)" example(60601fee-2bf1-4721-ae6f-7636e79a0cba)
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
 // @Param file formData file true "File to upload"
+// @Param on_conflict query string false "What to do if the file already exists. Best-effort: concurrent uploads of the same name are not serialized" Enums(overwrite, rename, fail) default(overwrite)
 // @Success 200 {object} models.FileResponse
-// @Failure 405 {object} string "Method not allowed"
-// @Failure 500 {object} string "Internal server error"
+// @Failure 405 {object} models.ErrorResponse "Method not allowed"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 409 {object} models.FileResponse "Some files are protected by retention or legal hold or already exist"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /client/api/v1/upload-files [post]
 func storeFilesFunc(w http.ResponseWriter, r *http.Request) {
 	// Берем логгер из контекста
 	logger := r.Context().Value("logger").(*slog.Logger)
 	// Если метод не тот
 	if r.Method != "POST" {
-		logger.Warn(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
-			r.RemoteAddr, r.URL, r.Method, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		logger.Warn("user uses not allowed method", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
+			"place", tools.GetPlace())
+		apierror.Write(w, r, apierror.New(http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed, "method not allowed"))
+		return
+	}
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
+	onConflict, ok := storage.ValidConflictPolicy(r.URL.Query().Get("on_conflict"))
+	if !ok {
+		apierror.Write(w, r, apierror.BadRequest("on_conflict must be overwrite, rename or fail"))
 		return
 	}
-	api := r.URL.Query().Get("api")
-	minio := r.Context().Value("minio").(*minioClient.MinioClient)
 	// MultipartReader для чтения form-data
 	reader, err := r.MultipartReader()
 	if err != nil {
-		logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: multipartReader error:%v",
-			r.RemoteAddr, r.URL, r.Method, err, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "Openfile error", http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.BadRequest("multipart/form-data body is required"))
 		return
 	}
 
-	// слайсы для загруженных файлов и ошибок
+	// итог по каждому файлу, имена загруженных файлов и ошибки
+	var results []models.UploadResult
 	var uploaded []string
-	var errors []string
+	var uploadErrors []string
+	// файлы, которые нельзя перезаписать из-за срока хранения или legal hold
+	var locked []string
+	// файлы, пропущенные по on_conflict=fail
+	var conflicts []string
 
 	// Читаем части multipart формы по очереди
 	for {
@@ -117,10 +127,10 @@ func storeFilesFunc(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 		if errNext != nil {
-			logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: nextPart error:%v",
-				r.RemoteAddr, r.URL, r.Method, errNext, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-			errors = append(errors, fmt.Sprintf("Error reading part: %v", errNext))
-			continue
+			logger.Error("nextPart error", "error", errNext.Error(), "client", r.RemoteAddr, "url", r.URL,
+				"method", r.Method, "place", tools.GetPlace())
+			uploadErrors = append(uploadErrors, "Error reading part: "+apierror.From(errNext).Message)
+			break
 		}
 
 		// Проверяем, что это файл (а не поле формы)
@@ -129,70 +139,28 @@ func storeFilesFunc(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		// Создаем временный файл для партишиона
-		tempFile, errTemp := os.CreateTemp("", "upload-*")
-		if errTemp != nil {
-			logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: create temp file error:%v",
-				r.RemoteAddr, r.URL, r.Method, errTemp, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-			_ = part.Close()
-			errors = append(errors, fmt.Sprintf("Error creating temp file for %s: %v", part.FileName(), errTemp))
-			continue
-		}
-		tempFileName := tempFile.Name()
-
-		// Копируем данные из part во временный файл
-		fileSize, errCopy := io.Copy(tempFile, part)
+		result := storePart(r, part, bucket, onConflict)
+		// дочитываем part, если файл был пропущен
 		_ = part.Close()
-		_ = tempFile.Close()
-
-		if errCopy != nil {
-			logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: copy part to temp error:%v",
-				r.RemoteAddr, r.URL, r.Method, errCopy, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-			_ = os.Remove(tempFileName)
-			errors = append(errors, fmt.Sprintf("Error saving %s: %v", part.FileName(), errCopy))
-			continue
-		}
-
-		// Теперь открываем временный файл для чтения и загружаем в MinIO
-		fileForUpload, errOpen := os.Open(tempFileName)
-		if errOpen != nil {
-			logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: open temp error:%v",
-				r.RemoteAddr, r.URL, r.Method, errOpen, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-			_ = os.Remove(tempFileName)
-			errors = append(errors, fmt.Sprintf("Error reopening %s: %v", part.FileName(), errOpen))
-			continue
-		}
-
-		contentType := "application/octet-stream"
-
-		filePartition := models.FileMinio{
-			FileName:    part.FileName(),
-			Reader:      fileForUpload,
-			Size:        fileSize,
-			ContentType: contentType,
-		}
-
-		uploadErr := minio.CreateOne(api, filePartition)
-
-		// Закрываем и удаляем временный файл
-		_ = fileForUpload.Close()
-		_ = os.Remove(tempFileName)
+		results = append(results, result)
 
-		if uploadErr != nil {
-			logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: upload file to minio error:%v",
-				r.RemoteAddr, r.URL, r.Method, uploadErr, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-			errors = append(errors, fmt.Sprintf("Error uploading %s: %v", part.FileName(), uploadErr))
-			continue
+		switch result.Status {
+		case models.UploadLocked:
+			locked = append(locked, result.Name)
+		case models.UploadConflict:
+			conflicts = append(conflicts, result.Name)
+		case models.UploadInvalidName, models.UploadFailed:
+			uploadErrors = append(uploadErrors, fmt.Sprintf("Error uploading %s: %s", result.Name, result.Error))
+		default:
+			uploaded = append(uploaded, result.StoredName)
 		}
-
-		uploaded = append(uploaded, part.FileName())
 	}
 
 	// Получаем список файлов
-	fileList, errList := minio.FilesList(api)
+	fileList, errList := filesList(r, st, bucket)
 	if errList != nil {
-		logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: open temp error:%v",
-			r.RemoteAddr, r.URL, r.Method, errList, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
+		logger.Error("open temp error", "error", errList.Error(), "client", r.RemoteAddr, "url", r.URL,
+			"method", r.Method, "place", tools.GetPlace())
 		fileList = []models.FileWebResponse{}
 	}
 
@@ -202,17 +170,92 @@ func storeFilesFunc(w http.ResponseWriter, r *http.Request) {
 		Message:       fmt.Sprintf("Successfully uploaded %d files", len(uploaded)),
 		NewFiles:      fileList,
 		UploadedFiles: uploaded,
+		OnConflict:    onConflict,
+		Files:         results,
 	}
 
-	if len(errors) > 0 {
-		response.Message = fmt.Sprintf("Uploaded %d files with %d errors", len(uploaded), len(errors))
+	if len(uploadErrors) > 0 {
+		response.Message = fmt.Sprintf("Uploaded %d files with %d errors", len(uploaded), len(uploadErrors))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if len(locked) > 0 {
+		response.Status = http.StatusConflict
+		response.Message = fmt.Sprintf("Uploaded %d files, %d files are protected by retention or legal hold: %s",
+			len(uploaded), len(locked), strings.Join(locked, ", "))
+		w.WriteHeader(http.StatusConflict)
+	} else if len(conflicts) > 0 {
+		response.Status = http.StatusConflict
+		response.Message = fmt.Sprintf("Uploaded %d files, %d files already exist: %s",
+			len(uploaded), len(conflicts), strings.Join(conflicts, ", "))
+		w.WriteHeader(http.StatusConflict)
+	}
 	bytes, _ := json.Marshal(response)
 	_, _ = w.Write(bytes)
 }
 
+// storePart - сохраняет файл из multipart-формы: нормализует имя и разрешает конфликт по политике on_conflict
+func storePart(r *http.Request, part *multipart.Part, bucket string, onConflict string) models.UploadResult {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	st := r.Context().Value("storage").(storage.Storage)
+	spool := r.Context().Value("spooler").(*upload.Spooler)
+	result := models.UploadResult{Name: part.FileName()}
+	failed := func(status string, err error) models.UploadResult {
+		result.Status = status
+		result.Error = apierror.From(err).Message
+		return result
+	}
+
+	name, err := storage.SanitizeObjectName(part.FileName())
+	if err != nil {
+		return failed(models.UploadInvalidName, err)
+	}
+	storedName, exists, err := storage.ResolveConflict(st, bucket, name, onConflict)
+	if errors.Is(err, storage.ErrObjectExists) {
+		return failed(models.UploadConflict, err)
+	}
+	if err != nil {
+		logger.Error("resolve upload conflict error", "error", err.Error(), "file", name, "place", tools.GetPlace())
+		return failed(models.UploadFailed, err)
+	}
+
+	// Маленький файл читается в память, большой идет в хранилище потоком прямо из part
+	body, err := spool.Prepare(part)
+	if err != nil {
+		logger.Error("prepare upload error", "error", err.Error(), "file", name, "place", tools.GetPlace())
+		return failed(models.UploadFailed, err)
+	}
+	// Освобождаем временный файл, если он был
+	defer body.Close()
+
+	err = st.CreateOne(bucket, models.FileMinio{
+		FileName:    storedName,
+		Reader:      body.Reader,
+		Size:        body.Size,
+		ContentType: "application/octet-stream",
+	})
+	if errors.Is(err, storage.ErrObjectLocked) {
+		return failed(models.UploadLocked, err)
+	}
+	if err != nil {
+		logger.Error("upload file to minio error", "error", err.Error(), "client", r.RemoteAddr, "url", r.URL,
+			"method", r.Method, "place", tools.GetPlace())
+		return failed(models.UploadFailed, err)
+	}
+
+	result.StoredName = storedName
+	switch {
+	case storedName != name:
+		result.Status = models.UploadRenamed
+	case exists:
+		result.Status = models.UploadOverwritten
+	default:
+		result.Status = models.UploadCreated
+	}
+	publishEvent(r, bucket, models.EventUpload, storedName, "")
+	return result
+}
+
 // deleteFilesFunc - delete file by apikey: DELETE /delete-file?api=xxx&filename=yyy
 // deleteFilesFunc godoc
 // @Summary Delete a file by api
@@ -220,43 +263,44 @@ func storeFilesFunc(w http.ResponseWriter, r *http.Request) {
 // @Tags files
 // @Accept json
 // @Produce json
-// @Param api query string true "APIKEY (UUID)" example(60601fee-2bf1-4721-ae6f-7636e79a0cba)
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
 // @Param filename query string true "File name" example(alohadance.png)
 // @Success 200 {object} models.FileResponse
-// @Failure 400 {object} string "Bad request"
-// @Failure 405 {object} string "Method not allowed"
-// @Failure 404 {object} string "Not found"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 403 {object} models.ErrorResponse "File is protected by retention or legal hold"
+// @Failure 405 {object} models.ErrorResponse "Method not allowed"
+// @Failure 404 {object} models.ErrorResponse "Not found"
 // @Router /client/api/v1/delete-file [delete]
 func deleteFilesFunc(w http.ResponseWriter, r *http.Request) {
 	logger := r.Context().Value("logger").(*slog.Logger)
 	if r.Method != "DELETE" {
-		logger.Warn(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
-			r.RemoteAddr, r.URL, r.Method, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		logger.Warn("user uses not allowed method", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
+			"place", tools.GetPlace())
+		apierror.Write(w, r, apierror.New(http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed, "method not allowed"))
 		return
 	}
-	api := r.URL.Query().Get("api")
+	bucket := r.Context().Value("bucket").(string)
 	filename := r.URL.Query().Get("filename")
 	if filename == "" {
-		logger.Warn(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: bad filename parameter",
-			r.RemoteAddr, r.URL, r.Method, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "filename is required", http.StatusBadRequest)
+		logger.Warn("bad filename parameter", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
+			"place", tools.GetPlace())
+		apierror.Write(w, r, apierror.BadRequest("filename is required"))
 		return
 	}
-	minio := r.Context().Value("minio").(*minioClient.MinioClient)
+	st := r.Context().Value("storage").(storage.Storage)
 
-	errDelete := minio.Delete(api, filename)
+	errDelete := st.Delete(bucket, filename)
+	// защищенный файл - 403 object_locked
 	if errDelete != nil {
-		logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: delete minio file error: %v",
-			r.RemoteAddr, r.URL, r.Method, errDelete, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "Error", http.StatusNotFound)
+		apierror.Write(w, r, errDelete)
 		return
 	}
-	fileList, errList := minio.FilesList(api)
+	publishEvent(r, bucket, models.EventDelete, filename, "")
+	fileList, errList := filesList(r, st, bucket)
 	if errList != nil {
-		logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: get minio files error: %v",
-			r.RemoteAddr, r.URL, r.Method, errList, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "Error", http.StatusNotFound)
+		apierror.Write(w, r, errList)
 		return
 	}
 
@@ -271,6 +315,64 @@ func deleteFilesFunc(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+// renameFileFunc - rename file by apikey: PATCH /rename-file?api=xxx&filename=yyy&new_name=zzz
+// renameFileFunc godoc
+// @Summary Rename a file by api
+// @Description Rename file by user apikey, current filename and new filename
+// @Tags files
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param filename query string true "File name" example(alohadance.png)
+// @Param new_name query string true "New file name" example(alohadance2.png)
+// @Success 200 {object} models.FileResponse
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 403 {object} models.ErrorResponse "File is protected by retention or legal hold"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /client/api/v1/rename-file [patch]
+func renameFileFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	bucket := r.Context().Value("bucket").(string)
+	filename := r.URL.Query().Get("filename")
+	newName := r.URL.Query().Get("new_name")
+	if filename == "" || newName == "" {
+		logger.Warn("bad filename or new_name parameter", "client", r.RemoteAddr, "url", r.URL,
+			"method", r.Method, "place", tools.GetPlace())
+		apierror.Write(w, r, apierror.BadRequest("filename and new_name are required"))
+		return
+	}
+	st := r.Context().Value("storage").(storage.Storage)
+
+	newName, err := storage.SanitizeObjectName(newName)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	errRename := st.Rename(bucket, filename, newName)
+	// защищенный файл - 403 object_locked
+	if errRename != nil {
+		apierror.Write(w, r, errRename)
+		return
+	}
+	publishEvent(r, bucket, models.EventRename, filename, newName)
+	fileList, errList := filesList(r, st, bucket)
+	if errList != nil {
+		apierror.Write(w, r, errList)
+		return
+	}
+
+	response := models.FileResponse{
+		Status:   200,
+		Message:  "success",
+		NewFiles: fileList,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	bytes, _ := json.Marshal(response)
+	_, _ = w.Write(bytes)
+}
+
 // getFilesListFunc - get user file list by apikey: GET /delete-file?api=xxx
 // getFilesListFunc godoc
 // @Summary Get user file list by api
@@ -278,28 +380,28 @@ func deleteFilesFunc(w http.ResponseWriter, r *http.Request) {
 // @Tags files
 // @Accept json
 // @Produce json
-// @Param api query string true "APIKEY (UUID)" example(60601fee-2bf1-4721-ae6f-7636e79a0cba)
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
 // @Success 200 {object} models.FileWebResponse
-// @Failure 405 {object} string "Method not allowed"
-// @Failure 404 {object} string "Not found"
+// @Failure 405 {object} models.ErrorResponse "Method not allowed"
+// @Failure 404 {object} models.ErrorResponse "Not found"
 // @Router /client/api/v1/get-files-list [get]
 func getFilesListFunc(w http.ResponseWriter, r *http.Request) {
 	// пример запроса: POST /client/api/v1/get-files-list?api=api_key
 	logger := r.Context().Value("logger").(*slog.Logger)
 	if r.Method != "GET" {
-		logger.Warn(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
-			r.RemoteAddr, r.URL, r.Method, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		logger.Warn("user uses not allowed method", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
+			"place", tools.GetPlace())
+		apierror.Write(w, r, apierror.New(http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed, "method not allowed"))
 		return
 	}
-	api := r.URL.Query().Get("api")
-	minio := r.Context().Value("minio").(*minioClient.MinioClient)
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
 
-	files, err := minio.FilesList(api)
+	files, err := filesList(r, st, bucket)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: get minio files error: %v",
-			r.RemoteAddr, r.URL, r.Method, err, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "Error", http.StatusNotFound)
+		apierror.Write(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -316,23 +418,23 @@ func getFilesListFunc(w http.ResponseWriter, r *http.Request) {
 // @Produce html
 // @Success 200 {file} html "Login page"
 // @Success 302 {object} string "Found"
-// @Failure 405 {object} string "Method not allowed"
+// @Failure 405 {object} models.ErrorResponse "Method not allowed"
 // @Router /index [get]
 func indexPage(w http.ResponseWriter, r *http.Request) {
 	logger := r.Context().Value("logger").(*slog.Logger)
 	if r.Method != "GET" {
-		logger.Warn(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
-			r.RemoteAddr, r.URL, r.Method, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		logger.Warn("user uses not allowed method", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
+			"place", tools.GetPlace())
+		apierror.Write(w, r, apierror.New(http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed, "method not allowed"))
 		return
 	}
 	TemplatePath := r.Context().Value("tmplPath").(string)
-	apikey, is := r.Cookie("apikey")
-	if is != nil {
+	if _, is := r.Cookie("apikey"); is != nil {
 		http.ServeFile(w, r, TemplatePath+"/index.html")
 		return
 	}
-	http.Redirect(w, r, fmt.Sprintf("/client/api/v1/storage?api=%s", apikey.Value), http.StatusFound)
+	// ключ страница хранилища берет из cookie, в адрес он не попадает
+	http.Redirect(w, r, "/client/api/v1/storage", http.StatusFound)
 	return
 }
 
@@ -344,18 +446,18 @@ func indexPage(w http.ResponseWriter, r *http.Request) {
 // @Produce html
 // @Success 200 {file} html "Storage page"
 // @Success 302 {object} string "Found"
-// @Failure 405 {object} string "Method not allowed"
+// @Failure 405 {object} models.ErrorResponse "Method not allowed"
 // @Router /client/api/v1/storage/ [get]
 func storagePage(w http.ResponseWriter, r *http.Request) {
 	logger := r.Context().Value("logger").(*slog.Logger)
 	if r.Method != "GET" {
-		logger.Warn(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
-			r.RemoteAddr, r.URL, r.Method, time.Now().Format("02.01.2006 15:04:05")), tools.GetPlace())
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		logger.Warn("user uses not allowed method", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
+			"place", tools.GetPlace())
+		apierror.Write(w, r, apierror.New(http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed, "method not allowed"))
 		return
 	}
 	TemplatePath := r.Context().Value("tmplPath").(string)
-	apikey := r.URL.Query().Get("api")
+	apikey := r.Context().Value("api").(string)
 	if apikey == "" || apikey == "undefined" {
 		http.Redirect(w, r, "/index", http.StatusFound)
 		return
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// filesList - список файлов пользователя с ближайшими срабатываниями правил жизненного цикла
+func filesList(r *http.Request, st storage.Storage, bucket string) ([]models.FileWebResponse, error) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	rules, err := pgs.LifecycleRules(bucket)
+	if err != nil {
+		// без правил список всё равно можно отдать, просто без даты истечения
+		logger.Error("get lifecycle rules error", "error", err.Error(), "place", tools.GetPlace())
+	}
+	return storage.FilesList(st, bucket, rules...)
+}
+
+// decodeLifecycleRule - читает и проверяет правило из тела запроса
+func decodeLifecycleRule(r *http.Request) (*models.LifecycleRule, error) {
+	rule := &models.LifecycleRule{}
+	if err := json.NewDecoder(r.Body).Decode(rule); err != nil {
+		return nil, err
+	}
+	if rule.Action == "" {
+		rule.Action = models.LifecycleActionDelete
+	}
+	if !rule.Valid() {
+		return nil, errors.New("days must be positive, action must be delete or trash, tag_value requires tag_key, trash rules cannot target .trash/")
+	}
+	// по префиксу правила обходчик строит список файлов
+	if err := storage.ValidatePrefix(rule.Prefix); err != nil {
+		return nil, err
+	}
+	rule.Bucket = r.Context().Value("bucket").(string)
+	return rule, nil
+}
+
+// getLifecycleRulesFunc - list lifecycle rules by apikey: GET /lifecycle-rules?api=xxx
+// getLifecycleRulesFunc godoc
+// @Summary List lifecycle rules
+// @Description Get lifecycle rules of the user storage
+// @Tags lifecycle
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Success 200 {array} models.LifecycleRule
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /client/api/v1/lifecycle-rules [get]
+func getLifecycleRulesFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	rules, err := pgs.LifecycleRules(r.Context().Value("bucket").(string))
+	if err != nil {
+		logger.Error("get lifecycle rules error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rules)
+}
+
+// createLifecycleRuleFunc - create lifecycle rule by apikey: POST /lifecycle-rules?api=xxx
+// createLifecycleRuleFunc godoc
+// @Summary Create lifecycle rule
+// @Description Files matching prefix (and tag, if set) are deleted or moved to trash after N days
+// @Tags lifecycle
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param rule body models.LifecycleRule true "Lifecycle rule"
+// @Success 201 {object} models.LifecycleRule
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /client/api/v1/lifecycle-rules [post]
+func createLifecycleRuleFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	rule, err := decodeLifecycleRule(r)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err.Error()))
+		return
+	}
+	if err = pgs.CreateLifecycleRule(rule); err != nil {
+		logger.Error("create lifecycle rule error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+// updateLifecycleRuleFunc - update lifecycle rule by apikey: PUT /lifecycle-rules?api=xxx&id=1
+// updateLifecycleRuleFunc godoc
+// @Summary Update lifecycle rule
+// @Description Replace prefix, tag, action and days of the lifecycle rule
+// @Tags lifecycle
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param id query int true "Rule id" example(1)
+// @Param rule body models.LifecycleRule true "Lifecycle rule"
+// @Success 200 {object} models.LifecycleRule
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /client/api/v1/lifecycle-rules [put]
+func updateLifecycleRuleFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("id is required"))
+		return
+	}
+	rule, err := decodeLifecycleRule(r)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err.Error()))
+		return
+	}
+	rule.Id = id
+	if err = pgs.UpdateLifecycleRule(rule); err != nil {
+		logger.Error("update lifecycle rule error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+// deleteLifecycleRuleFunc - delete lifecycle rule by apikey: DELETE /lifecycle-rules?api=xxx&id=1
+// deleteLifecycleRuleFunc godoc
+// @Summary Delete lifecycle rule
+// @Description Delete lifecycle rule of the user storage
+// @Tags lifecycle
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param id query int true "Rule id" example(1)
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /client/api/v1/lifecycle-rules [delete]
+func deleteLifecycleRuleFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("id is required"))
+		return
+	}
+	if err = pgs.DeleteLifecycleRule(r.Context().Value("bucket").(string), id); err != nil {
+		logger.Error("delete lifecycle rule error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
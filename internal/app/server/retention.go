@@ -0,0 +1,150 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// locker - хранилище из контекста, если драйвер поддерживает блокировки
+func locker(r *http.Request) (storage.Locker, error) {
+	st := r.Context().Value("storage").(storage.Storage)
+	lock, ok := st.(storage.Locker)
+	if !ok {
+		return nil, storage.ErrLockingNotSupported
+	}
+	return lock, nil
+}
+
+// retentionError - ответ на ошибку хранилища при работе с блокировками
+func retentionError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrObjectLocked) {
+		// COMPLIANCE нельзя сократить или снять
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, models.ErrCodeObjectLocked,
+			"retention can not be shortened or removed"))
+		return
+	}
+	// ErrLockingNotSupported: бакет создан без object locking или драйвер не умеет блокировки
+	apierror.Write(w, r, err)
+}
+
+// getRetentionFunc - get file protection by apikey: GET /retention?api=xxx&filename=yyy
+// getRetentionFunc godoc
+// @Summary Get file retention
+// @Description Get retention mode, retain-until date and legal hold of the file
+// @Tags retention
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param filename query string true "File name" example(alohadance.png)
+// @Success 200 {object} models.ObjectProtection
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /client/api/v1/retention [get]
+func getRetentionFunc(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		apierror.Write(w, r, apierror.BadRequest("filename is required"))
+		return
+	}
+	lock, err := locker(r)
+	if err != nil {
+		retentionError(w, r, err)
+		return
+	}
+	protection, err := lock.ObjectProtection(bucket, filename)
+	if err != nil {
+		retentionError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(protection)
+}
+
+// setRetentionFunc - set file retention by apikey: PUT /retention?api=xxx&filename=yyy
+// setRetentionFunc godoc
+// @Summary Set file retention
+// @Description Protect the file from deletion and overwrite until retain_until (WORM). Requires storage with object locking
+// @Tags retention
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param filename query string true "File name" example(alohadance.png)
+// @Param retention body models.RetentionRequest true "Retention mode and date"
+// @Success 200 {object} models.ObjectProtection
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 403 {object} models.ErrorResponse "Retention can not be shortened"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Object locking is not enabled"
+// @Router /client/api/v1/retention [put]
+func setRetentionFunc(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		apierror.Write(w, r, apierror.BadRequest("filename is required"))
+		return
+	}
+	var request models.RetentionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierror.Write(w, r, apierror.BadRequest("bad request body"))
+		return
+	}
+	lock, err := locker(r)
+	if err != nil {
+		retentionError(w, r, err)
+		return
+	}
+	if err = lock.SetRetention(bucket, filename, request); err != nil {
+		retentionError(w, r, err)
+		return
+	}
+	getRetentionFunc(w, r)
+}
+
+// setLegalHoldFunc - set file legal hold by apikey: PUT /legal-hold?api=xxx&filename=yyy
+// setLegalHoldFunc godoc
+// @Summary Set file legal hold
+// @Description Enable or disable legal hold: the file can not be deleted or overwritten while it is enabled
+// @Tags retention
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param filename query string true "File name" example(alohadance.png)
+// @Param legal_hold body models.LegalHoldRequest true "Legal hold status"
+// @Success 200 {object} models.ObjectProtection
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Object locking is not enabled"
+// @Router /client/api/v1/legal-hold [put]
+func setLegalHoldFunc(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		apierror.Write(w, r, apierror.BadRequest("filename is required"))
+		return
+	}
+	var request models.LegalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apierror.Write(w, r, apierror.BadRequest("bad request body"))
+		return
+	}
+	lock, err := locker(r)
+	if err != nil {
+		retentionError(w, r, err)
+		return
+	}
+	if err = lock.SetLegalHold(bucket, filename, request.Enabled); err != nil {
+		retentionError(w, r, err)
+		return
+	}
+	getRetentionFunc(w, r)
+}
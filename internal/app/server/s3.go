@@ -0,0 +1,49 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/gateway"
+	"CloudStorageProject-FileServer/pkg/models"
+	"encoding/json"
+	"net/http"
+)
+
+// s3CredentialsFunc - S3 gateway keys by apikey: GET /s3-credentials?api=xxx
+// s3CredentialsFunc godoc
+// @Summary S3 gateway credentials
+// @Description Access key, secret key and bucket for S3 clients (aws cli, rclone, SDK). Keys are derived
+// @Description from the account storage and API key: rotating the API key replaces the secret key, revoking or
+// @Description suspending it disables the S3 keys too. Requests must use path-style addressing and SigV4
+// @Description with the returned region
+// @Tags files
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Success 200 {object} models.S3Credentials
+// @Failure 404 {object} models.ErrorResponse "S3 gateway is disabled"
+// @Failure 409 {object} models.ErrorResponse "Storage must be migrated with cmd/migrate-storage first"
+// @Router /client/api/v1/s3-credentials [get]
+func s3CredentialsFunc(w http.ResponseWriter, r *http.Request) {
+	keys, _ := r.Context().Value("s3keys").(*gateway.Keys)
+	if keys == nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, models.ErrCodeNotFound, "s3 gateway is disabled"))
+		return
+	}
+	account := r.Context().Value("account").(*models.APIPGS)
+	// у неперенесенного аккаунта имя хранилища совпадает с ключом, отдавать его как access key нельзя
+	if !gateway.Usable(account) {
+		apierror.Write(w, r, apierror.New(http.StatusConflict, models.ErrCodeConflict,
+			"storage must be migrated with cmd/migrate-storage before s3 keys can be issued"))
+		return
+	}
+	accessKey, secretKey := keys.Credentials(account)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(models.S3Credentials{
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Bucket:    account.StorageId,
+		Region:    keys.Region(),
+	})
+}
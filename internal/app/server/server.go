@@ -3,9 +3,14 @@ package server
 import (
 	"CloudStorageProject-FileServer/internal/database/postgres"
 	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/dav"
+	"CloudStorageProject-FileServer/internal/fetch"
+	"CloudStorageProject-FileServer/internal/gateway"
 	"CloudStorageProject-FileServer/internal/metrics"
 	"CloudStorageProject-FileServer/internal/middleware"
-	minioClient "CloudStorageProject-FileServer/internal/minio"
+	"CloudStorageProject-FileServer/internal/provisioning"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/internal/upload"
 	consts "CloudStorageProject-FileServer/pkg/Constants"
 	"CloudStorageProject-FileServer/pkg/config"
 	"fmt"
@@ -29,7 +34,8 @@ type Server struct {
 }
 
 func NewServer(config *config.Config, logs *slog.Logger, pgs *postgres.Postgres, rds *redis.Redis,
-	minio *minioClient.MinioClient, metric *metrics.HTTPMetrics) *Server {
+	st storage.Storage, spool *upload.Spooler, fetcher *fetch.Fetcher, signer *upload.Signer, s3keys *gateway.Keys,
+	prov *provisioning.Provisioning, metric *metrics.HTTPMetrics) *Server {
 	router := http.NewServeMux()
 	// страницы
 	// для static элементов (папка static)
@@ -51,6 +57,57 @@ func NewServer(config *config.Config, logs *slog.Logger, pgs *postgres.Postgres,
 	router.HandleFunc("POST /client/api/v1/upload-files", storeFilesFunc)
 	router.HandleFunc("GET /client/api/v1/get-files-list", getFilesListFunc)
 	router.HandleFunc("DELETE /client/api/v1/delete-file", deleteFilesFunc)
+	router.HandleFunc("PATCH /client/api/v1/rename-file", renameFileFunc)
+	router.HandleFunc("PUT /client/api/v1/files/{path...}", putFileFunc)
+	router.HandleFunc("HEAD /client/api/v1/files/{path...}", headFileFunc)
+	// загрузка по URL
+	router.HandleFunc("POST /client/api/v1/upload-url", uploadURLFunc)
+	router.HandleFunc("GET /client/api/v1/upload-jobs/{id}", uploadJobFunc)
+	// правила жизненного цикла
+	router.HandleFunc("GET /client/api/v1/lifecycle-rules", getLifecycleRulesFunc)
+	router.HandleFunc("POST /client/api/v1/lifecycle-rules", createLifecycleRuleFunc)
+	router.HandleFunc("PUT /client/api/v1/lifecycle-rules", updateLifecycleRuleFunc)
+	router.HandleFunc("DELETE /client/api/v1/lifecycle-rules", deleteLifecycleRuleFunc)
+	// WORM-защита файлов (object locking)
+	router.HandleFunc("GET /client/api/v1/retention", getRetentionFunc)
+	router.HandleFunc("PUT /client/api/v1/retention", setRetentionFunc)
+	router.HandleFunc("PUT /client/api/v1/legal-hold", setLegalHoldFunc)
+	// события хранилища (SSE)
+	router.HandleFunc("GET /client/api/v1/events", eventsFunc)
+	// журнал изменений для клиентов синхронизации
+	router.HandleFunc("GET /client/api/v1/changes", changesFunc)
+	// синхронизация каталога по манифесту и загрузка по подписанной ссылке
+	router.HandleFunc("POST /client/api/v1/sync", syncFunc)
+	router.HandleFunc("PUT /upload/{token}", presignedUploadFunc)
+	// ссылки на скачивание без ключа
+	router.HandleFunc("POST /client/api/v1/share", shareFileFunc)
+	router.HandleFunc("GET /download/{token}", sharedDownloadFunc)
+	// ключи S3-шлюза
+	router.HandleFunc("GET /client/api/v1/s3-credentials", s3CredentialsFunc)
+	// ключи для входа по SFTP
+	router.HandleFunc("GET /client/api/v1/ssh-keys", getSSHKeysFunc)
+	router.HandleFunc("POST /client/api/v1/ssh-keys", createSSHKeyFunc)
+	router.HandleFunc("DELETE /client/api/v1/ssh-keys", deleteSSHKeyFunc)
+
+	// v2: ресурсные пути, ключ файла - часть пути
+	router.HandleFunc("GET /api/v2/files", listFilesV2Func)
+	router.HandleFunc("GET /api/v2/files/{path...}", getFileV2Func)
+	router.HandleFunc("HEAD /api/v2/files/{path...}", headFileFunc)
+	router.HandleFunc("PUT /api/v2/files/{path...}", putFileFunc)
+	router.HandleFunc("DELETE /api/v2/files/{path...}", deleteFileV2Func)
+	router.HandleFunc("POST /api/v2/files/{path...}", copyFileV2Func)
+	router.HandleFunc("GET /api/v2/changes", changesFunc)
+	router.HandleFunc("POST /api/v2/sync", syncFunc)
+
+	// WebDAV: хранилище как сетевой диск, ключ - пароль Basic-авторизации
+	davHandler := dav.NewHandler(logs)
+	router.Handle(dav.Prefix, davHandler)
+	router.Handle(dav.Prefix+"/", davHandler)
+
+	// API администратора: отдельный токен вместо ключа аккаунта, без токена в конфиге выключен
+	if config.AdminToken != "" {
+		router.Handle("/admin/api/v1/", adminHandler(config.AdminToken, logs))
+	}
 
 	//health check
 	router.HandleFunc("/health", healthCheck)
@@ -64,8 +121,14 @@ func NewServer(config *config.Config, logs *slog.Logger, pgs *postgres.Postgres,
 	ShutDown := middleware.ShutdownMiddleware(exitChan, conns, router)
 	CheckPanics := middleware.PanicMiddleware(ShutDown, logs)
 	HttpMetrics := metrics.HTTPMetricsMiddleware(CheckPanics, metric)
-	validations := middleware.ValidateAPI(HttpMetrics, pgs, rds, minio, consts.TemplatePath, logs)
-	handler := middleware.Logger(logs, validations)
+	uploads := middleware.WithValue(HttpMetrics, "spooler", spool)
+	uploads = middleware.WithValue(uploads, "fetcher", fetcher)
+	uploads = middleware.WithValue(uploads, "signer", signer)
+	uploads = middleware.WithValue(uploads, "s3keys", s3keys)
+	uploads = middleware.WithValue(uploads, "provisioning", prov)
+	validations := middleware.ValidateAPI(uploads, pgs, rds, st, consts.TemplatePath, config.APIKeyInQuery, logs)
+	logged := middleware.Logger(logs, validations)
+	handler := middleware.RequestID(logged)
 	return &Server{
 		Port:        config.ServerPort,
 		Logger:      logs,
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/internal/upload"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// defaultShareExpiry - срок действия ссылки на скачивание, если клиент его не задал
+const defaultShareExpiry = 24 * time.Hour
+
+// shareFileFunc - signed download link by apikey: POST /share?api=xxx&filename=yyy&expires_in=3600
+// shareFileFunc godoc
+// @Summary Share a file
+// @Description Signed link that downloads the file without the API key until it expires (24 hours by default,
+// @Description 7 days at most). The link follows the path: a file uploaded later under the same name is served
+// @Tags files
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param filename query string true "File name" example(photos/alohadance.png)
+// @Param expires_in query int false "Link lifetime in seconds" example(3600)
+// @Success 201 {object} models.ShareLink
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /client/api/v1/share [post]
+func shareFileFunc(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
+	signer := r.Context().Value("signer").(*upload.Signer)
+
+	name := r.URL.Query().Get("filename")
+	if name == "" {
+		apierror.Write(w, r, apierror.BadRequest("filename is required"))
+		return
+	}
+	expiry := defaultShareExpiry
+	if value := r.URL.Query().Get("expires_in"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		expiry = time.Duration(seconds) * time.Second
+		if err != nil || expiry <= 0 || expiry > upload.MaxPresignExpiry {
+			apierror.Write(w, r, apierror.BadRequest("expires_in must be between 1 second and 7 days"))
+			return
+		}
+	}
+	info, err := st.Stat(bucket, name)
+	if errors.Is(err, storage.ErrInvalidObjectName) {
+		err = storage.ErrNotFound
+	}
+	if err == nil && info.IsDir {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	expiresAt := time.Now().Add(expiry).UTC().Truncate(time.Second)
+	token := signer.Sign(upload.Target{
+		Bucket:    bucket,
+		Path:      info.Key,
+		ExpiresAt: expiresAt.Unix(),
+		Action:    upload.ActionDownload,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(models.ShareLink{
+		FileName:  info.Key,
+		Url:       baseURL(r) + "/download/" + token,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// sharedDownloadFunc - download a file by a signed link from /share: GET /download/{token}
+// sharedDownloadFunc godoc
+// @Summary Download by a shared link
+// @Description Downloads the shared file without the API key. Supports Range and conditional requests
+// @Tags files
+// @Produce octet-stream
+// @Param token path string true "Signed download token"
+// @Success 200 {file} file
+// @Success 206 {file} file "Partial content"
+// @Failure 403 {object} models.ErrorResponse "Link is invalid or expired"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /download/{token} [get]
+func sharedDownloadFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	st := r.Context().Value("storage").(storage.Storage)
+	signer := r.Context().Value("signer").(*upload.Signer)
+	target, err := signer.Verify(r.PathValue("token"))
+	if err == nil && target.Action != upload.ActionDownload {
+		err = upload.ErrInvalidTarget
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, models.ErrCodeForbidden, "link is invalid or expired"))
+		return
+	}
+	file, info, err := st.GetOne(target.Bucket, target.Path)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			logger.Error("shared download error", "error", err.Error(), "client", r.RemoteAddr, "place", tools.GetPlace())
+		}
+		apierror.Write(w, r, err)
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	setFileHeaders(w, info)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(info.Key)}))
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		w.Header().Del("Content-Length")
+		http.ServeContent(w, r, "", info.LastModified, seeker)
+		return
+	}
+	_, _ = io.Copy(w, file)
+}
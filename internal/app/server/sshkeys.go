@@ -0,0 +1,121 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// getSSHKeysFunc - list sftp public keys by apikey: GET /ssh-keys?api=xxx
+// getSSHKeysFunc godoc
+// @Summary List SSH keys
+// @Description Public keys that can log in to the SFTP server instead of the API key
+// @Tags sftp
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Success 200 {array} models.SSHKey
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /client/api/v1/ssh-keys [get]
+func getSSHKeysFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	keys, err := pgs.SSHKeys(r.Context().Value("bucket").(string))
+	if err != nil {
+		logger.Error("get ssh keys error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(keys)
+}
+
+// createSSHKeyFunc - register sftp public key by apikey: POST /ssh-keys?api=xxx
+// createSSHKeyFunc godoc
+// @Summary Add SSH key
+// @Description Register a public key in authorized_keys format for SFTP login. A key can belong to one account only
+// @Tags sftp
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param key body models.SSHKeyRequest true "Public key"
+// @Success 201 {object} models.SSHKey
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 409 {object} models.ErrorResponse "Key is already registered"
+// @Router /client/api/v1/ssh-keys [post]
+func createSSHKeyFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	req := models.SSHKeyRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, apierror.BadRequest("invalid request body"))
+		return
+	}
+	publicKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(req.PublicKey))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("public_key must be a single line in authorized_keys format"))
+		return
+	}
+	// сертификаты и ключи с опциями authorized_keys не поддерживаются: вход проверяется только по отпечатку
+	if _, isCert := publicKey.(*ssh.Certificate); isCert {
+		apierror.Write(w, r, apierror.BadRequest("ssh certificates are not supported"))
+		return
+	}
+	key := &models.SSHKey{
+		Fingerprint: ssh.FingerprintSHA256(publicKey),
+		Type:        publicKey.Type(),
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))),
+		Comment:     comment,
+	}
+	if len(key.Comment) > 256 {
+		key.Comment = key.Comment[:256]
+	}
+	if err = pgs.CreateSSHKey(r.Context().Value("bucket").(string), key); err != nil {
+		logger.Error("create ssh key error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(key)
+}
+
+// deleteSSHKeyFunc - delete sftp public key by apikey: DELETE /ssh-keys?api=xxx&id=1
+// deleteSSHKeyFunc godoc
+// @Summary Delete SSH key
+// @Description Revoke SFTP login with the public key. Open sessions are not interrupted
+// @Tags sftp
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param id query int true "Key id" example(1)
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Router /client/api/v1/ssh-keys [delete]
+func deleteSSHKeyFunc(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	pgs := r.Context().Value("postgres").(*postgres.Postgres)
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("id is required"))
+		return
+	}
+	if err = pgs.DeleteSSHKey(r.Context().Value("bucket").(string), id); err != nil {
+		logger.Error("delete ssh key error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/internal/upload"
+	"CloudStorageProject-FileServer/pkg/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxManifestFiles - предел числа файлов в одном манифесте
+	maxManifestFiles = 100000
+	// maxManifestBytes - предел размера тела запроса с манифестом
+	maxManifestBytes = 64 << 20
+	// defaultPresignExpiry - срок действия ссылок на загрузку, если клиент его не задал
+	defaultPresignExpiry = time.Hour
+)
+
+// syncFunc - compare a directory manifest with the storage by apikey: POST /sync?api=xxx
+// syncFunc godoc
+// @Summary Compare a manifest with the storage
+// @Description Takes (path, size, sha256) of local files under prefix and returns which of them must be uploaded,
+// @Description which stored files are absent from the manifest (delete) and which are unchanged. With presign
+// @Description every file to upload gets a signed PUT link that works without the API key
+// @Tags sync
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param manifest body models.SyncRequest true "Manifest"
+// @Success 200 {object} models.SyncResponse
+// @Failure 400 {object} models.ErrorResponse "Bad manifest"
+// @Failure 413 {object} models.ErrorResponse "Manifest is too large"
+// @Router /client/api/v1/sync [post]
+// @Router /api/v2/sync [post]
+func syncFunc(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
+	signer := r.Context().Value("signer").(*upload.Signer)
+
+	var request models.SyncRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxManifestBytes)).Decode(&request); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apierror.Write(w, r, apierror.New(http.StatusRequestEntityTooLarge, models.ErrCodeTooLarge,
+				"manifest is too large"))
+			return
+		}
+		apierror.Write(w, r, apierror.BadRequest("manifest is not valid JSON"))
+		return
+	}
+	if len(request.Files) > maxManifestFiles {
+		apierror.Write(w, r, apierror.New(http.StatusRequestEntityTooLarge, models.ErrCodeTooLarge,
+			fmt.Sprintf("manifest has more than %d files", maxManifestFiles)))
+		return
+	}
+	expiry := defaultPresignExpiry
+	if request.ExpiresIn != 0 {
+		expiry = time.Duration(request.ExpiresIn) * time.Second
+		if expiry <= 0 || expiry > upload.MaxPresignExpiry {
+			apierror.Write(w, r, apierror.BadRequest("expires_in must be between 1 second and 7 days"))
+			return
+		}
+	}
+	prefix, err := syncPrefix(request.Prefix)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	keys, err := manifestKeys(prefix, request.Files)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	objects, err := st.List(bucket, prefix, true)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		apierror.Write(w, r, err)
+		return
+	}
+	stored := make(map[string]*models.ObjectInfo, len(objects))
+	for i := range objects {
+		// корзина - служебная папка, ее содержимое с клиентом не синхронизируется
+		if objects[i].IsDir || strings.HasPrefix(objects[i].Key, models.TrashPrefix) {
+			continue
+		}
+		stored[objects[i].Key] = &objects[i]
+	}
+
+	response := models.SyncResponse{Prefix: prefix, Upload: []models.SyncUpload{}, Delete: []string{},
+		Unchanged: []string{}}
+	expiresAt := time.Now().Add(expiry).UTC().Truncate(time.Second)
+	for i, entry := range request.Files {
+		key := keys[i]
+		reason := syncReason(stored[key], entry)
+		delete(stored, key)
+		if reason == "" {
+			response.Unchanged = append(response.Unchanged, entry.Path)
+			continue
+		}
+		item := models.SyncUpload{Path: entry.Path, Reason: reason}
+		if request.Presign {
+			token := signer.Sign(upload.Target{
+				Bucket:    bucket,
+				Path:      key,
+				Size:      entry.Size,
+				Sha256:    strings.ToLower(entry.Sha256),
+				ExpiresAt: expiresAt.Unix(),
+			})
+			item.Method = http.MethodPut
+			item.Url = baseURL(r) + "/upload/" + token
+			item.Headers = map[string]string{checksumHeader: strings.ToLower(entry.Sha256)}
+			item.ExpiresAt = &expiresAt
+		}
+		response.Upload = append(response.Upload, item)
+	}
+	// все, что осталось на сервере и чего нет в манифесте
+	for key := range stored {
+		response.Delete = append(response.Delete, strings.TrimPrefix(key, prefix))
+	}
+	sort.Strings(response.Delete)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// syncPrefix - каталог синхронизации в виде "a/b/", пустой - все хранилище
+func syncPrefix(prefix string) (string, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return "", nil
+	}
+	cleaned, err := storage.SanitizeObjectName(prefix)
+	if err != nil {
+		return "", err
+	}
+	return cleaned + "/", nil
+}
+
+// manifestKeys - ключи объектов для файлов манифеста, в том же порядке. Имена проверяются так же, как при загрузке
+func manifestKeys(prefix string, files []models.ManifestEntry) ([]string, error) {
+	keys := make([]string, len(files))
+	seen := make(map[string]bool, len(files))
+	for i, entry := range files {
+		if entry.Size < 0 {
+			return nil, apierror.BadRequest("size must not be negative").WithDetails("path", entry.Path)
+		}
+		if sum, err := hex.DecodeString(entry.Sha256); err != nil || len(sum) != sha256.Size {
+			return nil, apierror.BadRequest("sha256 must be 64 hex characters").WithDetails("path", entry.Path)
+		}
+		key, err := storage.SanitizeObjectName(prefix + entry.Path)
+		if err != nil {
+			return nil, apierror.From(err).WithDetails("path", entry.Path)
+		}
+		if seen[key] {
+			return nil, apierror.BadRequest("duplicate path in manifest").WithDetails("path", entry.Path)
+		}
+		seen[key] = true
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+// syncReason - почему файл нужно загрузить; пустая строка - файл на сервере совпадает с локальным
+func syncReason(stored *models.ObjectInfo, entry models.ManifestEntry) string {
+	switch {
+	case stored == nil:
+		return models.SyncMissing
+	case stored.Size != entry.Size:
+		return models.SyncSizeChanged
+	case stored.Checksum == "":
+		return models.SyncChecksumUnknown
+	case !strings.EqualFold(stored.Checksum, entry.Sha256):
+		return models.SyncChecksumChanged
+	}
+	return ""
+}
+
+// baseURL - адрес сервера, по которому клиент прислал запрос (с учетом прокси с TLS)
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// presignedUploadFunc - upload a file by a signed link from /sync: PUT /upload/{token}
+// presignedUploadFunc godoc
+// @Summary Upload by a signed link
+// @Description Uploads one file from the sync response without the API key. Size and sha256 of the body must match
+// @Description the manifest entry the link was issued for
+// @Tags sync
+// @Accept octet-stream
+// @Produce json
+// @Param token path string true "Signed upload token"
+// @Param file body string true "File content"
+// @Success 200 {object} models.FileInfo "File overwritten"
+// @Success 201 {object} models.FileInfo "File created"
+// @Failure 400 {object} models.ErrorResponse "Size or checksum mismatch"
+// @Failure 403 {object} models.ErrorResponse "Link is invalid or expired"
+// @Router /upload/{token} [put]
+func presignedUploadFunc(w http.ResponseWriter, r *http.Request) {
+	st := r.Context().Value("storage").(storage.Storage)
+	signer := r.Context().Value("signer").(*upload.Signer)
+	target, err := signer.Verify(r.PathValue("token"))
+	if err == nil && target.Action != "" {
+		// ссылка на скачивание не дает права загрузки
+		err = upload.ErrInvalidTarget
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, models.ErrCodeForbidden, err.Error()))
+		return
+	}
+	if r.ContentLength >= 0 && r.ContentLength != target.Size {
+		apierror.Write(w, r, apierror.BadRequest("body size does not match the manifest"))
+		return
+	}
+	// сумма из ссылки обязательна: файл с другим содержимым не сохранится
+	r.Header.Set(checksumHeader, target.Sha256)
+	servePut(w, r, st, target.Bucket, target.Path)
+}
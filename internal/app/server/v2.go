@@ -0,0 +1,211 @@
+package server
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// copySuffix - действие над файлом в стиле Google API: POST /files/{path}:copy.
+// Шаблоны ServeMux не допускают суффикс после {path...}, поэтому он разбирается в обработчике
+const copySuffix = ":copy"
+
+// listFilesV2Func - list files: GET /api/v2/files?prefix=xxx
+// listFilesV2Func godoc
+// @Summary List files
+// @Description Files and folders under the prefix. Folders are returned with is_dir unless recursive=true
+// @Tags v2
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param prefix query string false "Folder prefix" example(photos/)
+// @Param recursive query bool false "List the whole subtree"
+// @Success 200 {object} models.FileList
+// @Failure 400 {object} models.ErrorResponse "Invalid prefix"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v2/files [get]
+func listFilesV2Func(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
+	prefix := r.URL.Query().Get("prefix")
+	recursive := r.URL.Query().Get("recursive") == "true"
+	if err := storage.ValidatePrefix(prefix); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	objects, err := st.List(bucket, prefix, recursive)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logger.Error("list files error", "error", err.Error(), "client", r.RemoteAddr, "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	files := make([]models.FileInfo, 0, len(objects))
+	for i := range objects {
+		// корзину показываем, только если ее запросили явно
+		if strings.HasPrefix(objects[i].Key, models.TrashPrefix) && !strings.HasPrefix(prefix, models.TrashPrefix) {
+			continue
+		}
+		files = append(files, fileInfo(&objects[i]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(models.FileList{Prefix: prefix, Files: files})
+}
+
+// getFileV2Func - download a file: GET /api/v2/files/{path}
+// getFileV2Func godoc
+// @Summary Download a file
+// @Description File content with ETag and Last-Modified. Supports Range, If-None-Match and If-Modified-Since
+// @Tags v2
+// @Produce octet-stream
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param path path string true "File path" example(photos/alohadance.png)
+// @Success 200 {file} file
+// @Success 206 {file} file "Partial content"
+// @Success 304 "Not modified"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v2/files/{path} [get]
+func getFileV2Func(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
+	name := r.PathValue("path")
+	// GET /api/v2/files/ - тот же список, что и без слеша
+	if name == "" {
+		listFilesV2Func(w, r)
+		return
+	}
+
+	file, info, err := st.GetOne(bucket, name)
+	switch {
+	case errors.Is(err, storage.ErrInvalidObjectName):
+		apierror.Write(w, r, apierror.From(storage.ErrNotFound))
+		return
+	case err != nil:
+		logger.Error("get file error", "error", err.Error(), "client", r.RemoteAddr, "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	setFileHeaders(w, info)
+	// файл приватный, но по ETag клиент может переспросить и получить 304
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		// ServeContent сам обработает Range и условные заголовки по ETag и Last-Modified
+		w.Header().Del("Content-Length")
+		http.ServeContent(w, r, "", info.LastModified, seeker)
+		return
+	}
+	_, _ = io.Copy(w, file)
+}
+
+// deleteFileV2Func - delete a file: DELETE /api/v2/files/{path}
+// deleteFileV2Func godoc
+// @Summary Delete a file
+// @Tags v2
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param path path string true "File path" example(photos/alohadance.png)
+// @Success 204 "Deleted"
+// @Failure 403 {object} models.ErrorResponse "File is protected by retention or legal hold"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v2/files/{path} [delete]
+func deleteFileV2Func(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
+	name := r.PathValue("path")
+
+	// S3 удаляет несуществующий ключ без ошибки, поэтому 404 проверяем сами
+	if _, err := st.Stat(bucket, name); errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidObjectName) {
+		apierror.Write(w, r, apierror.From(storage.ErrNotFound))
+		return
+	}
+	err := st.Delete(bucket, name)
+	if err != nil {
+		logger.Error("delete file error", "error", err.Error(), "client", r.RemoteAddr, "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	publishEvent(r, bucket, models.EventDelete, name, "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// copyFileV2Func - copy a file: POST /api/v2/files/{path}:copy
+// copyFileV2Func godoc
+// @Summary Copy a file
+// @Description Server-side copy of the file to the destination path inside the same storage
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Security ApiKeyQuery
+// @Param path path string true "Source file path" example(photos/alohadance.png)
+// @Param request body models.CopyRequest true "Destination"
+// @Success 201 {object} models.FileInfo
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Destination is protected by retention or legal hold"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api/v2/files/{path}:copy [post]
+func copyFileV2Func(w http.ResponseWriter, r *http.Request) {
+	logger := r.Context().Value("logger").(*slog.Logger)
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
+	name, ok := strings.CutSuffix(r.PathValue("path"), copySuffix)
+	if !ok || name == "" {
+		apierror.Write(w, r, apierror.BadRequest("unknown action, use {path}:copy"))
+		return
+	}
+	var request models.CopyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Destination == "" {
+		apierror.Write(w, r, apierror.BadRequest("destination is required"))
+		return
+	}
+
+	destination, err := storage.SanitizeObjectName(request.Destination)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	request.Destination = destination
+
+	err = st.Copy(bucket, name, request.Destination)
+	switch {
+	case errors.Is(err, storage.ErrObjectLocked):
+		apierror.Write(w, r, apierror.From(err).WithStatus(http.StatusConflict))
+		return
+	case err != nil:
+		logger.Error("copy file error", "error", err.Error(), "client", r.RemoteAddr, "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	publishEvent(r, bucket, models.EventUpload, request.Destination, "")
+
+	info, err := st.Stat(bucket, request.Destination)
+	if err != nil {
+		logger.Error("stat copied file error", "error", err.Error(), "place", tools.GetPlace())
+		apierror.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v2/files/"+request.Destination)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(fileInfo(info))
+}
@@ -0,0 +1,74 @@
+package changes
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/pkg/config"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// compactInterval - как часто сжимается журнал
+const compactInterval = time.Hour
+
+// Compactor - фоновое сжатие журнала изменений: старые записи и записи сверх лимита на аккаунт удаляются
+type Compactor struct {
+	postgres  *postgres.Postgres
+	logger    *slog.Logger
+	retention time.Duration
+	keep      int
+	exitChan  chan struct{}
+	done      chan struct{}
+}
+
+func NewCompactor(ctx context.Context, pgs *postgres.Postgres) *Compactor {
+	conf := ctx.Value("config").(*config.Config)
+	logger := ctx.Value("logger").(*slog.Logger)
+	return &Compactor{
+		postgres:  pgs,
+		logger:    logger,
+		retention: time.Duration(conf.ChangesRetentionHours) * time.Hour,
+		keep:      conf.ChangesMaxPerAccount,
+		exitChan:  make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run - сжимает журнал сразу и дальше по таймеру, пока не вызван Close
+func (c *Compactor) Run() {
+	defer close(c.done)
+	ticker := time.NewTicker(compactInterval)
+	defer ticker.Stop()
+	for {
+		c.Compact()
+		select {
+		case <-c.exitChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close - останавливает сжатие и дожидается окончания текущего прохода
+func (c *Compactor) Close(ctx context.Context) error {
+	close(c.exitChan)
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Compact - один проход сжатия
+func (c *Compactor) Compact() {
+	removed, err := c.postgres.CompactChanges(time.Now().Add(-c.retention).UTC(), c.keep)
+	if err != nil {
+		c.logger.Error("changes compaction error", "error", err.Error(), "place", tools.GetPlace())
+		return
+	}
+	if removed > 0 {
+		c.logger.Info("changes compacted", "removed", removed)
+	}
+}
@@ -0,0 +1,208 @@
+package changes
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// Journal - обертка над хранилищем, которая пишет каждое изменение файлов в журнал Postgres.
+// Через нее проходят и обработчики, и загрузка по URL, и правила жизненного цикла
+type Journal struct {
+	storage.Storage
+	postgres *postgres.Postgres
+	logger   *slog.Logger
+}
+
+func NewJournal(ctx context.Context, st storage.Storage, pgs *postgres.Postgres) *Journal {
+	return &Journal{
+		Storage:  st,
+		postgres: pgs,
+		logger:   ctx.Value("logger").(*slog.Logger),
+	}
+}
+
+// record - файл уже изменен, поэтому ошибка журнала только логируется
+func (j *Journal) record(change models.Change) {
+	if err := j.postgres.RecordChange(&change); err != nil {
+		j.logger.Error("record change error", "error", err.Error(), "bucket", change.Bucket, "type", change.Type,
+			"path", change.Path, "place", tools.GetPlace())
+	}
+}
+
+// written - запись о появлении файла: create или overwrite, с размером и ETag сохраненной версии
+func (j *Journal) written(bucket string, name string, existed bool) {
+	change := models.Change{Bucket: bucket, Type: models.ChangeCreate, Path: name}
+	if existed {
+		change.Type = models.ChangeOverwrite
+	}
+	if info, err := j.Storage.Stat(bucket, name); err == nil {
+		change.Size = info.Size
+		change.ETag = info.ETag
+	}
+	j.record(change)
+}
+
+// exists - был ли файл до изменения; при ошибке считаем, что был - overwrite безопаснее для клиента
+func (j *Journal) exists(bucket string, name string) bool {
+	_, err := j.Storage.Stat(bucket, name)
+	return !errors.Is(err, storage.ErrNotFound)
+}
+
+func (j *Journal) CreateOne(bucket string, file models.FileMinio) error {
+	existed := j.exists(bucket, file.FileName)
+	if err := j.Storage.CreateOne(bucket, file); err != nil {
+		return err
+	}
+	j.written(bucket, file.FileName, existed)
+	return nil
+}
+
+func (j *Journal) Delete(bucket string, objectName string) error {
+	if err := j.Storage.Delete(bucket, objectName); err != nil {
+		return err
+	}
+	j.record(models.Change{Bucket: bucket, Type: models.ChangeDelete, Path: objectName})
+	return nil
+}
+
+func (j *Journal) Copy(bucket string, objectName string, newObjectName string) error {
+	existed := j.exists(bucket, newObjectName)
+	if err := j.Storage.Copy(bucket, objectName, newObjectName); err != nil {
+		return err
+	}
+	j.written(bucket, newObjectName, existed)
+	return nil
+}
+
+func (j *Journal) Rename(bucket string, objectName string, newObjectName string) error {
+	if err := j.Storage.Rename(bucket, objectName, newObjectName); err != nil {
+		return err
+	}
+	change := models.Change{Bucket: bucket, Type: models.ChangeMove, Path: objectName, NewPath: newObjectName}
+	if info, err := j.Storage.Stat(bucket, newObjectName); err == nil {
+		change.Size = info.Size
+		change.ETag = info.ETag
+	}
+	j.record(change)
+	return nil
+}
+
+// ObjectProtection, SetRetention, SetLegalHold - блокировки не меняют содержимое, в журнал не пишутся
+func (j *Journal) ObjectProtection(bucket string, objectName string) (*models.ObjectProtection, error) {
+	lock, ok := j.Storage.(storage.Locker)
+	if !ok {
+		return nil, storage.ErrLockingNotSupported
+	}
+	return lock.ObjectProtection(bucket, objectName)
+}
+
+func (j *Journal) SetRetention(bucket string, objectName string, request models.RetentionRequest) error {
+	lock, ok := j.Storage.(storage.Locker)
+	if !ok {
+		return storage.ErrLockingNotSupported
+	}
+	return lock.SetRetention(bucket, objectName, request)
+}
+
+func (j *Journal) SetLegalHold(bucket string, objectName string, enabled bool) error {
+	lock, ok := j.Storage.(storage.Locker)
+	if !ok {
+		return storage.ErrLockingNotSupported
+	}
+	return lock.SetLegalHold(bucket, objectName, enabled)
+}
+
+func (j *Journal) EnsureBucket(bucket string) (bool, error) {
+	provisioner, ok := j.Storage.(storage.Provisioner)
+	if !ok {
+		return false, nil
+	}
+	return provisioner.EnsureBucket(bucket)
+}
+
+func (j *Journal) Buckets() ([]string, error) {
+	provisioner, ok := j.Storage.(storage.Provisioner)
+	if !ok {
+		return []string{}, nil
+	}
+	return provisioner.Buckets()
+}
+
+func (j *Journal) RemoveBucket(bucket string) error {
+	provisioner, ok := j.Storage.(storage.Provisioner)
+	if !ok {
+		return nil
+	}
+	return provisioner.RemoveBucket(bucket)
+}
+
+// SetBucketQuota - квота не меняет файлы, в журнал не пишется
+func (j *Journal) SetBucketQuota(bucket string, quotaBytes int64) error {
+	quoter, ok := j.Storage.(storage.Quoter)
+	if !ok {
+		return storage.ErrQuotaNotSupported
+	}
+	return quoter.SetBucketQuota(bucket, quotaBytes)
+}
+
+// NewMultipartUpload, PutObjectPart, AbortMultipartUpload - части не видны как файлы, в журнал попадает
+// только собранный объект
+func (j *Journal) NewMultipartUpload(bucket string, objectName string, contentType string) (string, error) {
+	multipart, ok := j.Storage.(storage.Multiparter)
+	if !ok {
+		return "", storage.ErrMultipartNotSupported
+	}
+	return multipart.NewMultipartUpload(bucket, objectName, contentType)
+}
+
+func (j *Journal) PutObjectPart(bucket string, objectName string, uploadId string, partNumber int,
+	reader io.Reader, size int64) (string, error) {
+	multipart, ok := j.Storage.(storage.Multiparter)
+	if !ok {
+		return "", storage.ErrMultipartNotSupported
+	}
+	return multipart.PutObjectPart(bucket, objectName, uploadId, partNumber, reader, size)
+}
+
+func (j *Journal) CompleteMultipartUpload(bucket string, objectName string, uploadId string,
+	parts []models.CompletedPart) (string, error) {
+	multipart, ok := j.Storage.(storage.Multiparter)
+	if !ok {
+		return "", storage.ErrMultipartNotSupported
+	}
+	existed := j.exists(bucket, objectName)
+	etag, err := multipart.CompleteMultipartUpload(bucket, objectName, uploadId, parts)
+	if err != nil {
+		return "", err
+	}
+	j.written(bucket, objectName, existed)
+	return etag, nil
+}
+
+func (j *Journal) AbortMultipartUpload(bucket string, objectName string, uploadId string) error {
+	multipart, ok := j.Storage.(storage.Multiparter)
+	if !ok {
+		return storage.ErrMultipartNotSupported
+	}
+	return multipart.AbortMultipartUpload(bucket, objectName, uploadId)
+}
+
+// CopyObject - копирование между хранилищами нужно только переносу хранилищ, журнал его не видит
+func (j *Journal) CopyObject(srcBucket string, objectName string, dstBucket string) error {
+	return storage.CopyObjectTo(j.Storage, srcBucket, objectName, dstBucket)
+}
+
+var (
+	_ storage.Storage      = (*Journal)(nil)
+	_ storage.Locker       = (*Journal)(nil)
+	_ storage.Provisioner  = (*Journal)(nil)
+	_ storage.Quoter       = (*Journal)(nil)
+	_ storage.BucketCopier = (*Journal)(nil)
+	_ storage.Multiparter  = (*Journal)(nil)
+)
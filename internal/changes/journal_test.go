@@ -0,0 +1,44 @@
+package changes
+
+import (
+	"CloudStorageProject-FileServer/internal/replication"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/internal/storage/memory"
+	"errors"
+	"testing"
+)
+
+// quotaStorage - драйвер с квотами, как MinIO
+type quotaStorage struct {
+	*memory.Memory
+	quotas map[string]int64
+}
+
+func (q *quotaStorage) SetBucketQuota(bucket string, quotaBytes int64) error {
+	q.quotas[bucket] = quotaBytes
+	return nil
+}
+
+// TestQuotaThroughWrappers - хранилище собрано как в app.go: драйвер, репликация, журнал
+func TestQuotaThroughWrappers(t *testing.T) {
+	driver := &quotaStorage{Memory: memory.NewMemory(), quotas: map[string]int64{}}
+	var st storage.Storage = &Journal{Storage: &replication.Replicator{Storage: driver}}
+
+	quoter, ok := st.(storage.Quoter)
+	if !ok {
+		t.Fatal("wrapped storage does not implement storage.Quoter")
+	}
+	if err := quoter.SetBucketQuota("u-a", 10<<20); err != nil {
+		t.Fatal(err)
+	}
+	if driver.quotas["u-a"] != 10<<20 {
+		t.Fatalf("quota is not applied to the driver: %v", driver.quotas)
+	}
+}
+
+func TestQuotaNotSupported(t *testing.T) {
+	st := &Journal{Storage: &replication.Replicator{Storage: memory.NewMemory()}}
+	if err := st.SetBucketQuota("u-a", 1); !errors.Is(err, storage.ErrQuotaNotSupported) {
+		t.Fatalf("err = %v, want ErrQuotaNotSupported", err)
+	}
+}
@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrAccountNotFound - аккаунта с таким id нет
+var ErrAccountNotFound = errors.New("account not found")
+
+// AccountById - аккаунт по id; nil, nil - аккаунта нет
+func (p *Postgres) AccountById(id int) (*models.APIPGS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	account, err := scanAPIKey(p.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM minio_keys WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		p.observe("account_by_id", start, nil)
+		return nil, nil
+	}
+	p.observe("account_by_id", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+	return account, nil
+}
+
+// Accounts - аккаунты по фильтру в порядке создания
+func (p *Postgres) Accounts(filter models.AccountFilter) ([]models.APIPGS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	start := time.Now()
+
+	var conditions []string
+	var args []any
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, "status = $"+strconv.Itoa(len(args)))
+	}
+	if filter.Email != "" {
+		args = append(args, "%"+filter.Email+"%")
+		conditions = append(conditions, "email ILIKE $"+strconv.Itoa(len(args)))
+	}
+	query := `SELECT ` + apiKeyColumns + ` FROM minio_keys`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	rows, err := p.pool.Query(ctx, query+" ORDER BY id", args...)
+	if err != nil {
+		p.observe("list_accounts", start, err)
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []models.APIPGS{}
+	for rows.Next() {
+		account, errScan := scanAPIKey(rows)
+		if errScan != nil {
+			p.observe("list_accounts", start, errScan)
+			return nil, fmt.Errorf("failed to scan account: %w", errScan)
+		}
+		accounts = append(accounts, *account)
+	}
+	err = rows.Err()
+	p.observe("list_accounts", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// updateAccount - меняет аккаунт одним UPDATE и возвращает его новое состояние
+func (p *Postgres) updateAccount(operation string, id int, set string, args ...any) (*models.APIPGS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	args = append(args, id)
+	account, err := scanAPIKey(p.pool.QueryRow(ctx, `UPDATE minio_keys SET `+set+` WHERE id = $`+
+		strconv.Itoa(len(args))+` RETURNING `+apiKeyColumns, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		p.observe(operation, start, nil)
+		return nil, ErrAccountNotFound
+	}
+	p.observe(operation, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update account: %w", err)
+	}
+	return account, nil
+}
+
+// SetAccountStatus - отзыв ключа или его возврат в работу
+func (p *Postgres) SetAccountStatus(id int, status string) (*models.APIPGS, error) {
+	return p.updateAccount("set_account_status", id, "status = $1", status)
+}
+
+// SetAccountPermissions - права ключа
+func (p *Postgres) SetAccountPermissions(id int, permissions string) (*models.APIPGS, error) {
+	return p.updateAccount("set_account_permissions", id, "permissions = $1", permissions)
+}
+
+// SetAccountQuota - квота аккаунта в мегабайтах, 0 - без квоты
+func (p *Postgres) SetAccountQuota(id int, quotaMB int64) (*models.APIPGS, error) {
+	return p.updateAccount("set_account_quota", id, "quota_mb = $1", quotaMB)
+}
+
+// RotateAPIKey - заменяет ключ и удаляет SSH-ключи аккаунта: их мог добавить тот, у кого был старый ключ.
+// Хранилище и настройки аккаунта остаются прежними. Открытое значение ключа старого формата стирается вместе с ним
+func (p *Postgres) RotateAPIKey(id int, newKey string) (*models.APIPGS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	prefix, _ := models.APIKeyPrefix(newKey)
+	var account *models.APIPGS
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var err error
+		account, err = scanAPIKey(tx.QueryRow(ctx, `UPDATE minio_keys
+			SET key_prefix = NULLIF($1, ''), key_hash = $2, key_name = NULL WHERE id = $3
+			RETURNING `+apiKeyColumns, prefix, models.HashAPIKey(newKey), id))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAccountNotFound
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM ssh_keys WHERE account_id = $1`, id)
+		return err
+	})
+	if errors.Is(err, ErrAccountNotFound) {
+		p.observe("rotate_api_key", start, nil)
+		return nil, err
+	}
+	p.observe("rotate_api_key", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate api key: %w", err)
+	}
+	account.Key = newKey
+	return account, nil
+}
+
+// ClearPlaintextKeys - стирает открытые значения ключей старого формата, которые остались в key_name
+// после перехода на хэши. Возвращает стертые значения, чтобы по ним можно было почистить кэш.
+// Записи, у которых storage_id все еще равен ключу (не прошли cmd/migrate-storage), не трогает:
+// без key_name по ним уже не понять, что значение ключа лежит в имени хранилища
+func (p *Postgres) ClearPlaintextKeys() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `UPDATE minio_keys m SET key_name = NULL
+		FROM (SELECT id, key_name FROM minio_keys WHERE key_name IS NOT NULL AND storage_id <> key_name FOR UPDATE) old
+		WHERE m.id = old.id RETURNING old.key_name`)
+	if err != nil {
+		p.observe("clear_plaintext_keys", start, err)
+		return nil, fmt.Errorf("failed to clear plaintext keys: %w", err)
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	p.observe("clear_plaintext_keys", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear plaintext keys: %w", err)
+	}
+	return keys, nil
+}
+
+// DeleteAccount - удаляет аккаунт вместе с SSH-ключами, правилами жизненного цикла и журналом изменений
+// его хранилища. Сами файлы должны быть удалены заранее
+func (p *Postgres) DeleteAccount(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		var storageId string
+		// ssh_keys удаляются каскадом
+		err := tx.QueryRow(ctx, `DELETE FROM minio_keys WHERE id = $1 RETURNING storage_id`, id).Scan(&storageId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAccountNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, `DELETE FROM lifecycle_rules WHERE bucket = $1`, storageId); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, `DELETE FROM changes WHERE bucket = $1`, storageId); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM changes_compaction WHERE bucket = $1`, storageId)
+		return err
+	})
+	if errors.Is(err, ErrAccountNotFound) {
+		p.observe("delete_account", start, nil)
+		return err
+	}
+	p.observe("delete_account", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+const changeColumns = "id, bucket, type, path, new_path, size, etag, created_at"
+
+// RecordChange - добавляет запись в журнал изменений хранилища
+func (p *Postgres) RecordChange(change *models.Change) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	err := p.pool.QueryRow(ctx, `INSERT INTO changes (bucket, type, path, new_path, size, etag)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		change.Bucket, change.Type, change.Path, change.NewPath, change.Size, change.ETag).Scan(&change.Id, &change.Time)
+	p.observe("record_change", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to record change: %w", err)
+	}
+	return nil
+}
+
+// Changes - изменения хранилища после курсора по возрастанию id, не больше limit
+func (p *Postgres) Changes(bucket string, after int64, limit int) ([]models.Change, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `SELECT `+changeColumns+` FROM changes WHERE bucket = $1 AND id > $2
+		ORDER BY id LIMIT $3`, bucket, after, limit)
+	if err != nil {
+		p.observe("list_changes", start, err)
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []models.Change{}
+	for rows.Next() {
+		var change models.Change
+		if err = rows.Scan(&change.Id, &change.Bucket, &change.Type, &change.Path, &change.NewPath, &change.Size,
+			&change.ETag, &change.Time); err != nil {
+			p.observe("list_changes", start, err)
+			return nil, fmt.Errorf("failed to scan change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	err = rows.Err()
+	p.observe("list_changes", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+	return changes, nil
+}
+
+// ChangesState - последний id журнала хранилища и граница, до которой журнал сжат (0 - не сжимался)
+func (p *Postgres) ChangesState(bucket string) (head int64, compactedThrough int64, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	err = p.pool.QueryRow(ctx, `SELECT
+			COALESCE((SELECT compacted_through FROM changes_compaction WHERE bucket = $1), 0),
+			COALESCE((SELECT max(id) FROM changes WHERE bucket = $1), 0)`, bucket).Scan(&compactedThrough, &head)
+	p.observe("changes_state", start, err)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get changes state: %w", err)
+	}
+	// после сжатия всего журнала записей нет, но курсор не должен откатиться назад
+	return max(head, compactedThrough), compactedThrough, nil
+}
+
+// CompactChanges - удаляет записи старше olderThan и сверх keep последних в каждом хранилище,
+// запоминая границу, чтобы отличать устаревшие курсоры. Возвращает число удаленных записей
+func (p *Postgres) CompactChanges(olderThan time.Time, keep int) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	start := time.Now()
+	var removed int64
+	err := p.pool.QueryRow(ctx, `WITH ranked AS (
+			SELECT id, bucket, created_at, row_number() OVER (PARTITION BY bucket ORDER BY id DESC) AS position
+			FROM changes
+		), removed AS (
+			DELETE FROM changes WHERE id IN (SELECT id FROM ranked WHERE created_at < $1 OR position > $2)
+			RETURNING id, bucket
+		), bounds AS (
+			INSERT INTO changes_compaction (bucket, compacted_through)
+			SELECT bucket, max(id) FROM removed GROUP BY bucket
+			ON CONFLICT (bucket) DO UPDATE
+				SET compacted_through = GREATEST(changes_compaction.compacted_through, EXCLUDED.compacted_through)
+		)
+		SELECT count(*) FROM removed`, olderThan, keep).Scan(&removed)
+	p.observe("compact_changes", start, err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compact changes: %w", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrRuleNotFound - правило не найдено (или принадлежит другому ключу)
+var ErrRuleNotFound = errors.New("lifecycle rule not found")
+
+const lifecycleRuleColumns = "id, bucket, prefix, tag_key, tag_value, action, days, created_at"
+
+func scanLifecycleRule(row pgx.Row) (models.LifecycleRule, error) {
+	var rule models.LifecycleRule
+	err := row.Scan(&rule.Id, &rule.Bucket, &rule.Prefix, &rule.TagKey, &rule.TagValue, &rule.Action,
+		&rule.Days, &rule.CreatedAt)
+	return rule, err
+}
+
+func (p *Postgres) observe(operation string, start time.Time, err error) {
+	if err != nil {
+		p.metrics.ErrorsTotal.WithLabelValues("query_error", operation).Inc()
+		p.metrics.QueryTotal.WithLabelValues(operation, "error").Inc()
+		return
+	}
+	p.metrics.QueryTotal.WithLabelValues(operation, "success").Inc()
+	p.metrics.QueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// CreateLifecycleRule - сохраняет новое правило жизненного цикла
+func (p *Postgres) CreateLifecycleRule(rule *models.LifecycleRule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	err := p.pool.QueryRow(ctx, `INSERT INTO lifecycle_rules (bucket, prefix, tag_key, tag_value, action, days)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		rule.Bucket, rule.Prefix, rule.TagKey, rule.TagValue, rule.Action, rule.Days).Scan(&rule.Id, &rule.CreatedAt)
+	p.observe("create_lifecycle_rule", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to create lifecycle rule: %w", err)
+	}
+	return nil
+}
+
+// LifecycleRules - правила одного пользователя
+func (p *Postgres) LifecycleRules(bucket string) ([]models.LifecycleRule, error) {
+	return p.queryLifecycleRules("list_lifecycle_rules",
+		`SELECT `+lifecycleRuleColumns+` FROM lifecycle_rules WHERE bucket = $1 ORDER BY id`, bucket)
+}
+
+// AllLifecycleRules - все правила, используются фоновым обходчиком
+func (p *Postgres) AllLifecycleRules() ([]models.LifecycleRule, error) {
+	return p.queryLifecycleRules("list_all_lifecycle_rules",
+		`SELECT `+lifecycleRuleColumns+` FROM lifecycle_rules ORDER BY bucket, id`)
+}
+
+func (p *Postgres) queryLifecycleRules(operation string, query string, args ...any) ([]models.LifecycleRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		p.observe(operation, start, err)
+		return nil, fmt.Errorf("failed to list lifecycle rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.LifecycleRule{}
+	for rows.Next() {
+		rule, errScan := scanLifecycleRule(rows)
+		if errScan != nil {
+			p.observe(operation, start, errScan)
+			return nil, fmt.Errorf("failed to scan lifecycle rule: %w", errScan)
+		}
+		rules = append(rules, rule)
+	}
+	err = rows.Err()
+	p.observe(operation, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lifecycle rules: %w", err)
+	}
+	return rules, nil
+}
+
+// UpdateLifecycleRule - обновляет правило пользователя
+func (p *Postgres) UpdateLifecycleRule(rule *models.LifecycleRule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	err := p.pool.QueryRow(ctx, `UPDATE lifecycle_rules SET prefix = $1, tag_key = $2, tag_value = $3, action = $4, days = $5
+		WHERE id = $6 AND bucket = $7 RETURNING created_at`,
+		rule.Prefix, rule.TagKey, rule.TagValue, rule.Action, rule.Days, rule.Id, rule.Bucket).Scan(&rule.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		p.observe("update_lifecycle_rule", start, nil)
+		return ErrRuleNotFound
+	}
+	p.observe("update_lifecycle_rule", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to update lifecycle rule: %w", err)
+	}
+	return nil
+}
+
+// DeleteLifecycleRule - удаляет правило пользователя
+func (p *Postgres) DeleteLifecycleRule(bucket string, id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `DELETE FROM lifecycle_rules WHERE id = $1 AND bucket = $2`, id, bucket)
+	p.observe("delete_lifecycle_rule", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete lifecycle rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
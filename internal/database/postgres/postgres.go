@@ -5,9 +5,12 @@ import (
 	"CloudStorageProject-FileServer/pkg/config"
 	"CloudStorageProject-FileServer/pkg/models"
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -39,7 +42,8 @@ func InitPostgres(ctx context.Context, metric *metrics.PostgresMetrics) (*Postgr
 }
 func createTables(ctx context.Context, pool *pgxpool.Pool, m *metrics.PostgresMetrics) error {
 	start := time.Now()
-	_, err := pool.Query(ctx, `
+	// Exec без аргументов идет по simple protocol, поэтому можно несколько выражений за раз
+	_, err := pool.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS minio_keys (
     		id SERIAL PRIMARY KEY,
     		key_name VARCHAR(100) NOT NULL UNIQUE,
@@ -48,6 +52,63 @@ func createTables(ctx context.Context, pool *pgxpool.Pool, m *metrics.PostgresMe
     		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			last_login TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
+		-- хранилище аккаунта отвязано от ключа. У старых записей storage_id = key_name, то есть открытое
+		-- значение ключа, пока их бакеты не перенесены командой cmd/migrate-storage. Поэтому она
+		-- запускается раньше cmd/admin hash-keys, а hash-keys такие записи пропускает
+		ALTER TABLE minio_keys ADD COLUMN IF NOT EXISTS storage_id VARCHAR(100) UNIQUE;
+		UPDATE minio_keys SET storage_id = key_name WHERE storage_id IS NULL;
+		ALTER TABLE minio_keys ALTER COLUMN storage_id SET DEFAULT ('u-' || replace(gen_random_uuid()::text, '-', ''));
+		ALTER TABLE minio_keys ALTER COLUMN storage_id SET NOT NULL;
+		-- управление ключами из cmd/admin: отзыв, права и квота аккаунта
+		ALTER TABLE minio_keys ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active';
+		ALTER TABLE minio_keys ADD COLUMN IF NOT EXISTS permissions VARCHAR(32) NOT NULL DEFAULT 'read,write';
+		ALTER TABLE minio_keys ADD COLUMN IF NOT EXISTS quota_mb BIGINT NOT NULL DEFAULT 0;
+		-- ключ хранится только хэшем: новые ключи prefix.secret ищутся по префиксу, старые - по хэшу.
+		-- Открытые значения старых ключей в key_name стирает cmd/admin hash-keys
+		ALTER TABLE minio_keys ADD COLUMN IF NOT EXISTS key_prefix VARCHAR(32) UNIQUE;
+		ALTER TABLE minio_keys ADD COLUMN IF NOT EXISTS key_hash VARCHAR(64) UNIQUE;
+		UPDATE minio_keys SET key_hash = encode(sha256(convert_to(key_name, 'UTF8')), 'hex') WHERE key_hash IS NULL;
+		ALTER TABLE minio_keys ALTER COLUMN key_hash SET NOT NULL;
+		ALTER TABLE minio_keys ALTER COLUMN key_name DROP NOT NULL;
+		CREATE TABLE IF NOT EXISTS lifecycle_rules (
+			id SERIAL PRIMARY KEY,
+			bucket VARCHAR(100) NOT NULL,
+			prefix VARCHAR(1024) NOT NULL DEFAULT '',
+			tag_key VARCHAR(128) NOT NULL DEFAULT '',
+			tag_value VARCHAR(256) NOT NULL DEFAULT '',
+			action VARCHAR(10) NOT NULL,
+			days INTEGER NOT NULL CHECK (days > 0),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS lifecycle_rules_bucket_idx ON lifecycle_rules (bucket);
+		-- журнал изменений: только добавление, id - курсор клиентов синхронизации
+		CREATE TABLE IF NOT EXISTS changes (
+			id BIGSERIAL PRIMARY KEY,
+			bucket VARCHAR(100) NOT NULL,
+			type VARCHAR(16) NOT NULL,
+			path VARCHAR(1024) NOT NULL,
+			new_path VARCHAR(1024) NOT NULL DEFAULT '',
+			size BIGINT NOT NULL DEFAULT 0,
+			etag VARCHAR(128) NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS changes_bucket_id_idx ON changes (bucket, id);
+		-- граница сжатия: записи хранилища с id <= compacted_through удалены
+		CREATE TABLE IF NOT EXISTS changes_compaction (
+			bucket VARCHAR(100) PRIMARY KEY,
+			compacted_through BIGINT NOT NULL
+		);
+		-- публичные ключи для входа по SFTP; привязаны к аккаунту, а не к хранилищу, и переживают его перенос
+		CREATE TABLE IF NOT EXISTS ssh_keys (
+			id SERIAL PRIMARY KEY,
+			account_id INTEGER NOT NULL REFERENCES minio_keys (id) ON DELETE CASCADE,
+			fingerprint VARCHAR(128) NOT NULL UNIQUE,
+			key_type VARCHAR(64) NOT NULL,
+			public_key TEXT NOT NULL,
+			comment VARCHAR(256) NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS ssh_keys_account_idx ON ssh_keys (account_id);
 	`)
 	duration := time.Since(start).Seconds()
 	if err != nil {
@@ -66,31 +127,145 @@ func createExampleAPI(ctx context.Context, pool *pgxpool.Pool) {
 	if need := conf.TestAPINeeded; !need {
 		return
 	}
-	userTest := conf.TestAPIKey
-	emailTest := conf.TestAPIEmail
+	prefix, _ := models.APIKeyPrefix(conf.TestAPIKey)
+	_, _ = pool.Exec(ctx, `INSERT INTO minio_keys (key_prefix, key_hash, email) VALUES (NULLIF($1, ''), $2, $3)
+		ON CONFLICT DO NOTHING`, prefix, models.HashAPIKey(conf.TestAPIKey), conf.TestAPIEmail)
+}
+
+const apiKeyColumns = "id, COALESCE(key_prefix, ''), key_hash, storage_id, cloud_access, email, created_at, " +
+	"last_login, status, permissions, quota_mb"
 
-	query := fmt.Sprintf("INSERT INTO minio_keys (key_name, email) VALUES ('%s', '%s') on conflict do nothing", userTest, emailTest)
-	_ = pool.QueryRow(ctx, query)
+func scanAPIKey(row pgx.Row) (*models.APIPGS, error) {
+	apiStruct := &models.APIPGS{}
+	err := row.Scan(&apiStruct.Id, &apiStruct.KeyPrefix, &apiStruct.KeyHash, &apiStruct.StorageId,
+		&apiStruct.CloudAccess, &apiStruct.Email, &apiStruct.CreatedAt, &apiStruct.LastLogin, &apiStruct.Status,
+		&apiStruct.Permissions, &apiStruct.QuotaMB)
+	return apiStruct, err
 }
 
+// CheckApiExists - аккаунт по значению ключа. Ключ prefix.secret ищется по префиксу и сверяется с хэшем,
+// ключ старого формата - сразу по хэшу. nil - ключа нет
 func (p *Postgres) CheckApiExists(api string) *models.APIPGS {
 	start := time.Now()
 	ctx := context.Background()
-	apiStruct := &models.APIPGS{}
-	query := fmt.Sprintf("SELECT * FROM minio_keys WHERE key_name = '%s'", api)
-	if _ = p.pool.QueryRow(ctx, query).Scan(&apiStruct.Id, &apiStruct.KeyName, &apiStruct.CloudAccess, &apiStruct.Email,
-		&apiStruct.CreatedAt, &apiStruct.LastLogin); apiStruct.KeyName == "" {
+	hash := models.HashAPIKey(api)
+	var row pgx.Row
+	if prefix, ok := models.APIKeyPrefix(api); ok {
+		row = p.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM minio_keys WHERE key_prefix = $1`, prefix)
+	} else {
+		row = p.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM minio_keys WHERE key_prefix IS NULL AND key_hash = $1`,
+			hash)
+	}
+	apiStruct, err := scanAPIKey(row)
+	if err != nil || subtle.ConstantTimeCompare([]byte(apiStruct.KeyHash), []byte(hash)) != 1 {
 		return nil
 	}
 	p.metrics.QueryTotal.WithLabelValues("check_api_exists", "success").Inc()
 	p.metrics.QueryDuration.WithLabelValues("check_api_exists").Observe(time.Since(start).Seconds())
 	return apiStruct
 }
-func (p *Postgres) UpdateLastLogin(api string) error {
+
+// AccountByStorage - аккаунт по имени его хранилища; nil, nil - такого хранилища нет
+func (p *Postgres) AccountByStorage(storageId string) (*models.APIPGS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	account, err := scanAPIKey(p.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM minio_keys WHERE storage_id = $1`,
+		storageId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		p.observe("account_by_storage", start, nil)
+		return nil, nil
+	}
+	p.observe("account_by_storage", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+	return account, nil
+}
+
+// APIKeys - все зарегистрированные ключи
+func (p *Postgres) APIKeys() ([]models.APIPGS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `SELECT `+apiKeyColumns+` FROM minio_keys ORDER BY id`)
+	if err != nil {
+		p.observe("list_api_keys", start, err)
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIPGS{}
+	for rows.Next() {
+		key, errScan := scanAPIKey(rows)
+		if errScan != nil {
+			p.observe("list_api_keys", start, errScan)
+			return nil, fmt.Errorf("failed to scan api key: %w", errScan)
+		}
+		keys = append(keys, *key)
+	}
+	err = rows.Err()
+	p.observe("list_api_keys", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// CreateAPIKey - регистрирует новый ключ, в базу попадают только его префикс и хэш
+func (p *Postgres) CreateAPIKey(key string, email string, permissions string) (*models.APIPGS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	prefix, _ := models.APIKeyPrefix(key)
+	apiStruct, err := scanAPIKey(p.pool.QueryRow(ctx, `INSERT INTO minio_keys (key_prefix, key_hash, email, permissions)
+		VALUES (NULLIF($1, ''), $2, $3, $4) RETURNING `+apiKeyColumns, prefix, models.HashAPIKey(key), email, permissions))
+	p.observe("create_api_key", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+	apiStruct.Key = key
+	return apiStruct, nil
+}
+
+// MoveStorage - переносит аккаунт и его правила жизненного цикла на новое хранилище одной транзакцией
+func (p *Postgres) MoveStorage(accountId int, oldStorageId string, newStorageId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE minio_keys SET storage_id = $1 WHERE id = $2 AND storage_id = $3`,
+			newStorageId, accountId, oldStorageId)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("account %d is not bound to storage %s", accountId, oldStorageId)
+		}
+		_, err = tx.Exec(ctx, `UPDATE lifecycle_rules SET bucket = $1 WHERE bucket = $2`, newStorageId, oldStorageId)
+		if err != nil {
+			return err
+		}
+		// журнал изменений переезжает вместе с хранилищем, чтобы курсоры клиентов остались верными
+		_, err = tx.Exec(ctx, `UPDATE changes SET bucket = $1 WHERE bucket = $2`, newStorageId, oldStorageId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `UPDATE changes_compaction SET bucket = $1 WHERE bucket = $2`, newStorageId, oldStorageId)
+		return err
+	})
+	p.observe("move_storage", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to move storage: %w", err)
+	}
+	return nil
+}
+
+func (p *Postgres) UpdateLastLogin(accountId int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	start := time.Now()
-	_, err := p.pool.Exec(ctx, `UPDATE minio_keys SET last_login = $1 WHERE key_name = $2`, time.Now(), api)
+	_, err := p.pool.Exec(ctx, `UPDATE minio_keys SET last_login = $1 WHERE id = $2`, time.Now(), accountId)
 	if err != nil {
 		p.metrics.ErrorsTotal.WithLabelValues("query_error", "update_last_login").Inc()
 		p.metrics.QueryTotal.WithLabelValues("update_last_login", "error").Inc()
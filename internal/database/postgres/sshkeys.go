@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrSSHKeyNotFound - ключ не найден (или принадлежит другому аккаунту)
+var ErrSSHKeyNotFound = errors.New("ssh key not found")
+
+const sshKeyColumns = "k.id, k.fingerprint, k.key_type, k.public_key, k.comment, k.created_at"
+
+func scanSSHKey(row pgx.Row) (models.SSHKey, error) {
+	var key models.SSHKey
+	err := row.Scan(&key.Id, &key.Fingerprint, &key.Type, &key.PublicKey, &key.Comment, &key.CreatedAt)
+	return key, err
+}
+
+// SSHKeys - публичные ключи аккаунта, которому принадлежит хранилище
+func (p *Postgres) SSHKeys(storageId string) ([]models.SSHKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	rows, err := p.pool.Query(ctx, `SELECT `+sshKeyColumns+` FROM ssh_keys k
+		JOIN minio_keys m ON m.id = k.account_id WHERE m.storage_id = $1 ORDER BY k.id`, storageId)
+	if err != nil {
+		p.observe("list_ssh_keys", start, err)
+		return nil, fmt.Errorf("failed to list ssh keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.SSHKey{}
+	for rows.Next() {
+		key, errScan := scanSSHKey(rows)
+		if errScan != nil {
+			p.observe("list_ssh_keys", start, errScan)
+			return nil, fmt.Errorf("failed to scan ssh key: %w", errScan)
+		}
+		keys = append(keys, key)
+	}
+	err = rows.Err()
+	p.observe("list_ssh_keys", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ssh keys: %w", err)
+	}
+	return keys, nil
+}
+
+// CreateSSHKey - регистрирует ключ для аккаунта хранилища; один ключ не может принадлежать двум аккаунтам
+func (p *Postgres) CreateSSHKey(storageId string, key *models.SSHKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	err := p.pool.QueryRow(ctx, `INSERT INTO ssh_keys (account_id, fingerprint, key_type, public_key, comment)
+		SELECT id, $2, $3, $4, $5 FROM minio_keys WHERE storage_id = $1 RETURNING id, created_at`,
+		storageId, key.Fingerprint, key.Type, key.PublicKey, key.Comment).Scan(&key.Id, &key.CreatedAt)
+	p.observe("create_ssh_key", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to create ssh key: %w", err)
+	}
+	return nil
+}
+
+// DeleteSSHKey - удаляет ключ аккаунта хранилища
+func (p *Postgres) DeleteSSHKey(storageId string, id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	tag, err := p.pool.Exec(ctx, `DELETE FROM ssh_keys k USING minio_keys m
+		WHERE k.id = $1 AND m.id = k.account_id AND m.storage_id = $2`, id, storageId)
+	p.observe("delete_ssh_key", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete ssh key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSSHKeyNotFound
+	}
+	return nil
+}
+
+// AccountBySSHKey - аккаунт по отпечатку публичного ключа; nil, nil - ключ не зарегистрирован
+func (p *Postgres) AccountBySSHKey(fingerprint string) (*models.APIPGS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	account, err := scanAPIKey(p.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM minio_keys
+		WHERE id = (SELECT account_id FROM ssh_keys WHERE fingerprint = $1)`, fingerprint))
+	if errors.Is(err, pgx.ErrNoRows) {
+		p.observe("account_by_ssh_key", start, nil)
+		return nil, nil
+	}
+	p.observe("account_by_ssh_key", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+	return account, nil
+}
@@ -5,6 +5,10 @@ import (
 	"CloudStorageProject-FileServer/pkg/config"
 	"CloudStorageProject-FileServer/pkg/models"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -52,17 +56,72 @@ func (rds *Redis) CloseConnection(ctx context.Context) error {
 	}
 }
 
-func (rds *Redis) SetAPIField(apiData *models.APIPGS) error {
+const (
+	// apiCacheTTL - сколько живет запись кэша ключа. Отзыв удаляет запись сразу, TTL - страховка
+	// на случай, если удаление не дошло до Redis
+	apiCacheTTL = 10 * time.Minute
+	// apiVersionTTL - счетчик версии ключа должен пережить любое чтение аккаунта из базы
+	apiVersionTTL = time.Hour
+)
+
+// ErrAPIFieldChanged - ключ изменили (отозвали, приостановили, сменили права), пока аккаунт читали из базы.
+// Прочитанное состояние могло устареть, в кэш оно не записано
+var ErrAPIFieldChanged = errors.New("api key changed while it was being cached")
+
+func apiVersionKey(keyHash string) string {
+	return "apikey-version:" + keyHash
+}
+
+// APIFieldVersion - версия записи ключа; ее нужно взять до чтения аккаунта из базы и передать в SetAPIField
+func (rds *Redis) APIFieldVersion(keyHash string) (string, error) {
+	version, err := rds.pool.Get(context.Background(), apiVersionKey(keyHash)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return version, err
+}
+
+// SetAPIField - кэширует аккаунт под хэшем ключа, значение ключа в Redis не попадает. version - из
+// APIFieldVersion до чтения базы: если с тех пор DelAPIField менял ключ, запись не делается и возвращается
+// ErrAPIFieldChanged. Иначе отозванный в этот момент ключ вернулся бы в кэш действующим
+func (rds *Redis) SetAPIField(apiData *models.APIPGS, version string) error {
 	ctx := context.Background()
 	start := time.Now()
-	_, err := rds.pool.HSet(ctx, "apikey:"+apiData.KeyName, map[string]interface{}{
-		"id":          apiData.Id,
-		"name":        apiData.KeyName,
-		"email":       apiData.Email,
-		"createdAt":   apiData.CreatedAt,
-		"lastLogin":   apiData.LastLogin,
-		"cloudAccess": apiData.CloudAccess,
-	}).Result()
+	key := "apikey:" + apiData.KeyHash
+	versionKey := apiVersionKey(apiData.KeyHash)
+	err := rds.pool.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, versionKey).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if current != version {
+			return ErrAPIFieldChanged
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, map[string]interface{}{
+				"id":          apiData.Id,
+				"prefix":      apiData.KeyPrefix,
+				"storageId":   apiData.StorageId,
+				"email":       apiData.Email,
+				"createdAt":   apiData.CreatedAt,
+				"lastLogin":   apiData.LastLogin,
+				"cloudAccess": apiData.CloudAccess,
+				"status":      apiData.Status,
+				"permissions": apiData.Permissions,
+				"quotaMb":     apiData.QuotaMB,
+			})
+			pipe.Expire(ctx, key, apiCacheTTL)
+			return nil
+		})
+		return err
+	}, versionKey)
+	// версию поменяли между WATCH и EXEC - то же самое, что и несовпадение версии
+	if errors.Is(err, redis.TxFailedErr) {
+		err = ErrAPIFieldChanged
+	}
+	if errors.Is(err, ErrAPIFieldChanged) {
+		return err
+	}
 	if err != nil {
 		rds.metrics.ErrorsTotal.WithLabelValues("redis_set_api").Inc()
 		rds.metrics.QueryTotal.WithLabelValues("redis_set_api", "error").Inc()
@@ -73,42 +132,62 @@ func (rds *Redis) SetAPIField(apiData *models.APIPGS) error {
 	return nil
 }
 
-func (rds *Redis) GetAPIField(apikey string) (*models.APIPGS, error) {
+// GetAPIField - аккаунт из кэша по хэшу ключа (models.HashAPIKey); nil, nil - в кэше его нет
+func (rds *Redis) GetAPIField(keyHash string) (*models.APIPGS, error) {
 	ctx := context.Background()
 	start := time.Now()
-	user, err := rds.pool.HGetAll(ctx, fmt.Sprintf("apikey:%s", apikey)).Result()
+	user, err := rds.pool.HGetAll(ctx, fmt.Sprintf("apikey:%s", keyHash)).Result()
 	if err != nil {
 		rds.metrics.ErrorsTotal.WithLabelValues("redis_get_api").Inc()
 		rds.metrics.QueryTotal.WithLabelValues("redis_get_api", "error").Inc()
 		return nil, err
 	}
+	// в кэше нет ключа или запись сохранена до появления storageId и статуса ключа
+	if user["storageId"] == "" || user["status"] == "" {
+		return nil, nil
+	}
 	id, _ := strconv.Atoi(user["id"])
 	cloudAccess := user["cloudAccess"]
 	email := user["email"]
 	CreatedAt, _ := time.Parse("2006-01-02 15:04:05", user["createdAt"])
 	LastLogin, _ := time.Parse("2006-01-02 15:04:05", user["lastLogin"])
+	quotaMB, _ := strconv.ParseInt(user["quotaMb"], 10, 64)
 
 	rds.metrics.QueryTotal.WithLabelValues("redis_get_api", "success").Inc()
 	rds.metrics.QueryDuration.WithLabelValues("redis_get_api").Observe(time.Since(start).Seconds())
 	return &models.APIPGS{
 		Id:          id,
-		KeyName:     apikey,
+		KeyPrefix:   user["prefix"],
+		KeyHash:     keyHash,
+		StorageId:   user["storageId"],
 		Email:       email,
 		CloudAccess: cloudAccess,
 		CreatedAt:   CreatedAt,
 		LastLogin:   LastLogin,
+		Status:      user["status"],
+		Permissions: user["permissions"],
+		QuotaMB:     quotaMB,
 	}, nil
 }
 
-func (rds *Redis) DelAPIField(apikey string) {
+// DelAPIField - удаляет аккаунт из кэша и поднимает версию ключа, чтобы запись, которую в этот момент
+// готовит Authenticate по старому состоянию из базы, не попала в кэш. id - хэш ключа, у записей
+// до перехода на хэши - сам ключ. Вызывается после изменения в базе
+func (rds *Redis) DelAPIField(id string) {
 	ctx := context.Background()
-	rds.pool.HDel(ctx, fmt.Sprintf("apikey:%s", apikey))
+	pipeline := rds.pool.TxPipeline()
+	pipeline.Incr(ctx, apiVersionKey(id))
+	pipeline.Expire(ctx, apiVersionKey(id), apiVersionTTL)
+	pipeline.Del(ctx, fmt.Sprintf("apikey:%s", id))
+	if _, err := pipeline.Exec(ctx); err != nil {
+		rds.metrics.ErrorsTotal.WithLabelValues("redis_del_api").Inc()
+	}
 }
 
-func (rds *Redis) ExistsAPIField(apikey string) bool {
+func (rds *Redis) ExistsAPIField(keyHash string) bool {
 	ctx := context.Background()
 	start := time.Now()
-	exist, err := rds.pool.Exists(ctx, fmt.Sprintf("apikey:%s", apikey)).Result()
+	exist, err := rds.pool.Exists(ctx, fmt.Sprintf("apikey:%s", keyHash)).Result()
 	if err != nil {
 		rds.metrics.ErrorsTotal.WithLabelValues("redis_exists_api").Inc()
 		rds.metrics.QueryTotal.WithLabelValues("redis_exists_api", "error").Inc()
@@ -123,18 +202,22 @@ func (rds *Redis) ExistsAPIField(apikey string) bool {
 	return true
 }
 
-func (rds *Redis) UpdateLastLogin(apikey string) error {
+// updateLastLogin - время входа в существующей записи кэша
+var updateLastLogin = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("HSET", KEYS[1], "lastLogin", ARGV[1])
+end
+return 0
+`)
+
+func (rds *Redis) UpdateLastLogin(keyHash string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	start := time.Now()
-	// делаем транзакцию
-	pipeline := rds.pool.Pipeline()
-
-	pipeline.HSet(ctx, "apikey:"+apikey, "lastLogin", time.Now())
-	pipeline.Expire(ctx, "apikey:"+apikey, 14*24*time.Hour)
-
-	_, err := pipeline.Exec(ctx)
-	if err != nil {
+	// только у существующей записи: иначе HSET создал бы ее заново после отзыва ключа. Срок жизни записи
+	// не продлевается, он отсчитывается от чтения из базы
+	err := updateLastLogin.Run(ctx, rds.pool, []string{"apikey:" + keyHash}, time.Now()).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
 		rds.metrics.ErrorsTotal.WithLabelValues("redis_update_last_login").Inc()
 		rds.metrics.QueryTotal.WithLabelValues("redis_update_last_login", "error").Inc()
 		return err
@@ -143,3 +226,161 @@ func (rds *Redis) UpdateLastLogin(apikey string) error {
 	rds.metrics.QueryDuration.WithLabelValues("redis_update_last_login").Observe(time.Since(start).Seconds())
 	return nil
 }
+
+// eventsChannel - канал pub/sub, в который публикуются события конкретного хранилища
+func eventsChannel(bucket string) string {
+	return "events:" + bucket
+}
+
+// PublishEvent - публикует событие хранилища, чтобы его получили все реплики файлового сервера
+func (rds *Redis) PublishEvent(bucket string, event models.StorageEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err = rds.pool.Publish(ctx, eventsChannel(bucket), payload).Err(); err != nil {
+		rds.metrics.ErrorsTotal.WithLabelValues("redis_publish_event").Inc()
+		rds.metrics.QueryTotal.WithLabelValues("redis_publish_event", "error").Inc()
+		return err
+	}
+	rds.metrics.QueryTotal.WithLabelValues("redis_publish_event", "success").Inc()
+	rds.metrics.QueryDuration.WithLabelValues("redis_publish_event").Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// SubscribeEvents - подписка на события хранилища, канал закрывается после отмены ctx
+func (rds *Redis) SubscribeEvents(ctx context.Context, bucket string) (<-chan models.StorageEvent, error) {
+	pubSub := rds.pool.Subscribe(ctx, eventsChannel(bucket))
+	// Дожидаемся подтверждения подписки, иначе первые события могут потеряться
+	if _, err := pubSub.Receive(ctx); err != nil {
+		rds.metrics.ErrorsTotal.WithLabelValues("redis_subscribe_events").Inc()
+		rds.metrics.QueryTotal.WithLabelValues("redis_subscribe_events", "error").Inc()
+		_ = pubSub.Close()
+		return nil, err
+	}
+	rds.metrics.QueryTotal.WithLabelValues("redis_subscribe_events", "success").Inc()
+
+	events := make(chan models.StorageEvent)
+	go func() {
+		defer close(events)
+		defer func() { _ = pubSub.Close() }()
+		messages := pubSub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event models.StorageEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// fetchJobKey - задачи загрузки по URL лежат под хранилищем, поэтому чужую задачу не прочитать
+func fetchJobKey(bucket string, id string) string {
+	return "fetch:" + bucket + ":" + id
+}
+
+// SetFetchJob - сохраняет состояние задачи загрузки по URL, запись живет ttl
+func (rds *Redis) SetFetchJob(bucket string, job *models.FetchJob, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err = rds.pool.Set(ctx, fetchJobKey(bucket, job.Id), payload, ttl).Err(); err != nil {
+		rds.metrics.ErrorsTotal.WithLabelValues("redis_set_fetch_job").Inc()
+		rds.metrics.QueryTotal.WithLabelValues("redis_set_fetch_job", "error").Inc()
+		return err
+	}
+	rds.metrics.QueryTotal.WithLabelValues("redis_set_fetch_job", "success").Inc()
+	rds.metrics.QueryDuration.WithLabelValues("redis_set_fetch_job").Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// GetFetchJob - состояние задачи загрузки по URL, nil если задачи нет или она устарела
+func (rds *Redis) GetFetchJob(bucket string, id string) (*models.FetchJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	payload, err := rds.pool.Get(ctx, fetchJobKey(bucket, id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		rds.metrics.ErrorsTotal.WithLabelValues("redis_get_fetch_job").Inc()
+		rds.metrics.QueryTotal.WithLabelValues("redis_get_fetch_job", "error").Inc()
+		return nil, err
+	}
+	job := &models.FetchJob{}
+	if err = json.Unmarshal(payload, job); err != nil {
+		return nil, err
+	}
+	rds.metrics.QueryTotal.WithLabelValues("redis_get_fetch_job", "success").Inc()
+	rds.metrics.QueryDuration.WithLabelValues("redis_get_fetch_job").Observe(time.Since(start).Seconds())
+	return job, nil
+}
+
+// lockKey - блокировка фоновой задачи, общая для всех реплик
+func lockKey(name string) string {
+	return "lock:" + name
+}
+
+// Lock - берет блокировку name на ttl, чтобы фоновую задачу выполняла одна реплика. Возвращает токен для
+// Unlock; пустой токен без ошибки - блокировку держит другая реплика
+func (rds *Redis) Lock(name string, ttl time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	token := hex.EncodeToString(buf)
+	ok, err := rds.pool.SetNX(ctx, lockKey(name), token, ttl).Result()
+	if err != nil {
+		rds.metrics.ErrorsTotal.WithLabelValues("redis_lock").Inc()
+		rds.metrics.QueryTotal.WithLabelValues("redis_lock", "error").Inc()
+		return "", err
+	}
+	rds.metrics.QueryTotal.WithLabelValues("redis_lock", "success").Inc()
+	rds.metrics.QueryDuration.WithLabelValues("redis_lock").Observe(time.Since(start).Seconds())
+	if !ok {
+		return "", nil
+	}
+	return token, nil
+}
+
+// unlock - удаляет блокировку, только если она все еще наша: после истечения ttl ее могла взять другая реплика
+var unlock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Unlock - отпускает блокировку, взятую Lock
+func (rds *Redis) Unlock(name string, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := unlock.Run(ctx, rds.pool, []string{lockKey(name)}, token).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		rds.metrics.ErrorsTotal.WithLabelValues("redis_unlock").Inc()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"CloudStorageProject-FileServer/internal/metrics"
+	"CloudStorageProject-FileServer/pkg/config"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func testRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	ctx := context.WithValue(context.Background(), "config", &config.Config{RedisHost: server.Host(),
+		RedisPort: server.Port()})
+	rds, err := NewRedis(ctx, &metrics.RedisMetrics{
+		QueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"operation"}),
+		QueryTotal:    prometheus.NewCounterVec(prometheus.CounterOpts{Name: "total"}, []string{"operation", "status"}),
+		ErrorsTotal:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: "errors"}, []string{"operation"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rds, server
+}
+
+func TestLock(t *testing.T) {
+	rds, server := testRedis(t)
+	token, err := rds.Lock("sweep", time.Minute)
+	if err != nil || token == "" {
+		t.Fatalf("Lock = %q, %v", token, err)
+	}
+	if other, err := rds.Lock("sweep", time.Minute); err != nil || other != "" {
+		t.Fatalf("second Lock = %q, %v; want the lock to be held", other, err)
+	}
+	// чужой токен блокировку не снимает
+	if err = rds.Unlock("sweep", "stale"); err != nil {
+		t.Fatal(err)
+	}
+	if other, _ := rds.Lock("sweep", time.Minute); other != "" {
+		t.Fatal("Unlock with a foreign token released the lock")
+	}
+	if err = rds.Unlock("sweep", token); err != nil {
+		t.Fatal(err)
+	}
+	again, err := rds.Lock("sweep", time.Minute)
+	if err != nil || again == "" {
+		t.Fatalf("Lock after Unlock = %q, %v", again, err)
+	}
+
+	// блокировка упавшей реплики истекает
+	server.FastForward(2 * time.Minute)
+	if token, err = rds.Lock("sweep", time.Minute); err != nil || token == "" {
+		t.Fatalf("Lock after expiry = %q, %v", token, err)
+	}
+}
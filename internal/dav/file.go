@@ -0,0 +1,293 @@
+package dav
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// fileInfo - характеристики объекта для PROPFIND; ETag и Content-Type берутся из хранилища, без чтения файла
+type fileInfo struct {
+	info models.ObjectInfo
+}
+
+func (fi *fileInfo) Name() string {
+	return path.Base("/" + strings.TrimSuffix(fi.info.Key, "/"))
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.info.Size
+}
+
+func (fi *fileInfo) Mode() os.FileMode {
+	if fi.info.IsDir {
+		return os.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.info.LastModified
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.info.IsDir
+}
+
+func (fi *fileInfo) Sys() any {
+	return nil
+}
+
+func (fi *fileInfo) ETag(_ context.Context) (string, error) {
+	if fi.info.IsDir || fi.info.ETag == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return `"` + strings.Trim(fi.info.ETag, `"`) + `"`, nil
+}
+
+func (fi *fileInfo) ContentType(_ context.Context) (string, error) {
+	if fi.info.ContentType == "" || fi.info.ContentType == "application/octet-stream" {
+		return "", webdav.ErrNotImplemented
+	}
+	return fi.info.ContentType, nil
+}
+
+// dir - открытая папка, Readdir отдает ее содержимое одним списком хранилища
+type dir struct {
+	fs       *FileSystem
+	info     *fileInfo
+	children []fs.FileInfo
+	listed   bool
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+func (d *dir) Read(_ []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (d *dir) Seek(_ int64, _ int) (int64, error) {
+	return 0, os.ErrInvalid
+}
+
+func (d *dir) Write(_ []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Readdir(count int) ([]fs.FileInfo, error) {
+	if !d.listed {
+		prefix := d.info.info.Key
+		objects, err := d.fs.storage.List(d.fs.bucket, prefix, false)
+		if err != nil {
+			return nil, notExist(err)
+		}
+		for _, obj := range objects {
+			// маркер самой папки - не ее содержимое
+			if obj.Key == prefix {
+				continue
+			}
+			d.children = append(d.children, &fileInfo{info: obj})
+		}
+		d.listed = true
+	}
+	if count <= 0 {
+		children := d.children
+		d.children = nil
+		return children, nil
+	}
+	if len(d.children) == 0 {
+		return nil, io.EOF
+	}
+	n := min(count, len(d.children))
+	children := d.children[:n]
+	d.children = d.children[n:]
+	return children, nil
+}
+
+// reader - файл на чтение. Хранилище отдает только поток, поэтому Seek переоткрывает его с нужного места
+type reader struct {
+	fs     *FileSystem
+	info   *fileInfo
+	body   io.ReadCloser
+	offset int64
+	// position - сколько байт уже прочитано из body
+	position int64
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.offset >= r.info.Size() {
+		return 0, io.EOF
+	}
+	if r.body == nil || r.position != r.offset {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	r.position += int64(n)
+	return n, err
+}
+
+func (r *reader) open() error {
+	if r.body != nil {
+		_ = r.body.Close()
+		r.body = nil
+	}
+	body, _, err := r.fs.storage.GetOne(r.fs.bucket, r.info.info.Key)
+	if err != nil {
+		return notExist(err)
+	}
+	if _, err = io.CopyN(io.Discard, body, r.offset); err != nil {
+		_ = body.Close()
+		return err
+	}
+	r.body = body
+	r.position = r.offset
+	return nil
+}
+
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.info.Size()
+	default:
+		return 0, os.ErrInvalid
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *reader) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
+
+func (r *reader) Readdir(_ int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (r *reader) Stat() (fs.FileInfo, error) {
+	return r.info, nil
+}
+
+func (r *reader) Write(_ []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+// writer - файл на запись: тело потоком уходит в хранилище, файл появляется после Close
+type writer struct {
+	fs      *FileSystem
+	key     string
+	pipe    *io.PipeWriter
+	done    chan error
+	written int64
+	// copied - COPY внутри хранилища уже выполнен на стороне хранилища
+	copied bool
+}
+
+func (w *writer) start() {
+	pr, pw := io.Pipe()
+	w.pipe = pw
+	w.done = make(chan error, 1)
+	go func() {
+		err := w.fs.storage.CreateOne(w.fs.bucket, models.FileMinio{
+			FileName:    w.key,
+			Reader:      pr,
+			Size:        -1,
+			ContentType: mime.TypeByExtension(path.Ext(w.key)),
+		})
+		// если хранилище вернуло ошибку раньше конца тела, запись в pipe не должна зависнуть
+		_ = pr.CloseWithError(err)
+		w.done <- err
+	}()
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.copied {
+		return 0, os.ErrInvalid
+	}
+	if w.pipe == nil {
+		w.start()
+	}
+	n, err := w.pipe.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// ReadFrom - COPY из того же хранилища выполняется копированием объекта, без передачи содержимого через сервер
+func (w *writer) ReadFrom(src io.Reader) (int64, error) {
+	if r, ok := src.(*reader); ok && r.fs.storage == w.fs.storage && r.fs.bucket == w.fs.bucket &&
+		w.pipe == nil && r.offset == 0 {
+		if err := w.fs.storage.Copy(w.fs.bucket, r.info.info.Key, w.key); err != nil {
+			return 0, notExist(err)
+		}
+		w.copied = true
+		w.written = r.info.Size()
+		return w.written, nil
+	}
+	n, err := io.Copy(struct{ io.Writer }{w}, src)
+	if err != nil && w.pipe != nil {
+		// тело оборвалось: недописанный файл не должен попасть в хранилище
+		_ = w.pipe.CloseWithError(err)
+	}
+	return n, err
+}
+
+func (w *writer) Close() error {
+	if w.copied {
+		return nil
+	}
+	if w.pipe == nil {
+		// пустой файл: тело так и не пришло
+		w.start()
+	}
+	_ = w.pipe.Close()
+	return <-w.done
+}
+
+func (w *writer) Read(_ []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (w *writer) Seek(_ int64, _ int) (int64, error) {
+	return 0, os.ErrInvalid
+}
+
+func (w *writer) Readdir(_ int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (w *writer) Stat() (fs.FileInfo, error) {
+	return &fileInfo{info: models.ObjectInfo{Key: w.key, Size: w.written, LastModified: time.Now()}}, nil
+}
+
+var (
+	_ webdav.File         = (*dir)(nil)
+	_ webdav.File         = (*reader)(nil)
+	_ webdav.File         = (*writer)(nil)
+	_ webdav.ETager       = (*fileInfo)(nil)
+	_ webdav.ContentTyper = (*fileInfo)(nil)
+)
@@ -0,0 +1,250 @@
+package dav
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"errors"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/net/webdav"
+)
+
+// FileSystem - хранилище пользователя как файловая система WebDAV. Папки - префиксы ключей,
+// пустая папка хранится маркером с "/" в конце, как это делают S3-клиенты
+type FileSystem struct {
+	storage storage.Storage
+	bucket  string
+}
+
+func NewFileSystem(st storage.Storage, bucket string) *FileSystem {
+	return &FileSystem{storage: st, bucket: bucket}
+}
+
+// objectKey - ключ объекта из пути WebDAV, корень - пустая строка
+func objectKey(name string) string {
+	return strings.Trim(path.Clean("/"+name), "/")
+}
+
+// newKey - ключ для создаваемого объекта, имя проверяется так же, как при загрузке через API
+func newKey(name string) (string, error) {
+	key := objectKey(name)
+	if key == "" {
+		return "", os.ErrExist
+	}
+	return storage.SanitizeObjectName(key)
+}
+
+// notExist - ошибки хранилища "нет файла" в виде, который понимает webdav
+func notExist(err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return os.ErrNotExist
+	}
+	return err
+}
+
+// stat - файл или папка по ключу. Папка существует, если есть ее маркер или хотя бы один объект внутри
+func (fs *FileSystem) stat(key string) (*fileInfo, error) {
+	if key == "" {
+		return &fileInfo{info: models.ObjectInfo{IsDir: true}}, nil
+	}
+	info, err := fs.storage.Stat(fs.bucket, key)
+	if err == nil && !info.IsDir {
+		return &fileInfo{info: *info}, nil
+	}
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	if marker, errMarker := fs.storage.Stat(fs.bucket, key+"/"); errMarker == nil {
+		return &fileInfo{info: models.ObjectInfo{Key: key + "/", LastModified: marker.LastModified, IsDir: true}}, nil
+	}
+	children, err := fs.storage.List(fs.bucket, key+"/", false)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	if len(children) == 0 {
+		return nil, os.ErrNotExist
+	}
+	return &fileInfo{info: models.ObjectInfo{Key: key + "/", IsDir: true}}, nil
+}
+
+// parentExists - webdav отвечает 409, если родительской папки нет
+func (fs *FileSystem) parentExists(key string) error {
+	parent := path.Dir(key)
+	if parent == "." {
+		return nil
+	}
+	info, err := fs.stat(parent)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return os.ErrNotExist
+	}
+	return nil
+}
+
+func (fs *FileSystem) Mkdir(_ context.Context, name string, _ os.FileMode) error {
+	key, err := newKey(name)
+	if err != nil {
+		return err
+	}
+	if _, err = fs.stat(key); err == nil {
+		return os.ErrExist
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err = fs.parentExists(key); err != nil {
+		return err
+	}
+	return fs.storage.CreateOne(fs.bucket, models.FileMinio{FileName: key + "/", Reader: strings.NewReader("")})
+}
+
+func (fs *FileSystem) OpenFile(_ context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE) != 0 {
+		key, err := newKey(name)
+		if err != nil {
+			return nil, err
+		}
+		info, err := fs.stat(key)
+		if err == nil && info.IsDir() {
+			return nil, os.ErrExist
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err = fs.parentExists(key); err != nil {
+			return nil, err
+		}
+		return &writer{fs: fs, key: key}, nil
+	}
+	info, err := fs.stat(objectKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &dir{fs: fs, info: info}, nil
+	}
+	return &reader{fs: fs, info: info}, nil
+}
+
+func (fs *FileSystem) RemoveAll(_ context.Context, name string) error {
+	key := objectKey(name)
+	if key == "" {
+		return os.ErrPermission
+	}
+	info, err := fs.stat(key)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return notExist(fs.storage.Delete(fs.bucket, key))
+	}
+	objects, err := fs.storage.List(fs.bucket, key+"/", true)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err = fs.storage.Delete(fs.bucket, obj.Key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return fs.removeMarker(key)
+}
+
+// removeMarker - маркер папки мог и не существовать, если папка появилась из ключей файлов
+func (fs *FileSystem) removeMarker(key string) error {
+	if err := fs.storage.Delete(fs.bucket, key+"/"); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Rename - MOVE: файл переименовывается в хранилище, папка - каждый объект под ее префиксом
+func (fs *FileSystem) Rename(_ context.Context, oldName string, newName string) error {
+	oldKey := objectKey(oldName)
+	key, err := newKey(newName)
+	if err != nil {
+		return err
+	}
+	if oldKey == "" || strings.HasPrefix(key+"/", oldKey+"/") {
+		return os.ErrInvalid
+	}
+	info, err := fs.stat(oldKey)
+	if err != nil {
+		return err
+	}
+	if err = fs.parentExists(key); err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return notExist(fs.storage.Rename(fs.bucket, oldKey, key))
+	}
+	files, dirs, empty, err := fs.tree(oldKey + "/")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err = fs.storage.Rename(fs.bucket, file, key+"/"+strings.TrimPrefix(file, oldKey+"/")); err != nil {
+			return notExist(err)
+		}
+	}
+	// пустые папки существуют только маркерами, их нужно создать заново
+	if len(files) == 0 && len(empty) == 0 {
+		empty = append(empty, oldKey+"/")
+	}
+	for _, dir := range empty {
+		target := key + "/" + strings.TrimPrefix(dir, oldKey+"/")
+		if err = fs.storage.CreateOne(fs.bucket, models.FileMinio{FileName: target, Reader: strings.NewReader("")}); err != nil {
+			return err
+		}
+	}
+	// старые маркеры - с самых вложенных
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err = fs.storage.Delete(fs.bucket, dirs[i]); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return fs.removeMarker(oldKey)
+}
+
+// tree - содержимое папки: ключи файлов, все вложенные папки (сверху вниз) и пустые папки среди них.
+// Обход идет по уровням, потому что рекурсивный список не у всех драйверов показывает пустые папки
+func (fs *FileSystem) tree(prefix string) (files []string, dirs []string, empty []string, err error) {
+	objects, err := fs.storage.List(fs.bucket, prefix, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, obj := range objects {
+		if obj.Key == prefix {
+			continue
+		}
+		if !obj.IsDir {
+			files = append(files, obj.Key)
+			continue
+		}
+		subFiles, subDirs, subEmpty, errSub := fs.tree(obj.Key)
+		if errSub != nil {
+			return nil, nil, nil, errSub
+		}
+		dirs = append(append(dirs, obj.Key), subDirs...)
+		files = append(files, subFiles...)
+		empty = append(empty, subEmpty...)
+		if len(subFiles) == 0 && len(subDirs) == 0 {
+			empty = append(empty, obj.Key)
+		}
+	}
+	return files, dirs, empty, nil
+}
+
+func (fs *FileSystem) Stat(_ context.Context, name string) (os.FileInfo, error) {
+	info, err := fs.stat(objectKey(name))
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+var _ webdav.FileSystem = (*FileSystem)(nil)
@@ -0,0 +1,61 @@
+package dav
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+
+	"golang.org/x/net/webdav"
+)
+
+// Prefix - адрес, по которому хранилище монтируется как сетевой диск
+const Prefix = "/dav"
+
+// Handler - WebDAV поверх хранилища пользователя. Аккаунт определяет middleware по паролю Basic-авторизации,
+// блокировки LOCK у каждого хранилища свои, чтобы одинаковые пути разных пользователей не мешали друг другу
+type Handler struct {
+	logger *slog.Logger
+	mu     sync.Mutex
+	locks  map[string]webdav.LockSystem
+}
+
+func NewHandler(logger *slog.Logger) *Handler {
+	return &Handler{
+		logger: logger,
+		locks:  make(map[string]webdav.LockSystem),
+	}
+}
+
+// lockSystem - блокировки хранилища; живут в памяти процесса, как и в большинстве WebDAV-серверов
+func (h *Handler) lockSystem(bucket string) webdav.LockSystem {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ls, ok := h.locks[bucket]
+	if !ok {
+		ls = webdav.NewMemLS()
+		h.locks[bucket] = ls
+	}
+	return ls
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket := r.Context().Value("bucket").(string)
+	st := r.Context().Value("storage").(storage.Storage)
+	handler := &webdav.Handler{
+		Prefix:     Prefix,
+		FileSystem: NewFileSystem(st, bucket),
+		LockSystem: h.lockSystem(bucket),
+		Logger: func(r *http.Request, err error) {
+			// отсутствующий или уже существующий файл - обычный ответ 404/405, а не ошибка сервера
+			if err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, os.ErrExist) {
+				h.logger.Warn("webdav request error", "method", r.Method, "path", r.URL.Path,
+					"error", err.Error(), "place", tools.GetPlace())
+			}
+		},
+	}
+	handler.ServeHTTP(w, r)
+}
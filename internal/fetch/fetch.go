@@ -0,0 +1,290 @@
+package fetch
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/config"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// jobTTL - сколько хранится состояние задачи в redis
+	jobTTL = 24 * time.Hour
+	// progressInterval - как часто сохранять прогресс скачивания
+	progressInterval = time.Second
+	// defaultName - имя файла, если его не удалось взять ни из запроса, ни из URL
+	defaultName = "download"
+)
+
+var (
+	// ErrInvalidRequest - URL не http(s), хост не разрешается или неверный on_conflict
+	ErrInvalidRequest = errors.New("invalid fetch request")
+	// ErrForbiddenAddress - URL ведет во внутреннюю сеть, которой нет в FETCH_ALLOWED_NETWORKS
+	ErrForbiddenAddress = errors.New("address is not allowed")
+	// ErrTooLarge - файл больше FETCH_MAX_SIZE_MB
+	ErrTooLarge = errors.New("file is too large")
+	// ErrQueueFull - задач в очереди больше FETCH_QUEUE_SIZE
+	ErrQueueFull = errors.New("too many fetch jobs, try again later")
+)
+
+// Fetcher - скачивает файлы по URL в хранилище пользователя в фоне, состояние задач лежит в redis
+type Fetcher struct {
+	redis     *redis.Redis
+	storage   storage.Storage
+	logger    *slog.Logger
+	guard     *guard
+	client    *http.Client
+	maxSize   int64
+	timeout   time.Duration
+	queueSize int64
+	pending   atomic.Int64
+	slots     chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	jobs      sync.WaitGroup
+}
+
+func NewFetcher(ctx context.Context, rds *redis.Redis, st storage.Storage) (*Fetcher, error) {
+	conf := ctx.Value("config").(*config.Config)
+	logger := ctx.Value("logger").(*slog.Logger)
+	g, err := newGuard(conf.FetchAllowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+	jobsCtx, cancel := context.WithCancel(context.Background())
+	return &Fetcher{
+		redis:     rds,
+		storage:   st,
+		logger:    logger,
+		guard:     g,
+		client:    g.client(),
+		maxSize:   conf.FetchMaxSizeMB << 20,
+		timeout:   time.Duration(conf.FetchTimeout) * time.Second,
+		queueSize: int64(conf.FetchQueueSize),
+		slots:     make(chan struct{}, conf.FetchConcurrency),
+		ctx:       jobsCtx,
+		cancel:    cancel,
+	}, nil
+}
+
+// Submit - проверяет запрос и ставит задачу в очередь. Сама загрузка идет в фоне
+func (f *Fetcher) Submit(bucket string, request models.FetchRequest) (*models.FetchJob, error) {
+	source, err := url.Parse(request.Url)
+	if err != nil || (source.Scheme != "http" && source.Scheme != "https") || source.Hostname() == "" {
+		return nil, fmt.Errorf("%w: only absolute http and https urls are supported", ErrInvalidRequest)
+	}
+	if source.User != nil {
+		return nil, fmt.Errorf("%w: credentials in url are not supported", ErrInvalidRequest)
+	}
+	onConflict, ok := storage.ValidConflictPolicy(request.OnConflict)
+	if !ok {
+		return nil, fmt.Errorf("%w: on_conflict must be overwrite, rename or fail", ErrInvalidRequest)
+	}
+	name, err := storage.SanitizeObjectName(targetName(source, request.Path))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = f.guard.check(ctx, source.Hostname()); err != nil {
+		return nil, err
+	}
+
+	if f.pending.Add(1) > f.queueSize {
+		f.pending.Add(-1)
+		return nil, ErrQueueFull
+	}
+	now := time.Now().UTC()
+	job := &models.FetchJob{
+		Id:         uuid.NewString(),
+		Url:        source.String(),
+		Path:       name,
+		OnConflict: onConflict,
+		Status:     models.FetchQueued,
+		BytesTotal: -1,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err = f.redis.SetFetchJob(bucket, job, jobTTL); err != nil {
+		f.pending.Add(-1)
+		return nil, err
+	}
+	f.jobs.Add(1)
+	go f.run(bucket, *job)
+	return job, nil
+}
+
+// Close - прерывает текущие загрузки и дожидается, пока задачи сохранят свое состояние
+func (f *Fetcher) Close(ctx context.Context) error {
+	f.cancel()
+	done := make(chan struct{})
+	go func() {
+		f.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// targetName - путь из запроса; если он пустой или заканчивается на "/", имя берется из URL
+func targetName(source *url.URL, target string) string {
+	if target != "" && !strings.HasSuffix(target, "/") {
+		return target
+	}
+	base := path.Base(source.Path)
+	if base == "/" || base == "." || base == "" {
+		base = defaultName
+	}
+	return target + base
+}
+
+func (f *Fetcher) run(bucket string, job models.FetchJob) {
+	defer f.jobs.Done()
+	defer f.pending.Add(-1)
+	select {
+	case f.slots <- struct{}{}:
+		defer func() { <-f.slots }()
+	case <-f.ctx.Done():
+		f.finish(bucket, &job, f.ctx.Err())
+		return
+	}
+	job.Status = models.FetchRunning
+	f.save(bucket, &job)
+	f.finish(bucket, &job, f.fetch(bucket, &job))
+}
+
+// fetch - скачивает файл и потоком отдает его в хранилище
+func (f *Fetcher) fetch(bucket string, job *models.FetchJob) error {
+	ctx, cancel := context.WithTimeout(f.ctx, f.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, job.Url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "CloudStorage-Fetch/1.0")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("source responded with %s", resp.Status)
+	}
+	if resp.ContentLength > f.maxSize {
+		return ErrTooLarge
+	}
+
+	name, _, err := storage.ResolveConflict(f.storage, bucket, job.Path, job.OnConflict)
+	if err != nil {
+		return f.storageError(job, err)
+	}
+	job.Path = name
+	job.BytesTotal = resp.ContentLength
+
+	body := &progressReader{reader: resp.Body, limit: f.maxSize, saved: time.Now(), onProgress: func(done int64) {
+		job.BytesDone = done
+		f.save(bucket, job)
+	}}
+	err = f.storage.CreateOne(bucket, models.FileMinio{
+		FileName:    name,
+		Reader:      body,
+		Size:        resp.ContentLength,
+		ContentType: contentType(resp.Header.Get("Content-Type")),
+	})
+	job.BytesDone = body.done
+	if body.err != nil {
+		// ошибка источника понятнее, чем то, во что ее превратил драйвер хранилища
+		return body.err
+	}
+	if err != nil {
+		return f.storageError(job, err)
+	}
+	return nil
+}
+
+// storageError - ошибка хранилища попадает в задачу без подробностей драйвера, полностью - только в лог
+func (f *Fetcher) storageError(job *models.FetchJob, err error) error {
+	f.logger.Error("fetch job storage error", "job", job.Id, "error", err.Error(), "place", tools.GetPlace())
+	return errors.New(apierror.From(err).Message)
+}
+
+// finish - сохраняет итог задачи
+func (f *Fetcher) finish(bucket string, job *models.FetchJob, err error) {
+	job.Status = models.FetchDone
+	if err != nil {
+		job.Status = models.FetchFailed
+		job.Error = err.Error()
+		f.logger.Warn("fetch job failed", "job", job.Id, "url", job.Url, "error", err.Error(),
+			"place", tools.GetPlace())
+	}
+	f.save(bucket, job)
+	if err == nil {
+		event := models.StorageEvent{Type: models.EventUpload, FileName: job.Path, Time: time.Now()}
+		if errPublish := f.redis.PublishEvent(bucket, event); errPublish != nil {
+			f.logger.Error("publish storage event error", "error", errPublish.Error(), "place", tools.GetPlace())
+		}
+	}
+}
+
+func (f *Fetcher) save(bucket string, job *models.FetchJob) {
+	job.UpdatedAt = time.Now().UTC()
+	if err := f.redis.SetFetchJob(bucket, job, jobTTL); err != nil {
+		f.logger.Error("save fetch job error", "job", job.Id, "error", err.Error(), "place", tools.GetPlace())
+	}
+}
+
+// contentType - тип из ответа источника, если он разбирается
+func contentType(header string) string {
+	if _, _, err := mime.ParseMediaType(header); err != nil || header == "" {
+		return "application/octet-stream"
+	}
+	return header
+}
+
+// progressReader - считает прочитанное, обрывает файл сверх лимита и раз в progressInterval сообщает прогресс
+type progressReader struct {
+	reader     io.Reader
+	limit      int64
+	done       int64
+	err        error
+	saved      time.Time
+	onProgress func(done int64)
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.reader.Read(buf)
+	p.done += int64(n)
+	if p.done > p.limit {
+		p.err = ErrTooLarge
+		return n, ErrTooLarge
+	}
+	if err != nil && err != io.EOF {
+		p.err = err
+	}
+	if time.Since(p.saved) >= progressInterval {
+		p.saved = time.Now()
+		p.onProgress(p.done)
+	}
+	return n, err
+}
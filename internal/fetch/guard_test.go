@@ -0,0 +1,171 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestGuardPermitted(t *testing.T) {
+	g, err := newGuard("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1", false},
+		{"127.1.2.3", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"172.31.255.255", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // метаданные облака
+		{"169.254.0.1", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"fd12:3456:789a::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false}, // NAT64 на 169.254.169.254
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"255.255.255.255", false},
+		{"8.8.8.8", true},
+		{"172.32.0.1", true},
+		{"2a00:1450:4001::1", true},
+		{"::ffff:8.8.8.8", true},
+	}
+	for _, c := range cases {
+		if got := g.permitted(netip.MustParseAddr(c.addr)); got != c.want {
+			t.Errorf("permitted(%s) = %v, want %v", c.addr, got, c.want)
+		}
+	}
+}
+
+func TestGuardAllowedNetworks(t *testing.T) {
+	g, err := newGuard(" 10.1.0.0/16, 192.168.5.7 ,fd00::/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range map[string]bool{
+		"10.1.2.3":         true,
+		"::ffff:10.1.2.3":  true,
+		"10.2.0.1":         false,
+		"192.168.5.7":      true,
+		"192.168.5.8":      false,
+		"fd00::1":          true,
+		"fc00::1":          false,
+		"169.254.169.254":  false,
+		"127.0.0.1":        false,
+		"93.184.215.14":    true,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := g.permitted(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("permitted(%s) = %v, want %v", addr, got, want)
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "localhost", "10.0.0.1/8/8"} {
+		if _, err = newGuard(bad); err == nil {
+			t.Errorf("newGuard(%q) accepted", bad)
+		}
+	}
+}
+
+func TestGuardCheckLiteral(t *testing.T) {
+	g, err := newGuard("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"127.0.0.1", "169.254.169.254", "::1", "::ffff:192.168.0.1", "fd00::1"} {
+		if err = g.check(context.Background(), host); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("check(%s) = %v, want ErrForbiddenAddress", host, err)
+		}
+	}
+	if err = g.check(context.Background(), "1.1.1.1"); err != nil {
+		t.Errorf("check(1.1.1.1) = %v", err)
+	}
+}
+
+func TestGuardControl(t *testing.T) {
+	g, err := newGuard("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, address := range []string{"127.0.0.1:80", "[::1]:443", "169.254.169.254:80", "[::ffff:10.0.0.1]:80"} {
+		if err = g.control("tcp", address, nil); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("control(%s) = %v, want ErrForbiddenAddress", address, err)
+		}
+	}
+	if err = g.control("tcp", "8.8.8.8:443", nil); err != nil {
+		t.Errorf("control(8.8.8.8:443) = %v", err)
+	}
+}
+
+func TestGuardBlocksRedirect(t *testing.T) {
+	// сервер с перенаправлением сам во внутренней сети, поэтому его адрес разрешен явно
+	g, err := newGuard("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("internal"))
+	}))
+	defer internal.Close()
+	_, port, _ := net.SplitHostPort(internal.Listener.Addr().String())
+
+	targets := map[string]string{
+		"metadata":      "http://169.254.169.254/latest/meta-data/",
+		"loopback ipv6": "http://[::1]:" + port + "/",
+		"private":       "http://10.0.0.1/",
+		"ipv4-mapped":   "http://[::ffff:127.0.0.2]:" + port + "/",
+	}
+	for name, target := range targets {
+		redirect := httptest.NewServer(http.RedirectHandler(target, http.StatusFound))
+		resp, err := g.client().Get(redirect.URL)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		redirect.Close()
+		if !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("%s: redirect to %s: %v, want ErrForbiddenAddress", name, target, err)
+		}
+	}
+
+	// без перенаправления разрешенный адрес доступен
+	resp, err := g.client().Get(internal.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("allowed host: %d", resp.StatusCode)
+	}
+}
+
+func TestGuardRedirectScheme(t *testing.T) {
+	g, err := newGuard("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirect := httptest.NewServer(http.RedirectHandler("file:///etc/passwd", http.StatusFound))
+	defer redirect.Close()
+	resp, err := g.client().Get(redirect.URL)
+	if err == nil {
+		_ = resp.Body.Close()
+	}
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("redirect to file://: %v, want ErrInvalidRequest", err)
+	}
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"encoding/xml"
+	"errors"
+	"net/http"
+)
+
+// s3Error - ошибка в терминах S3: клиенты (aws cli, rclone, SDK) различают ошибки по Code
+type s3Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *s3Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var (
+	errAccessDenied          = &s3Error{http.StatusForbidden, "AccessDenied", "Access Denied."}
+	errInvalidAccessKeyId    = &s3Error{http.StatusForbidden, "InvalidAccessKeyId", "The AWS Access Key Id you provided does not exist in our records."}
+	errSignatureDoesNotMatch = &s3Error{http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided."}
+	errRequestTimeTooSkewed  = &s3Error{http.StatusForbidden, "RequestTimeTooSkewed", "The difference between the request time and the server's time is too large."}
+	errExpiredPresign        = &s3Error{http.StatusForbidden, "AccessDenied", "Request has expired."}
+	errAuthorizationHeader   = &s3Error{http.StatusBadRequest, "AuthorizationHeaderMalformed", "The authorization header is malformed."}
+	errAuthorizationQuery    = &s3Error{http.StatusBadRequest, "AuthorizationQueryParametersError", "Query-string authentication parameters are malformed."}
+	errUnsupportedAlgorithm  = &s3Error{http.StatusBadRequest, "InvalidArgument", "Only AWS4-HMAC-SHA256 signatures are supported."}
+	errWrongRegion           = &s3Error{http.StatusBadRequest, "AuthorizationHeaderMalformed", "The authorization header contains a wrong region."}
+	errMissingDate           = &s3Error{http.StatusBadRequest, "AccessDenied", "AWS authentication requires a valid Date or x-amz-date header."}
+	errContentSHA256Mismatch = &s3Error{http.StatusBadRequest, "XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed."}
+	errInvalidContentSHA256  = &s3Error{http.StatusBadRequest, "InvalidArgument", "x-amz-content-sha256 must be UNSIGNED-PAYLOAD, a SHA256 hash or one of the STREAMING-* payload types."}
+	errIncompleteBody        = &s3Error{http.StatusBadRequest, "IncompleteBody", "You did not provide the number of bytes specified by the Content-Length HTTP header."}
+	errBadDigest             = &s3Error{http.StatusBadRequest, "BadDigest", "The checksum you specified did not match what we received."}
+	errMissingContentLength  = &s3Error{http.StatusLengthRequired, "MissingContentLength", "You must provide the Content-Length HTTP header."}
+	errEntityTooLarge        = &s3Error{http.StatusBadRequest, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed size."}
+	errMalformedXML          = &s3Error{http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema."}
+	errInvalidPartNumber     = &s3Error{http.StatusBadRequest, "InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive."}
+	errInvalidMaxKeys        = &s3Error{http.StatusBadRequest, "InvalidArgument", "max-keys must be a non-negative integer."}
+	errUnsupportedDelimiter  = &s3Error{http.StatusBadRequest, "InvalidArgument", "Only the \"/\" delimiter is supported."}
+	errInvalidToken          = &s3Error{http.StatusBadRequest, "InvalidArgument", "The continuation token provided is incorrect."}
+	errKeyTooLong            = &s3Error{http.StatusBadRequest, "KeyTooLongError", "Your key is too long."}
+	errNoSuchKey             = &s3Error{http.StatusNotFound, "NoSuchKey", "The specified key does not exist."}
+	errNoSuchBucket          = &s3Error{http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist."}
+	errNoSuchUpload          = &s3Error{http.StatusNotFound, "NoSuchUpload", "The specified multipart upload does not exist."}
+	errInvalidPart           = &s3Error{http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found, are out of order or have a wrong ETag."}
+	errNotImplemented        = &s3Error{http.StatusNotImplemented, "NotImplemented", "A header or operation you provided implies functionality that is not implemented."}
+	errMultipartUnsupported  = &s3Error{http.StatusNotImplemented, "NotImplemented", "Multipart upload is not supported by this storage."}
+	errMethodNotAllowed      = &s3Error{http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed against this resource."}
+	errServiceUnavailable    = &s3Error{http.StatusServiceUnavailable, "ServiceUnavailable", "Service is unable to handle request."}
+	errInternal              = &s3Error{http.StatusInternalServerError, "InternalError", "We encountered an internal error. Please try again."}
+)
+
+// toS3Error - ошибки хранилища в ошибки S3. Все, что не распознано, проходит через apierror.From,
+// чтобы недоступность MinIO или Postgres выглядела для клиента так же, как в v1
+func toS3Error(err error) *s3Error {
+	var s3Err *s3Error
+	if errors.As(err, &s3Err) {
+		return s3Err
+	}
+	var nameErr *storage.NameError
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return errNoSuchKey
+	case errors.Is(err, storage.ErrObjectLocked):
+		return errAccessDenied
+	case errors.Is(err, storage.ErrMultipartNotSupported):
+		return errMultipartUnsupported
+	case errors.Is(err, storage.ErrNoSuchUpload):
+		return errNoSuchUpload
+	case errors.Is(err, storage.ErrInvalidPart):
+		return errInvalidPart
+	case errors.As(err, &nameErr):
+		return &s3Error{http.StatusBadRequest, "InvalidArgument", nameErr.Error()}
+	case errors.Is(err, storage.ErrInvalidObjectName):
+		return &s3Error{http.StatusBadRequest, "InvalidArgument", "Object key is not valid."}
+	}
+	apiErr := apierror.From(err)
+	switch {
+	case apiErr.Code == models.ErrCodeStorageNotFound:
+		return errNoSuchBucket
+	case apiErr.Code == models.ErrCodeTooLarge:
+		return errEntityTooLarge
+	case apiErr.Status == http.StatusForbidden:
+		return errAccessDenied
+	case apiErr.Status == http.StatusServiceUnavailable, apiErr.Status == http.StatusGatewayTimeout,
+		apiErr.Status == http.StatusInsufficientStorage:
+		return errServiceUnavailable
+	}
+	return errInternal
+}
+
+// errorResponse - тело ошибки S3
+type errorResponse struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource"`
+	RequestId string   `xml:"RequestId"`
+}
@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Gateway - подмножество S3 API поверх хранилищ аккаунтов. Запрос подписывается SigV4 ключами из Keys,
+// бакет в пути - хранилище аккаунта; к чужим хранилищам доступа нет. Поддерживается только path-style
+type Gateway struct {
+	storage  storage.Storage
+	keys     *Keys
+	accounts AccountLookup
+	logger   *slog.Logger
+}
+
+func NewGateway(st storage.Storage, keys *Keys, lookup AccountLookup, logger *slog.Logger) *Gateway {
+	return &Gateway{
+		storage:  st,
+		keys:     keys,
+		accounts: lookup,
+		logger:   logger,
+	}
+}
+
+// request - проверенный запрос: чей он и чем подписан (подпись нужна для фрагментов aws-chunked)
+type request struct {
+	account *models.APIPGS
+	sig     *signature
+	secret  string
+	bucket  string
+	key     string
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := g.authenticate(r)
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	req.bucket, req.key, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	readOnly := r.Method == http.MethodGet || r.Method == http.MethodHead
+	switch {
+	case !readOnly && !req.account.CanWrite():
+		g.writeError(w, r, errAccessDenied)
+	case req.bucket == "":
+		if r.Method != http.MethodGet {
+			g.writeError(w, r, errMethodNotAllowed)
+			return
+		}
+		g.listBuckets(w, req)
+	case req.bucket != req.account.StorageId:
+		// существование чужих хранилищ не раскрываем
+		g.writeError(w, r, errAccessDenied)
+	case req.key == "":
+		g.serveBucket(w, r, req)
+	default:
+		g.serveObject(w, r, req)
+	}
+}
+
+// authenticate - аккаунт по access key и проверка подписи его секретом
+func (g *Gateway) authenticate(r *http.Request) (*request, error) {
+	sig, err := parseSignature(r)
+	if err != nil {
+		return nil, err
+	}
+	if sig == nil {
+		return nil, errAccessDenied
+	}
+	account, err := g.accounts(sig.accessKey)
+	if err != nil {
+		return nil, err
+	}
+	if !Usable(account) || !account.Active() {
+		return nil, errInvalidAccessKeyId
+	}
+	_, secret := g.keys.Credentials(account)
+	if err = sig.verify(r, secret, g.keys.Region()); err != nil {
+		return nil, err
+	}
+	return &request{account: account, sig: sig, secret: secret}, nil
+}
+
+func (g *Gateway) serveBucket(w http.ResponseWriter, r *http.Request, req *request) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && query.Has("location"):
+		g.writeXML(w, http.StatusOK, locationConstraint{Region: g.keys.Region()})
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		g.listObjectsV2(w, r, req)
+	case r.Method == http.MethodPut && len(query) == 0:
+		g.writeError(w, r, &s3Error{http.StatusConflict, "BucketAlreadyOwnedByYou",
+			"Your previous request to create the named bucket succeeded and you already own it."})
+	default:
+		g.writeError(w, r, errNotImplemented)
+	}
+}
+
+// objectSubresources - параметры, которые превращают запрос к объекту в другую операцию S3
+var objectSubresources = []string{"acl", "tagging", "retention", "legal-hold", "attributes", "torrent", "restore", "select"}
+
+func (g *Gateway) serveObject(w http.ResponseWriter, r *http.Request, req *request) {
+	query := r.URL.Query()
+	for _, sub := range objectSubresources {
+		if query.Has(sub) {
+			g.writeError(w, r, errNotImplemented)
+			return
+		}
+	}
+	switch {
+	case (r.Method == http.MethodGet || r.Method == http.MethodHead) && !query.Has("uploadId"):
+		g.getObject(w, r, req)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		g.uploadPart(w, r, req)
+	case r.Method == http.MethodPut:
+		g.putObject(w, r, req)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		g.abortMultipartUpload(w, r, req)
+	case r.Method == http.MethodDelete:
+		g.deleteObject(w, r, req)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		g.createMultipartUpload(w, r, req)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		g.completeMultipartUpload(w, r, req)
+	default:
+		g.writeError(w, r, errNotImplemented)
+	}
+}
+
+func (g *Gateway) writeXML(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(body)
+}
+
+// writeError - ошибка в формате S3. Сбои хранилища и базы пишутся в лог, ошибки клиента - нет
+func (g *Gateway) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	s3Err := toS3Error(err)
+	if s3Err.Status >= http.StatusInternalServerError && s3Err.Status != http.StatusNotImplemented {
+		g.logger.Error("s3 request failed", "error", err.Error(), "code", s3Err.Code,
+			"request_id", apierror.RequestID(r), "method", r.Method, "path", r.URL.Path, "place", tools.GetPlace())
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(s3Err.Status)
+		return
+	}
+	g.writeXML(w, s3Err.Status, errorResponse{
+		Code:      s3Err.Code,
+		Message:   s3Err.Message,
+		Resource:  r.URL.Path,
+		RequestId: apierror.RequestID(r),
+	})
+}
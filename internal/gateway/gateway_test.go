@@ -0,0 +1,215 @@
+package gateway
+
+import (
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/metrics"
+	"CloudStorageProject-FileServer/internal/provisioning"
+	"CloudStorageProject-FileServer/internal/storage/memory"
+	"CloudStorageProject-FileServer/pkg/config"
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const testRegion = "us-east-1"
+
+// testGateway - шлюз над памятью с аккаунтами u-alice и u-bob; у u-bob лежит secret.txt
+func testGateway(t *testing.T, accounts map[string]*models.APIPGS) (*Gateway, *Keys) {
+	t.Helper()
+	st := memory.NewMemory()
+	err := st.CreateOne("u-bob", models.FileMinio{FileName: "secret.txt", Size: 6, Reader: strings.NewReader("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := NewKeys("gateway-secret", testRegion)
+	lookup := func(storageId string) (*models.APIPGS, error) {
+		return accounts[storageId], nil
+	}
+	return NewGateway(st, keys, lookup, slog.New(slog.NewTextHandler(io.Discard, nil))), keys
+}
+
+func testAccount(id int, storageId string) *models.APIPGS {
+	return &models.APIPGS{Id: id, StorageId: storageId, KeyHash: "hash", Status: models.KeyStatusActive,
+		Permissions: models.DefaultPermissions}
+}
+
+// signed - запрос, подписанный заголовком Authorization как это делает SDK
+func signed(method string, target string, accessKey string, secret string) *http.Request {
+	r := httptest.NewRequest(method, "http://s3.local"+target, nil)
+	at := time.Now().UTC()
+	r.Header.Set("X-Amz-Date", at.Format(amzDateFormat))
+	r.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	sig := &signature{
+		accessKey:     accessKey,
+		date:          at.Format("20060102"),
+		region:        testRegion,
+		signedHeaders: []string{"host", "x-amz-content-sha256", "x-amz-date"},
+		amzDate:       at.Truncate(time.Second),
+		payloadHash:   emptySHA256,
+	}
+	r.Header.Set("Authorization", signAlgorithm+" Credential="+accessKey+"/"+sig.scope()+
+		",SignedHeaders=host;x-amz-content-sha256;x-amz-date,Signature="+sig.expected(r, secret))
+	return r
+}
+
+func serve(g *Gateway, r *http.Request) (int, string) {
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	return w.Code, w.Body.String()
+}
+
+func TestGatewayOwnBucket(t *testing.T) {
+	alice := testAccount(1, "u-alice")
+	bob := testAccount(2, "u-bob")
+	g, keys := testGateway(t, map[string]*models.APIPGS{"u-alice": alice, "u-bob": bob})
+	accessKey, secret := keys.Credentials(bob)
+
+	code, body := serve(g, signed(http.MethodGet, "/u-bob/secret.txt", accessKey, secret))
+	if code != http.StatusOK || body != "secret" {
+		t.Errorf("own object: %d %q", code, body)
+	}
+}
+
+func TestGatewayForeignBucket(t *testing.T) {
+	alice := testAccount(1, "u-alice")
+	bob := testAccount(2, "u-bob")
+	g, keys := testGateway(t, map[string]*models.APIPGS{"u-alice": alice, "u-bob": bob})
+	accessKey, secret := keys.Credentials(alice)
+
+	for _, target := range []string{"/u-bob/secret.txt", "/u-bob?list-type=2", "/u-bob", "/u-nobody/secret.txt"} {
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+			code, body := serve(g, signed(method, target, accessKey, secret))
+			if code != http.StatusForbidden || !strings.Contains(body, "<Code>AccessDenied</Code>") {
+				t.Errorf("%s %s: %d %s", method, target, code, body)
+			}
+		}
+	}
+	if _, err := g.storage.Stat("u-bob", "secret.txt"); err != nil {
+		t.Errorf("foreign object changed: %v", err)
+	}
+}
+
+func TestGatewayRejectsKeys(t *testing.T) {
+	alice := testAccount(1, "u-alice")
+	bob := testAccount(2, "u-bob")
+	suspended := testAccount(3, "u-suspended")
+	suspended.Status = models.KeyStatusSuspended
+	revoked := testAccount(4, "u-revoked")
+	revoked.Status = models.KeyStatusRevoked
+	legacy := testAccount(5, "legacy-key")
+	legacy.KeyHash = models.HashAPIKey("legacy-key")
+	accounts := map[string]*models.APIPGS{"u-alice": alice, "u-bob": bob,
+		"u-suspended": suspended, "u-revoked": revoked, "legacy-key": legacy}
+	g, keys := testGateway(t, accounts)
+
+	_, aliceSecret := keys.Credentials(alice)
+	_, otherSecret := NewKeys("other-secret", testRegion).Credentials(bob)
+	cases := []struct {
+		name      string
+		accessKey string
+		secret    string
+		code      string
+	}{
+		{"bob key with alice secret", "u-bob", aliceSecret, "SignatureDoesNotMatch"},
+		{"secret of another gateway", "u-bob", otherSecret, "SignatureDoesNotMatch"},
+		{"unknown access key", "u-nobody", aliceSecret, "InvalidAccessKeyId"},
+		{"suspended", "u-suspended", "", "InvalidAccessKeyId"},
+		{"revoked", "u-revoked", "", "InvalidAccessKeyId"},
+		{"legacy storage", "legacy-key", "", "InvalidAccessKeyId"},
+	}
+	for _, c := range cases {
+		secret := c.secret
+		if secret == "" {
+			_, secret = keys.Credentials(accounts[c.accessKey])
+		}
+		code, body := serve(g, signed(http.MethodGet, "/"+c.accessKey+"/secret.txt", c.accessKey, secret))
+		if code != http.StatusForbidden || !strings.Contains(body, "<Code>"+c.code+"</Code>") {
+			t.Errorf("%s: %d %s", c.name, code, body)
+		}
+	}
+
+	// отзыв действует на следующий же запрос: аккаунт не кэшируется
+	accessKey, secret := keys.Credentials(bob)
+	if code, _ := serve(g, signed(http.MethodGet, "/u-bob/secret.txt", accessKey, secret)); code != http.StatusOK {
+		t.Fatalf("active bob: %d", code)
+	}
+	bob.Status = models.KeyStatusRevoked
+	if code, _ := serve(g, signed(http.MethodGet, "/u-bob/secret.txt", accessKey, secret)); code != http.StatusForbidden {
+		t.Errorf("revoked bob: %d", code)
+	}
+}
+
+// rotatingAccounts - записи аккаунтов для Provisioning.Rotate
+type rotatingAccounts struct {
+	provisioning.AccountStore
+	accounts map[int]*models.APIPGS
+}
+
+func (a *rotatingAccounts) AccountById(id int) (*models.APIPGS, error) {
+	return a.accounts[id], nil
+}
+
+func (a *rotatingAccounts) RotateAPIKey(id int, newKey string) (*models.APIPGS, error) {
+	rotated := *a.accounts[id]
+	rotated.KeyHash = models.HashAPIKey(newKey)
+	rotated.Key = newKey
+	a.accounts[id] = &rotated
+	return &rotated, nil
+}
+
+func testRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+	server := miniredis.RunT(t)
+	ctx := context.WithValue(context.Background(), "config", &config.Config{RedisHost: server.Host(),
+		RedisPort: server.Port()})
+	rds, err := redis.NewRedis(ctx, &metrics.RedisMetrics{
+		QueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"operation"}),
+		QueryTotal:    prometheus.NewCounterVec(prometheus.CounterOpts{Name: "total"}, []string{"operation", "status"}),
+		ErrorsTotal:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: "errors"}, []string{"operation"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rds
+}
+
+func TestGatewayRotatedKey(t *testing.T) {
+	store := &rotatingAccounts{accounts: map[int]*models.APIPGS{2: testAccount(2, "u-bob")}}
+	ctx := context.WithValue(context.Background(), "config", &config.Config{})
+	ctx = context.WithValue(ctx, "logger", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	prov := provisioning.NewProvisioning(ctx, store, testRedis(t), memory.NewMemory())
+	g, keys := testGateway(t, nil)
+	g.accounts = func(storageId string) (*models.APIPGS, error) {
+		return store.accounts[2], nil
+	}
+
+	oldAccessKey, oldSecret := keys.Credentials(store.accounts[2])
+	if code, _ := serve(g, signed(http.MethodGet, "/u-bob/secret.txt", oldAccessKey, oldSecret)); code != http.StatusOK {
+		t.Fatalf("before rotation: %d", code)
+	}
+	rotated, err := prov.Rotate(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, body := serve(g, signed(http.MethodGet, "/u-bob/secret.txt", oldAccessKey, oldSecret))
+	if code != http.StatusForbidden || !strings.Contains(body, "<Code>SignatureDoesNotMatch</Code>") {
+		t.Errorf("old secret after rotation: %d %s", code, body)
+	}
+	accessKey, secret := keys.Credentials(rotated)
+	if accessKey != oldAccessKey || secret == oldSecret {
+		t.Errorf("rotation kept the secret or changed the bucket: %s", accessKey)
+	}
+	if code, _ = serve(g, signed(http.MethodGet, "/u-bob/secret.txt", accessKey, secret)); code != http.StatusOK {
+		t.Errorf("new secret: %d", code)
+	}
+}
@@ -0,0 +1,43 @@
+package gateway
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// Keys - ключи доступа S3 аккаунтов. Access key - имя хранилища аккаунта, secret key выводится секретом шлюза
+// из хранилища и хэша API-ключа, поэтому хранить ключи S3 отдельно не нужно. Смена API-ключа аккаунта меняет
+// и его secret key, а смена секрета шлюза отзывает все ключи сразу
+type Keys struct {
+	secret []byte
+	region string
+}
+
+func NewKeys(secret string, region string) *Keys {
+	return &Keys{secret: []byte(secret), region: region}
+}
+
+// Region - регион, который клиент должен указать в подписи
+func (k *Keys) Region() string {
+	return k.region
+}
+
+// Credentials - пара ключей S3 для аккаунта
+func (k *Keys) Credentials(account *models.APIPGS) (string, string) {
+	h := hmac.New(sha256.New, k.secret)
+	h.Write([]byte("s3:" + account.StorageId + ":" + account.KeyHash))
+	// 30 байт дают 40 символов, как у секретов AWS
+	return account.StorageId, base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:30])
+}
+
+// Usable - можно ли выдать аккаунту ключи S3. У неперенесенных аккаунтов хранилище называется
+// API-ключом, а access key передается открыто в каждом запросе
+func Usable(account *models.APIPGS) bool {
+	return account != nil && !account.LegacyStorage()
+}
+
+// AccountLookup - аккаунт по имени хранилища; nil, nil - аккаунта нет. Вызывается на каждый запрос
+// без кэша: статус ключа меняет и процесс администратора, а отзыв должен действовать сразу
+type AccountLookup func(storageId string) (*models.APIPGS, error)
@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// maxPartNumber - номера частей в S3 от 1 до 10000
+	maxPartNumber = 10000
+	// maxCompleteBody - 10000 частей с номерами и ETag помещаются в 2 МБ с запасом
+	maxCompleteBody = 2 << 20
+)
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ InitiateMultipartUploadResult"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadId string   `xml:"UploadId"`
+}
+
+type completeMultipartUpload struct {
+	Parts []struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CompleteMultipartUploadResult"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+// multiparter - составная загрузка есть не у всех драйверов, без нее клиенты S3 грузят файлы одним PUT
+func (g *Gateway) multiparter() (storage.Multiparter, error) {
+	multipart, ok := g.storage.(storage.Multiparter)
+	if !ok {
+		return nil, storage.ErrMultipartNotSupported
+	}
+	return multipart, nil
+}
+
+func (g *Gateway) createMultipartUpload(w http.ResponseWriter, r *http.Request, req *request) {
+	name, err := objectName(req.key)
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	multipart, err := g.multiparter()
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	uploadId, err := multipart.NewMultipartUpload(req.bucket, name, r.Header.Get("Content-Type"))
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	g.writeXML(w, http.StatusOK, initiateMultipartUploadResult{Bucket: req.bucket, Key: name, UploadId: uploadId})
+}
+
+func (g *Gateway) uploadPart(w http.ResponseWriter, r *http.Request, req *request) {
+	query := r.URL.Query()
+	partNumber, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+		g.writeError(w, r, errInvalidPartNumber)
+		return
+	}
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		g.writeError(w, r, errNotImplemented)
+		return
+	}
+	body, size, err := payload(r, req.sig, req.secret)
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	if size < 0 {
+		g.writeError(w, r, errMissingContentLength)
+		return
+	}
+	multipart, err := g.multiparter()
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	etag, err := multipart.PutObjectPart(req.bucket, req.key, query.Get("uploadId"), partNumber, body, size)
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", quoteETag(etag))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (g *Gateway) completeMultipartUpload(w http.ResponseWriter, r *http.Request, req *request) {
+	body, _, err := payload(r, req.sig, req.secret)
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	// тело читается целиком, чтобы хэш из подписи был сверен до разбора
+	data, err := io.ReadAll(io.LimitReader(body, maxCompleteBody+1))
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	if len(data) > maxCompleteBody {
+		g.writeError(w, r, errEntityTooLarge)
+		return
+	}
+	complete := completeMultipartUpload{}
+	if err = xml.Unmarshal(data, &complete); err != nil || len(complete.Parts) == 0 {
+		g.writeError(w, r, errMalformedXML)
+		return
+	}
+	parts := make([]models.CompletedPart, 0, len(complete.Parts))
+	for _, part := range complete.Parts {
+		parts = append(parts, models.CompletedPart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	multipart, err := g.multiparter()
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	etag, err := multipart.CompleteMultipartUpload(req.bucket, req.key, r.URL.Query().Get("uploadId"), parts)
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	g.writeXML(w, http.StatusOK, completeMultipartUploadResult{
+		Location: scheme + "://" + r.Host + "/" + req.bucket + "/" + encode(req.key, false),
+		Bucket:   req.bucket,
+		Key:      req.key,
+		ETag:     quoteETag(etag),
+	})
+}
+
+func (g *Gateway) abortMultipartUpload(w http.ResponseWriter, r *http.Request, req *request) {
+	multipart, err := g.multiparter()
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	if err = multipart.AbortMultipartUpload(req.bucket, req.key, r.URL.Query().Get("uploadId")); err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,284 @@
+package gateway
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxListKeys - больше 1000 ключей за страницу S3 не отдает
+	maxListKeys = 1000
+	// timeFormat - формат дат в XML-ответах S3
+	timeFormat = "2006-01-02T15:04:05.000Z"
+)
+
+type owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+type bucketEntry struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+type listAllMyBucketsResult struct {
+	XMLName xml.Name      `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult"`
+	Owner   owner         `xml:"Owner"`
+	Buckets []bucketEntry `xml:"Buckets>Bucket"`
+}
+
+type locationConstraint struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LocationConstraint"`
+	Region  string   `xml:",chardata"`
+}
+
+type objectEntry struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int            `xml:"KeyCount"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	EncodingType          string         `xml:"EncodingType,omitempty"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Contents              []objectEntry  `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+// listBuckets - у аккаунта ровно один бакет - его хранилище
+func (g *Gateway) listBuckets(w http.ResponseWriter, req *request) {
+	g.writeXML(w, http.StatusOK, listAllMyBucketsResult{
+		Owner:   owner{ID: req.account.StorageId, DisplayName: req.account.Email},
+		Buckets: []bucketEntry{{Name: req.account.StorageId, CreationDate: req.account.CreatedAt.UTC().Format(timeFormat)}},
+	})
+}
+
+// listObjectsV2 - список ключей по страницам. Из разделителей поддерживается только "/" - так хранилище
+// и сворачивает ключи в папки. Токен продолжения - последний отданный ключ
+func (g *Gateway) listObjectsV2(w http.ResponseWriter, r *http.Request, req *request) {
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	if delimiter != "" && delimiter != "/" {
+		g.writeError(w, r, errUnsupportedDelimiter)
+		return
+	}
+	if err := storage.ValidatePrefix(prefix); err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	maxKeys := maxListKeys
+	if value := query.Get("max-keys"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			g.writeError(w, r, errInvalidMaxKeys)
+			return
+		}
+		maxKeys = min(n, maxListKeys)
+	}
+	after := query.Get("start-after")
+	token := query.Get("continuation-token")
+	if token != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			g.writeError(w, r, errInvalidToken)
+			return
+		}
+		after = max(after, string(decoded))
+	}
+
+	objects, err := g.storage.List(req.bucket, prefix, delimiter == "")
+	if errors.Is(err, storage.ErrNotFound) {
+		err = errNoSuchBucket
+	}
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	// encoding-type=url просит aws cli: так в XML проходят ключи с управляющими символами
+	escape := func(s string) string { return s }
+	if query.Get("encoding-type") == "url" {
+		escape = func(s string) string { return encode(s, false) }
+	}
+	result := listBucketResult{
+		Name:              req.bucket,
+		Prefix:            escape(prefix),
+		Delimiter:         escape(delimiter),
+		StartAfter:        escape(query.Get("start-after")),
+		ContinuationToken: token,
+		MaxKeys:           maxKeys,
+		EncodingType:      query.Get("encoding-type"),
+	}
+	last := ""
+	for _, obj := range objects {
+		if obj.Key <= after {
+			continue
+		}
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = maxKeys > 0
+			if last != "" {
+				result.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+			}
+			break
+		}
+		// маркер самой запрошенной папки - обычный объект, остальные папки - общие префиксы
+		if delimiter != "" && obj.IsDir && obj.Key != prefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: escape(obj.Key)})
+		} else {
+			result.Contents = append(result.Contents, objectEntry{
+				Key:          escape(obj.Key),
+				LastModified: obj.LastModified.UTC().Format(timeFormat),
+				ETag:         quoteETag(obj.ETag),
+				Size:         obj.Size,
+				StorageClass: "STANDARD",
+			})
+		}
+		last = obj.Key
+		result.KeyCount++
+	}
+	g.writeXML(w, http.StatusOK, result)
+}
+
+// quoteETag - ETag в кавычках, как его отдает S3, какой бы вид ни вернул драйвер
+func quoteETag(etag string) string {
+	return `"` + strings.Trim(etag, `"`) + `"`
+}
+
+func setObjectHeaders(w http.ResponseWriter, info *models.ObjectInfo) {
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", quoteETag(info.ETag))
+	w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
+	if sum, err := hex.DecodeString(info.Checksum); err == nil && len(sum) > 0 {
+		w.Header().Set("X-Amz-Checksum-Sha256", base64.StdEncoding.EncodeToString(sum))
+	}
+}
+
+// getObject - GET и HEAD объекта. Range и условные заголовки обрабатывает http.ServeContent,
+// если драйвер отдает файл с произвольным доступом (MinIO и локальный диск)
+func (g *Gateway) getObject(w http.ResponseWriter, r *http.Request, req *request) {
+	if r.Method == http.MethodHead {
+		info, err := g.storage.Stat(req.bucket, req.key)
+		if err != nil {
+			g.writeError(w, r, readError(err))
+			return
+		}
+		setObjectHeaders(w, info)
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	reader, info, err := g.storage.GetOne(req.bucket, req.key)
+	if err != nil {
+		g.writeError(w, r, readError(err))
+		return
+	}
+	defer func() { _ = reader.Close() }()
+	setObjectHeaders(w, info)
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", info.LastModified, seeker)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	_, _ = io.Copy(w, reader)
+}
+
+// readError - ключ, который нельзя сохранить, не может и существовать
+func readError(err error) error {
+	if errors.Is(err, storage.ErrInvalidObjectName) {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
+// objectName - ключ для записи. Ключ проверяется так же, как имя файла в v1, но не переименовывается:
+// клиент S3 должен найти объект под тем ключом, под которым его записал
+func objectName(key string) (string, error) {
+	if len(key) > storage.MaxObjectNameLength {
+		return "", errKeyTooLong
+	}
+	marker := strings.HasSuffix(key, "/")
+	name, err := storage.SanitizeObjectName(strings.TrimSuffix(key, "/"))
+	if err != nil {
+		return "", err
+	}
+	if marker {
+		name += "/"
+	}
+	if name != key {
+		return "", &s3Error{http.StatusBadRequest, "InvalidArgument",
+			"Object key must be in Unicode NFC form and must not contain backslashes or a leading slash."}
+	}
+	return name, nil
+}
+
+func (g *Gateway) putObject(w http.ResponseWriter, r *http.Request, req *request) {
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		g.writeError(w, r, errNotImplemented)
+		return
+	}
+	name, err := objectName(req.key)
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	body, size, err := payload(r, req.sig, req.secret)
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	file := models.FileMinio{FileName: name, Reader: body, Size: size, ContentType: r.Header.Get("Content-Type")}
+	// подписанный хэш тела - тот же sha256, что v1 сохраняет для проверки целостности
+	file.Checksum = req.sig.contentHash()
+	if err = g.storage.CreateOne(req.bucket, file); err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	info, err := g.storage.Stat(req.bucket, name)
+	if err != nil {
+		g.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", quoteETag(info.ETag))
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteObject - как в S3, удаление отсутствующего ключа - не ошибка
+func (g *Gateway) deleteObject(w http.ResponseWriter, r *http.Request, req *request) {
+	err := readError(g.storage.Delete(req.bucket, req.key))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		g.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,257 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxChunkSize - предел одного фрагмента aws-chunked; клиенты шлют фрагменты по 64 КБ - 8 МБ
+const maxChunkSize = 16 << 20
+
+// payload - тело запроса, проверенное по x-amz-content-sha256, и его размер (-1 - неизвестен).
+// Хэш сверяется на чтении, которое дошло до размера или до конца тела: ошибка чтения прерывает запись в хранилище
+func payload(r *http.Request, sig *signature, secret string) (io.Reader, int64, error) {
+	var body io.Reader = r.Body
+	size := r.ContentLength
+	switch sig.payloadHash {
+	case unsignedPayload:
+	case streamingPayload, streamingPayloadTrail, streamingUnsignedTrail:
+		decoded, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil || decoded < 0 {
+			return nil, 0, errMissingContentLength
+		}
+		chunked := &chunkedReader{src: bufio.NewReader(r.Body), declared: decoded}
+		if sig.payloadHash != streamingUnsignedTrail {
+			chunked.sig, chunked.key, chunked.prevSig = sig, sig.signingKey(secret), sig.signature
+		}
+		if sig.payloadHash != streamingPayload {
+			chunked.trailer = strings.ToLower(r.Header.Get("X-Amz-Trailer"))
+			chunked.hash = checksumHash(chunked.trailer)
+		}
+		body, size = chunked, decoded
+	default:
+		expected, _ := hex.DecodeString(sig.payloadHash)
+		body = &verifyingReader{src: body, hash: sha256.New(), expected: expected, mismatch: errContentSHA256Mismatch,
+			remaining: size}
+	}
+	// контрольная сумма тела заголовком x-amz-checksum-*, ее шлют новые версии SDK
+	for name := range r.Header {
+		lower := strings.ToLower(name)
+		if !strings.HasPrefix(lower, "x-amz-checksum-") || lower == "x-amz-checksum-type" {
+			continue
+		}
+		h := checksumHash(lower)
+		expected, err := base64.StdEncoding.DecodeString(r.Header.Get(name))
+		if h == nil || err != nil {
+			continue
+		}
+		body = &verifyingReader{src: body, hash: h, expected: expected, mismatch: errBadDigest, remaining: size}
+	}
+	return body, size, nil
+}
+
+// checksumHash - алгоритм контрольной суммы S3 по имени заголовка; nil - алгоритм не проверяем (crc64nvme)
+func checksumHash(name string) hash.Hash {
+	switch strings.TrimPrefix(name, "x-amz-checksum-") {
+	case "crc32":
+		return crc32.NewIEEE()
+	case "crc32c":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	}
+	return nil
+}
+
+// verifyingReader - сверяет хэш тела с ожидаемым, когда тело дочитано до объявленного размера или до конца.
+// Хранилище с известным размером (minio PutObject) читает ровно size байт и EOF не видит, поэтому хэш
+// проверяется на чтении, которое дошло до размера. Последние байты при несовпадении не отдаются: io.ReadFull,
+// получив весь буфер, ошибку бы отбросил
+type verifyingReader struct {
+	src      io.Reader
+	hash     hash.Hash
+	expected []byte
+	mismatch error
+	// remaining - сколько байт осталось до объявленного размера, -1 - размер неизвестен
+	remaining int64
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.src.Read(p)
+	v.hash.Write(p[:n])
+	if v.remaining > 0 {
+		v.remaining -= int64(n)
+	}
+	if (err == io.EOF || v.remaining == 0) && !bytes.Equal(v.hash.Sum(nil), v.expected) {
+		return 0, v.mismatch
+	}
+	return n, err
+}
+
+// chunkedReader - тело в кодировке aws-chunked: "размер[;chunk-signature=...]\r\nданные\r\n", в конце
+// фрагмент нулевой длины и, для *-TRAILER, заголовок с контрольной суммой (и его подпись, если тело подписано).
+// Подписанные фрагменты проверяются цепочкой подписей от подписи запроса
+type chunkedReader struct {
+	src      *bufio.Reader
+	declared int64
+	total    int64
+	chunk    []byte
+	err      error
+
+	sig     *signature
+	key     []byte
+	prevSig string
+
+	trailer string
+	hash    hash.Hash
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	for len(c.chunk) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		c.err = c.next()
+	}
+	n := copy(p, c.chunk)
+	c.chunk = c.chunk[n:]
+	return n, nil
+}
+
+// next - читает следующий фрагмент; io.EOF - тело закончилось и сошлось по размеру
+func (c *chunkedReader) next() error {
+	line, err := c.line()
+	// тело оборвалось до фрагмента нулевой длины: его подпись закрывает цепочку
+	if err == io.EOF {
+		return errIncompleteBody
+	}
+	if err != nil {
+		return err
+	}
+	sizeHex, params, _ := strings.Cut(line, ";")
+	size, err := strconv.ParseInt(sizeHex, 16, 64)
+	if err != nil || size < 0 {
+		return errIncompleteBody
+	}
+	if size > maxChunkSize || c.total+size > c.declared {
+		return errEntityTooLarge
+	}
+	data := make([]byte, size)
+	if _, err = io.ReadFull(c.src, data); err != nil {
+		return errIncompleteBody
+	}
+	if c.sig != nil {
+		if err = c.verifyChunk(strings.TrimPrefix(params, "chunk-signature="), data); err != nil {
+			return err
+		}
+	}
+	if size == 0 {
+		return c.finish()
+	}
+	if crlf, errLine := c.line(); errLine != nil || crlf != "" {
+		return errIncompleteBody
+	}
+	if c.hash != nil {
+		c.hash.Write(data)
+	}
+	c.total += size
+	// клиент с известным размером EOF не увидит: завершающий фрагмент и трейлер проверяются до того,
+	// как отдать последние данные
+	if c.total == c.declared {
+		if err = c.next(); err != io.EOF {
+			return err
+		}
+	}
+	c.chunk = data
+	return err
+}
+
+// finish - после последнего фрагмента: заголовки-трейлеры до пустой строки и сверка размера
+func (c *chunkedReader) finish() error {
+	var trailers strings.Builder
+	trailerSig := ""
+	for {
+		line, err := c.line()
+		if err != nil {
+			// часть клиентов не шлет завершающую пустую строку
+			if err == io.EOF && c.trailer == "" {
+				break
+			}
+			return errIncompleteBody
+		}
+		if line == "" {
+			// minio-go отделяет подпись трейлера от самого трейлера пустой строкой
+			if c.sig != nil && c.trailer != "" && trailerSig == "" {
+				continue
+			}
+			break
+		}
+		name, value, _ := strings.Cut(line, ":")
+		name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+		if name == "x-amz-trailer-signature" {
+			trailerSig = value
+			continue
+		}
+		trailers.WriteString(name + ":" + value + "\n")
+		if c.hash != nil && name == c.trailer {
+			expected, errDecode := base64.StdEncoding.DecodeString(value)
+			if errDecode != nil || !bytes.Equal(c.hash.Sum(nil), expected) {
+				return errBadDigest
+			}
+		}
+	}
+	if c.sig != nil && c.trailer != "" {
+		sum := sha256.Sum256([]byte(trailers.String()))
+		if !c.signed("AWS4-HMAC-SHA256-TRAILER", trailerSig, hex.EncodeToString(sum[:])) {
+			return errSignatureDoesNotMatch
+		}
+	}
+	if c.total != c.declared {
+		return errIncompleteBody
+	}
+	return io.EOF
+}
+
+// line - строка до \r\n; длинные строки отсекает размер буфера bufio
+func (c *chunkedReader) line() (string, error) {
+	line, err := c.src.ReadSlice('\n')
+	if err != nil {
+		if err == io.EOF && len(line) == 0 {
+			return "", io.EOF
+		}
+		return "", errIncompleteBody
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"), nil
+}
+
+func (c *chunkedReader) verifyChunk(chunkSig string, data []byte) error {
+	sum := sha256.Sum256(data)
+	if !c.signed("AWS4-HMAC-SHA256-PAYLOAD", chunkSig, emptySHA256+"\n"+hex.EncodeToString(sum[:])) {
+		return errSignatureDoesNotMatch
+	}
+	return nil
+}
+
+// signed - сверяет подпись фрагмента или трейлера; каждая подпись строится от предыдущей
+func (c *chunkedReader) signed(algorithm string, sig string, hashes string) bool {
+	stringToSign := algorithm + "\n" + c.sig.amzDate.Format(amzDateFormat) + "\n" + c.sig.scope() + "\n" +
+		c.prevSig + "\n" + hashes
+	if !hmac.Equal([]byte(sig), []byte(hex.EncodeToString(hmacSHA256(c.key, stringToSign)))) {
+		return false
+	}
+	c.prevSig = sig
+	return true
+}
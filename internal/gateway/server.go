@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/metrics"
+	"CloudStorageProject-FileServer/internal/middleware"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/config"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// Server - S3-шлюз на отдельном порту. Хранилище то же, что у основного сервера, поэтому запросы
+// попадают в журнал изменений и реплику, а HTTP-метрики пишутся в те же счетчики, что и у v1
+type Server struct {
+	Port        string
+	Logger      *slog.Logger
+	Router      http.Handler
+	exitChan    chan struct{}
+	connections *sync.WaitGroup
+}
+
+func NewServer(conf *config.Config, logs *slog.Logger, pgs *postgres.Postgres, st storage.Storage, keys *Keys,
+	metric *metrics.HTTPMetrics) *Server {
+	gateway := NewGateway(st, keys, pgs.AccountByStorage, logs)
+
+	exitChan := make(chan struct{})
+	conns := new(sync.WaitGroup)
+	shutDown := middleware.ShutdownMiddleware(exitChan, conns, gateway)
+	checkPanics := middleware.PanicMiddleware(shutDown, logs)
+	httpMetrics := metrics.HTTPMetricsMiddleware(checkPanics, metric)
+	logged := middleware.Logger(logs, httpMetrics)
+	return &Server{
+		Port:        conf.S3GatewayPort,
+		Logger:      logs,
+		Router:      middleware.RequestID(logged),
+		exitChan:    exitChan,
+		connections: conns,
+	}
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	close(s.exitChan)
+
+	finished := make(chan struct{})
+	go func() {
+		s.connections.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) Run() error {
+	return http.ListenAndServe(fmt.Sprintf(":%s", s.Port), s.Router)
+}
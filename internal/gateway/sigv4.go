@@ -0,0 +1,282 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat = "20060102T150405Z"
+	// maxClockSkew - расхождение часов клиента и сервера, как в AWS
+	maxClockSkew = 15 * time.Minute
+	// maxPresignExpiry - presigned-ссылка живет не дольше недели
+	maxPresignExpiry = 7 * 24 * time.Hour
+
+	// значения x-amz-content-sha256, кроме hex-хэша тела
+	unsignedPayload        = "UNSIGNED-PAYLOAD"
+	streamingPayload       = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	streamingPayloadTrail  = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	streamingUnsignedTrail = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+	emptySHA256            = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// now - часы для проверки даты подписи; тесты подставляют дату из примеров AWS
+var now = time.Now
+
+// signature - разобранная подпись SigV4 из заголовка Authorization или из параметров presigned-ссылки
+type signature struct {
+	accessKey     string
+	date          string // yyyymmdd из области подписи
+	region        string
+	signedHeaders []string
+	signature     string
+	amzDate       time.Time
+	payloadHash   string
+	presigned     bool
+}
+
+// scope - область подписи: дата/регион/s3/aws4_request
+func (s *signature) scope() string {
+	return s.date + "/" + s.region + "/s3/aws4_request"
+}
+
+// parseSignature - подпись запроса; nil без ошибки - анонимный запрос
+func parseSignature(r *http.Request) (*signature, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		return parseAuthorizationHeader(r, header)
+	}
+	if r.URL.Query().Has("X-Amz-Signature") {
+		return parsePresigned(r)
+	}
+	return nil, nil
+}
+
+func parseAuthorizationHeader(r *http.Request, header string) (*signature, error) {
+	algorithm, fields, ok := strings.Cut(header, " ")
+	if !ok {
+		return nil, errAuthorizationHeader
+	}
+	if algorithm != signAlgorithm {
+		return nil, errUnsupportedAlgorithm
+	}
+	sig := &signature{}
+	var credential, signedHeaders string
+	for _, field := range strings.Split(fields, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch key {
+		case "Credential":
+			credential = value
+		case "SignedHeaders":
+			signedHeaders = value
+		case "Signature":
+			sig.signature = value
+		}
+	}
+	if !sig.parseCredential(credential) || signedHeaders == "" || sig.signature == "" {
+		return nil, errAuthorizationHeader
+	}
+	sig.signedHeaders = strings.Split(signedHeaders, ";")
+
+	amzDate, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, errMissingDate
+	}
+	sig.amzDate = amzDate
+	sig.payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+	if !validPayloadHash(sig.payloadHash) {
+		return nil, errInvalidContentSHA256
+	}
+	return sig, nil
+}
+
+func parsePresigned(r *http.Request) (*signature, error) {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != signAlgorithm {
+		return nil, errUnsupportedAlgorithm
+	}
+	sig := &signature{signature: query.Get("X-Amz-Signature"), presigned: true, payloadHash: unsignedPayload}
+	if !sig.parseCredential(query.Get("X-Amz-Credential")) || query.Get("X-Amz-SignedHeaders") == "" {
+		return nil, errAuthorizationQuery
+	}
+	sig.signedHeaders = strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	amzDate, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, errAuthorizationQuery
+	}
+	sig.amzDate = amzDate
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires <= 0 || time.Duration(expires)*time.Second > maxPresignExpiry {
+		return nil, errAuthorizationQuery
+	}
+	current := now()
+	if current.Before(amzDate.Add(-maxClockSkew)) || current.After(amzDate.Add(time.Duration(expires)*time.Second)) {
+		return nil, errExpiredPresign
+	}
+	return sig, nil
+}
+
+// parseCredential - AKID/yyyymmdd/region/s3/aws4_request
+func (s *signature) parseCredential(credential string) bool {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[0] == "" || len(parts[1]) != 8 || parts[3] != "s3" || parts[4] != "aws4_request" {
+		return false
+	}
+	s.accessKey, s.date, s.region = parts[0], parts[1], parts[2]
+	return true
+}
+
+func validPayloadHash(hash string) bool {
+	switch hash {
+	case unsignedPayload, streamingPayload, streamingPayloadTrail, streamingUnsignedTrail:
+		return true
+	}
+	decoded, err := hex.DecodeString(hash)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// contentHash - sha256 тела в hex, если клиент подписал тело целиком, иначе пусто
+func (s *signature) contentHash() string {
+	switch s.payloadHash {
+	case unsignedPayload, streamingPayload, streamingPayloadTrail, streamingUnsignedTrail:
+		return ""
+	}
+	return s.payloadHash
+}
+
+// verify - проверяет подпись запроса секретом аккаунта
+func (s *signature) verify(r *http.Request, secret string, region string) error {
+	if s.region != region {
+		return errWrongRegion
+	}
+	if s.amzDate.Format("20060102") != s.date {
+		return errSignatureDoesNotMatch
+	}
+	if !s.presigned {
+		if skew := now().Sub(s.amzDate); skew > maxClockSkew || skew < -maxClockSkew {
+			return errRequestTimeTooSkewed
+		}
+	}
+	if !hmac.Equal([]byte(s.signature), []byte(s.expected(r, secret))) {
+		return errSignatureDoesNotMatch
+	}
+	return nil
+}
+
+// expected - подпись, которую должен был посчитать клиент
+func (s *signature) expected(r *http.Request, secret string) string {
+	canonical := strings.Join([]string{
+		r.Method,
+		canonicalURI(r.URL.Path),
+		canonicalQuery(r.URL.Query()),
+		canonicalHeaders(r, s.signedHeaders),
+		strings.Join(s.signedHeaders, ";"),
+		s.payloadHash,
+	}, "\n")
+	return hex.EncodeToString(hmacSHA256(s.signingKey(secret), s.stringToSign(canonical)))
+}
+
+func (s *signature) stringToSign(canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	return signAlgorithm + "\n" + s.amzDate.Format(amzDateFormat) + "\n" + s.scope() + "\n" + hex.EncodeToString(sum[:])
+}
+
+func (s *signature) signingKey(secret string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), s.date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalURI - путь как его кодирует клиент S3: каждый сегмент по RFC 3986, "/" не кодируется
+func canonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return encode(path, false)
+}
+
+// canonicalQuery - параметры, отсортированные по имени, затем по значению; подпись presigned-ссылки в нее не входит
+func canonicalQuery(query url.Values) string {
+	pairs := make([][2]string, 0, len(query))
+	for key, values := range query {
+		if key == "X-Amz-Signature" {
+			continue
+		}
+		for _, value := range values {
+			pairs = append(pairs, [2]string{encode(key, true), encode(value, true)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	encoded := make([]string, len(pairs))
+	for i, pair := range pairs {
+		encoded[i] = pair[0] + "=" + pair[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// canonicalHeaders - подписанные заголовки. Host и Content-Length net/http убирает из Header в поля запроса
+func canonicalHeaders(r *http.Request, names []string) string {
+	var b strings.Builder
+	for _, name := range names {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = r.Header.Get("Content-Length")
+			if value == "" && r.ContentLength >= 0 {
+				value = strconv.FormatInt(r.ContentLength, 10)
+			}
+		case "transfer-encoding":
+			value = strings.Join(r.TransferEncoding, ",")
+		default:
+			values := slices.Clone(r.Header.Values(name))
+			for i := range values {
+				values[i] = strings.Join(strings.Fields(values[i]), " ")
+			}
+			value = strings.Join(values, ",")
+		}
+		b.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	return b.String()
+}
+
+// encode - URI-кодирование AWS: не кодируются только A-Z a-z 0-9 - _ . ~ (и "/" в пути)
+func encode(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0f])
+		}
+	}
+	return b.String()
+}
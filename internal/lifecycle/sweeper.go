@@ -0,0 +1,166 @@
+package lifecycle
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/config"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Sweeper - фоновый обходчик, который применяет правила жизненного цикла к файлам пользователей
+type Sweeper struct {
+	postgres *postgres.Postgres
+	redis    *redis.Redis
+	storage  storage.Storage
+	logger   *slog.Logger
+	interval time.Duration
+	exitChan chan struct{}
+	done     chan struct{}
+	// mu защищает running и closed: Close ждет done, только если Run успел запуститься
+	mu      sync.Mutex
+	running bool
+	closed  bool
+}
+
+func NewSweeper(ctx context.Context, pgs *postgres.Postgres, rds *redis.Redis, st storage.Storage) *Sweeper {
+	conf := ctx.Value("config").(*config.Config)
+	logger := ctx.Value("logger").(*slog.Logger)
+	return &Sweeper{
+		postgres: pgs,
+		redis:    rds,
+		storage:  st,
+		logger:   logger,
+		interval: time.Duration(conf.LifecycleSweepInterval) * time.Minute,
+		exitChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run - запускает обход сразу и дальше по таймеру, пока не вызван Close
+func (s *Sweeper) Run() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.Sweep()
+		select {
+		case <-s.exitChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close - останавливает обходчик и дожидается окончания текущего прохода
+func (s *Sweeper) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	running := s.running
+	s.mu.Unlock()
+	close(s.exitChan)
+	// Run не запускался - ждать нечего
+	if !running {
+		return nil
+	}
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sweepLock - блокировка в Redis: обходчик запущен на каждой реплике, а проход выполняет одна из них
+const sweepLock = "lifecycle-sweep"
+
+// Sweep - один проход по всем правилам. Если проход уже идет на другой реплике, пропускается
+func (s *Sweeper) Sweep() {
+	// блокировка истекает через интервал, даже если реплика упала посреди прохода
+	token, err := s.redis.Lock(sweepLock, s.interval)
+	if err != nil {
+		s.logger.Error("lifecycle lock error", "error", err.Error(), "place", tools.GetPlace())
+		return
+	}
+	if token == "" {
+		s.logger.Debug("lifecycle sweep is running on another replica")
+		return
+	}
+	defer func() {
+		if errUnlock := s.redis.Unlock(sweepLock, token); errUnlock != nil {
+			s.logger.Error("lifecycle unlock error", "error", errUnlock.Error(), "place", tools.GetPlace())
+		}
+	}()
+	rules, err := s.postgres.AllLifecycleRules()
+	if err != nil {
+		s.logger.Error("lifecycle rules loading error", "error", err.Error(), "place", tools.GetPlace())
+		return
+	}
+	now := time.Now()
+	for i := range rules {
+		select {
+		case <-s.exitChan:
+			return
+		default:
+		}
+		s.apply(&rules[i], now)
+	}
+}
+
+func (s *Sweeper) apply(rule *models.LifecycleRule, now time.Time) {
+	objects, err := s.storage.List(rule.Bucket, rule.Prefix, true)
+	if err != nil {
+		s.logger.Error("lifecycle list objects error", "error", err.Error(), "bucket", rule.Bucket,
+			"rule", rule.Id, "place", tools.GetPlace())
+		return
+	}
+	for _, obj := range objects {
+		if rule.ExpiresAt(obj.LastModified).After(now) {
+			continue
+		}
+		var tags map[string]string
+		if rule.HasTag() {
+			if tags, err = s.storage.ObjectTags(rule.Bucket, obj.Key); err != nil {
+				continue
+			}
+		}
+		if !rule.Matches(obj.Key, tags) {
+			continue
+		}
+
+		event := models.StorageEvent{Type: models.EventDelete, FileName: obj.Key, Time: time.Now()}
+		if rule.Action == models.LifecycleActionTrash {
+			err = storage.MoveToTrash(s.storage, rule.Bucket, obj.Key)
+			event.Type = models.EventRename
+			event.NewName = models.TrashPrefix + obj.Key
+		} else {
+			err = s.storage.Delete(rule.Bucket, obj.Key)
+		}
+		if err != nil {
+			s.logger.Error("lifecycle apply error", "error", err.Error(), "bucket", rule.Bucket,
+				"object", obj.Key, "rule", rule.Id, "place", tools.GetPlace())
+			continue
+		}
+		s.logger.Info("lifecycle rule applied", "bucket", rule.Bucket, "object", obj.Key,
+			"rule", rule.Id, "action", rule.Action)
+		if errPublish := s.redis.PublishEvent(rule.Bucket, event); errPublish != nil {
+			s.logger.Error("publish storage event error", "error", errPublish.Error(), "place", tools.GetPlace())
+		}
+	}
+}
@@ -7,11 +7,13 @@ import (
 
 // Collector централизованный сборщик всех метрик
 type Collector struct {
-	HTTP     *HTTPMetrics
-	Postgres *PostgresMetrics
-	Minio    *MinIOMetrics
-	Redis    *RedisMetrics
-	Custom   *CustomMetrics
+	HTTP        *HTTPMetrics
+	Postgres    *PostgresMetrics
+	Minio       *MinIOMetrics
+	Redis       *RedisMetrics
+	Replication *ReplicationMetrics
+	Upload      *UploadMetrics
+	Custom      *CustomMetrics
 }
 
 // HTTPMetrics метрики HTTP запросов
@@ -38,10 +40,12 @@ type CustomMetrics struct {
 // NewCollector создает и регистрирует все метрики
 func NewCollector(appName string) *Collector {
 	return &Collector{
-		HTTP:     newHTTPMetrics(appName),
-		Postgres: newPostgresMetrics(appName),
-		Minio:    NewMinIOMetrics(appName),
-		Redis:    newRedisMetrics(appName),
+		HTTP:        newHTTPMetrics(appName),
+		Postgres:    newPostgresMetrics(appName),
+		Minio:       NewMinIOMetrics(appName),
+		Redis:       newRedisMetrics(appName),
+		Replication: newReplicationMetrics(appName),
+		Upload:      newUploadMetrics(appName),
 	}
 }
 
@@ -15,6 +15,11 @@ type responseWriter struct {
 	written    int64
 }
 
+// Unwrap - нужен http.ResponseController, чтобы добраться до Flush исходного writer (SSE)
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // HTTPMetricsMiddleware middleware для сбора HTTP метрик
 func HTTPMetricsMiddleware(next http.Handler, c *HTTPMetrics) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
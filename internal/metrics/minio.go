@@ -17,6 +17,8 @@ type MinIOMetrics struct {
 	DownloadErrors  *prometheus.CounterVec   // ОШИБКИ ПРИ СКАЧИВАНИИ
 	FilesListErrors *prometheus.CounterVec   // ОШИБКИ ПРИ ПОЛУЧЕНИИ СПИСКА ФАЙЛОВ
 	DeleteErrors    *prometheus.CounterVec   // Ошибки при удалении файлов
+	RenamesTotal    *prometheus.CounterVec   // Количество переименованных файлов
+	RenameErrors    *prometheus.CounterVec   // Ошибки при переименовании файлов
 }
 
 // NewMinIOMetrics - создает метрики
@@ -58,6 +60,15 @@ func NewMinIOMetrics(appName string) *MinIOMetrics {
 			[]string{"bucket"},
 		),
 
+		RenamesTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: appName,
+				Name:      "minio_renames_total",
+				Help:      "Всего переименовано файлов",
+			},
+			[]string{"bucket"},
+		),
+
 		UploadTime: promauto.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Namespace: appName,
@@ -103,6 +114,15 @@ func NewMinIOMetrics(appName string) *MinIOMetrics {
 			[]string{"bucket", "error"},
 		),
 
+		RenameErrors: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: appName,
+				Name:      "minio_rename_errors_total",
+				Help:      "Ошибки при переименовании файлов",
+			},
+			[]string{"bucket", "error"},
+		),
+
 		FilesListErrors: promauto.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: appName,
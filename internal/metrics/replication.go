@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ReplicationMetrics - метрики репликации во вторичное S3-хранилище
+type ReplicationMetrics struct {
+	Lag        *prometheus.HistogramVec // Сколько прошло от изменения до его появления на реплике
+	QueueDepth prometheus.Gauge         // Сколько заданий ждут в очереди
+	JobsTotal  *prometheus.CounterVec   // Задания по операциям и статусам
+	Failovers  *prometheus.CounterVec   // Чтения, ушедшие на реплику из-за недоступности основного хранилища
+}
+
+func newReplicationMetrics(appName string) *ReplicationMetrics {
+	namespace := appName
+
+	return &ReplicationMetrics{
+		Lag: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "replication_lag_seconds",
+				Help:      "Time between a change on the primary storage and its replication",
+				Buckets:   []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900},
+			},
+			[]string{"operation"},
+		),
+		QueueDepth: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "replication_queue_depth",
+				Help:      "Number of replication jobs waiting in the queue",
+			},
+		),
+		JobsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "replication_jobs_total",
+				Help:      "Total number of replication jobs",
+			},
+			[]string{"operation", "status"},
+		),
+		Failovers: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "replication_read_failovers_total",
+				Help:      "Reads served by the replica because the primary storage failed",
+			},
+			[]string{"operation"},
+		),
+	}
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// UploadMetrics - метрики приема загружаемых файлов
+type UploadMetrics struct {
+	TempDiskBytes prometheus.Gauge       // Сколько байт сейчас занимают временные файлы загрузок
+	PartsTotal    *prometheus.CounterVec // Файлы по способу передачи в хранилище: memory, stream, spill
+}
+
+func newUploadMetrics(appName string) *UploadMetrics {
+	namespace := appName
+
+	return &UploadMetrics{
+		TempDiskBytes: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "upload",
+				Name:      "temp_disk_bytes",
+				Help:      "Bytes currently held in upload temp files",
+			},
+		),
+		PartsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "upload",
+				Name:      "parts_total",
+				Help:      "Total number of uploaded files by transfer mode",
+			},
+			[]string{"mode"},
+		),
+	}
+}
@@ -1,14 +1,23 @@
 package middleware
 
 import (
+	"CloudStorageProject-FileServer/internal/apierror"
 	"CloudStorageProject-FileServer/internal/database/postgres"
 	"CloudStorageProject-FileServer/internal/database/redis"
-	minioClient "CloudStorageProject-FileServer/internal/minio"
+	"CloudStorageProject-FileServer/internal/dav"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
 	"CloudStorageProject-FileServer/pkg/tools"
 	"context"
-	"encoding/json"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -18,80 +27,266 @@ import (
 func Logger(logs *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !strings.Contains(r.URL.String(), "static") && !strings.Contains(r.URL.String(), "swagger") {
-			logs.Info("request url: "+r.URL.String(), "client", r.RemoteAddr, "method", r.Method,
-				"time", time.Now().String(), "place", tools.GetPlace())
+			logs.Info("request url: "+withoutAPIKey(r.URL).String(), "client", r.RemoteAddr, "method", r.Method,
+				"request_id", apierror.RequestID(r), "time", time.Now().String(), "place", tools.GetPlace())
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-// ValidateAPI - middleware в котором валидируется api
-func ValidateAPI(next http.Handler, pgs *postgres.Postgres, rds *redis.Redis,
-	minio *minioClient.MinioClient, TmplPath string, logger *slog.Logger) http.Handler {
+// Accounts - аккаунты для проверки ключа, в работе это *postgres.Postgres
+type Accounts interface {
+	CheckApiExists(api string) *models.APIPGS
+	UpdateLastLogin(accountId int) error
+}
+
+var _ Accounts = (*postgres.Postgres)(nil)
+
+// Authenticate - аккаунт по API-ключу: сначала кэш Redis, потом Postgres. Найденный в базе ключ кэшируется
+// до ответа, время последнего входа обновляется в фоне. nil - ключа нет, он отозван или приостановлен.
+// Так ключ проверяют все входы: HTTP, SFTP, gRPC и S3-шлюз
+func Authenticate(api string, pgs Accounts, rds *redis.Redis, logger *slog.Logger) *models.APIPGS {
+	// кэш и база знают только хэш ключа
+	keyHash := models.HashAPIKey(api)
+	account, errRedis := rds.GetAPIField(keyHash)
+	if errRedis != nil || account == nil {
+		account = loadAccount(api, keyHash, pgs, rds, logger)
+	}
+	// в кэш попадают только действующие ключи, но статус проверяется и у записи из кэша
+	if account == nil || !account.Active() {
+		return nil
+	}
+	go func() {
+		if err := pgs.UpdateLastLogin(account.Id); err != nil {
+			logger.Error("update last login postgres error", "error", err.Error(),
+				"place", tools.GetPlace())
+		}
+		if err := rds.UpdateLastLogin(keyHash); err != nil {
+			logger.Error("update last login redis error", "error", err.Error(),
+				"place", tools.GetPlace())
+		}
+	}()
+	return account
+}
+
+// loadAccount - аккаунт из базы с записью в кэш. Версия ключа берется до чтения базы: если ключ изменили,
+// пока шло чтение, запись в кэш отменяется, а аккаунт перечитывается
+func loadAccount(api string, keyHash string, pgs Accounts, rds *redis.Redis,
+	logger *slog.Logger) *models.APIPGS {
+	version, errVersion := rds.APIFieldVersion(keyHash)
+	account := pgs.CheckApiExists(api)
+	if account == nil || !account.Active() || errVersion != nil {
+		return account
+	}
+	err := rds.SetAPIField(account, version)
+	if errors.Is(err, redis.ErrAPIFieldChanged) {
+		return pgs.CheckApiExists(api)
+	}
+	if err != nil {
+		logger.Error("error to write api to redis", "error", err.Error(), "place", tools.GetPlace())
+	}
+	return account
+}
+
+// davChallenge - запрос пароля для WebDAV-клиентов
+const davChallenge = `Basic realm="CloudStorage", charset="UTF-8"`
+
+const (
+	// APIKeyHeader - заголовок с ключом для клиентов, которым неудобен Authorization
+	APIKeyHeader = "X-API-Key"
+	// apiKeyCookie - cookie веб-интерфейса с ключом
+	apiKeyCookie = "apikey"
+	// apiKeyParam - ключ в адресе, устаревший способ; выключается API_KEY_IN_QUERY=false
+	apiKeyParam = "api"
+)
+
+// requestAPIKey - ключ из запроса: Authorization: Bearer, X-API-Key, cookie веб-интерфейса и, если разрешено,
+// параметр ?api=. Cookie принимается только в GET и HEAD - так браузер открывает страницу хранилища, скачивает
+// файл и слушает события, а изменить что-то чужой страницей через cookie нельзя
+func requestAPIKey(r *http.Request, queryKeys bool) string {
+	if scheme, value, _ := strings.Cut(r.Header.Get("Authorization"), " "); strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(value)
+	}
+	if api := r.Header.Get(APIKeyHeader); api != "" {
+		return api
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		if cookie, err := r.Cookie(apiKeyCookie); err == nil && cookie.Value != "" {
+			return cookie.Value
+		}
+	}
+	if queryKeys {
+		return r.URL.Query().Get(apiKeyParam)
+	}
+	return ""
+}
+
+// withoutAPIKey - адрес без параметра ?api=, чтобы ключ не попал в логи и обработчики
+func withoutAPIKey(u *url.URL) *url.URL {
+	query := u.Query()
+	if !query.Has(apiKeyParam) {
+		return u
+	}
+	query.Del(apiKeyParam)
+	clean := *u
+	clean.RawQuery = query.Encode()
+	return &clean
+}
+
+// ValidateAPI - middleware в котором валидируется api. queryKeys - принимать ли ключ из ?api=.
+// pgs попадает в контекст под "postgres", обработчики ждут там *postgres.Postgres
+func ValidateAPI(next http.Handler, pgs Accounts, rds *redis.Redis,
+	st storage.Storage, TmplPath string, queryKeys bool, logger *slog.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		//Валидация api
 		////////////////////////////////////////////////////////////////////////////////////////////////////////////////
-		api := r.URL.Query().Get("api")
-		if strings.Contains(r.URL.String(), "client") {
+		api := requestAPIKey(r, queryKeys)
+		// ?api= дальше не нужен: в логах обработчиков и в ссылках ключа быть не должно
+		keyInQuery := r.URL.Query().Has(apiKeyParam)
+		r.URL = withoutAPIKey(r.URL)
+		// WebDAV-клиенты не умеют передавать ключ в адресе, для них ключ - пароль Basic-авторизации
+		isDAV := r.URL.Path == dav.Prefix || strings.HasPrefix(r.URL.Path, dav.Prefix+"/")
+		if _, password, ok := r.BasicAuth(); isDAV && ok {
+			api = password
+		}
+		// bucket - хранилище аккаунта, в нем работают все обработчики /client; account - аккаунт ключа,
+		// вне /client и /api nil
+		bucket := ""
+		var account *models.APIPGS
+		// у API администратора своя проверка токена, ключ аккаунта там не нужен
+		isAdmin := strings.HasPrefix(r.URL.Path, "/admin/")
+		if !isAdmin && (strings.Contains(r.URL.String(), "client") || strings.HasPrefix(r.URL.Path, "/api/") || isDAV) {
 			//ключ проверяется тут
 			if api == "" {
+				if isDAV {
+					// без этого заголовка проводник и файловые менеджеры не спросят пароль
+					w.Header().Set("WWW-Authenticate", davChallenge)
+				}
 				logger.Warn("bad url api parameter", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
 					"time", time.Now().String(), "place", tools.GetPlace())
-				http.Error(w, "api is required", http.StatusBadRequest)
+				message := "api key is required"
+				if keyInQuery {
+					message = "api key in query string is disabled, use the Authorization header"
+				}
+				apierror.Write(w, r, apierror.New(http.StatusUnauthorized, models.ErrCodeAPIKeyRequired, message))
 				return
 			}
 
-			if redisExists := rds.ExistsAPIField(api); !redisExists {
-				existsPGS := pgs.CheckApiExists(api)
-				if existsPGS == nil {
-					logger.Warn("bad api", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
-						"time", time.Now().String(), "place", tools.GetPlace())
-					http.SetCookie(w, &http.Cookie{
-						Name:    "apikey",
-						Value:   "",
-						Path:    "/",
-						MaxAge:  -1,
-						Expires: time.Unix(0, 0),
-					})
+			account = Authenticate(api, pgs, rds, logger)
+			if account == nil {
+				logger.Warn("bad api", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
+					"time", time.Now().String(), "place", tools.GetPlace())
+				http.SetCookie(w, &http.Cookie{
+					Name:    apiKeyCookie,
+					Value:   "",
+					Path:    "/",
+					MaxAge:  -1,
+					Expires: time.Unix(0, 0),
+				})
+				if isDAV {
+					w.Header().Set("WWW-Authenticate", davChallenge)
+				}
+				// браузер отправляем на страницу входа, API-клиентам отвечаем ошибкой
+				if !isDAV && strings.Contains(r.Header.Get("Accept"), "text/html") {
 					http.Redirect(w, r, "/", http.StatusFound)
 					return
 				}
-				go func() {
-					if err := rds.SetAPIField(existsPGS); err != nil {
-						logger.Error("error to write api to redis", "error", err.Error(),
-							"place", tools.GetPlace())
-						return
-					}
-				}()
+				apierror.Write(w, r, apierror.New(http.StatusUnauthorized, models.ErrCodeAPIKeyInvalid, "api key is invalid"))
+				return
 			}
-			go func() {
-				if err := pgs.UpdateLastLogin(api); err != nil {
-					logger.Error("update last login postgres error", "error", err.Error(),
-						"place", tools.GetPlace())
-				}
-				if err := rds.UpdateLastLogin(api); err != nil {
-					logger.Error("update last login redis error", "error", err.Error(),
-						"place", tools.GetPlace())
-				}
-			}()
+			if !account.CanWrite() && !readOnlyMethod(r.Method) {
+				logger.Warn("write with read-only api", "client", r.RemoteAddr, "url", r.URL, "method", r.Method,
+					"time", time.Now().String(), "place", tools.GetPlace())
+				apierror.Write(w, r, apierror.New(http.StatusForbidden, models.ErrCodeForbidden,
+					"api key has no write permission"))
+				return
+			}
+			bucket = account.StorageId
 		}
 		////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 		r = r.WithContext(context.WithValue(r.Context(), "api", api))
+		r = r.WithContext(context.WithValue(r.Context(), "bucket", bucket))
+		r = r.WithContext(context.WithValue(r.Context(), "account", account))
 		r = r.WithContext(context.WithValue(r.Context(), "postgres", pgs))
 		r = r.WithContext(context.WithValue(r.Context(), "redis", rds))
-		r = r.WithContext(context.WithValue(r.Context(), "minio", minio))
+		r = r.WithContext(context.WithValue(r.Context(), "storage", st))
 		r = r.WithContext(context.WithValue(r.Context(), "tmplPath", TmplPath))
 		r = r.WithContext(context.WithValue(r.Context(), "logger", logger))
 		next.ServeHTTP(w, r)
 	})
 }
 
+// ValidateAdmin - middleware API администратора: токен ADMIN_TOKEN в заголовке Authorization: Bearer
+func ValidateAdmin(next http.Handler, token string, logger *slog.Logger) http.Handler {
+	// сравниваются хэши, чтобы время сравнения не зависело и от длины токена
+	expected := sha256.Sum256([]byte(token))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, value, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		got := sha256.Sum256([]byte(strings.TrimSpace(value)))
+		if !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare(got[:], expected[:]) != 1 {
+			logger.Warn("bad admin token", "client", r.RemoteAddr, "url", r.URL.Path, "method", r.Method,
+				"time", time.Now().String(), "place", tools.GetPlace())
+			w.Header().Set("WWW-Authenticate", `Bearer realm="CloudStorage admin"`)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, models.ErrCodeUnauthorized,
+				"admin token is invalid"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readOnlyMethod - методы, которые не меняют хранилище; ключу без права write доступны только они
+func readOnlyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND":
+		return true
+	}
+	return false
+}
+
+// RequestID - middleware, присваивает запросу идентификатор (или берет X-Request-Id клиента)
+// и возвращает его в заголовке, чтобы ошибку из ответа можно было найти в логах
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if !validRequestID(id) {
+			buf := make([]byte, 8)
+			_, _ = rand.Read(buf)
+			id = hex.EncodeToString(buf)
+		}
+		w.Header().Set("X-Request-Id", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "requestId", id)))
+	})
+}
+
+// validRequestID - чужой идентификатор принимаем, только если он короткий и без спецсимволов
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// WithValue - middleware, кладет в контекст запроса зависимость обработчиков
+func WithValue(next http.Handler, key string, value any) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), key, value)))
+	})
+}
+
 func PanicMiddleware(next http.Handler, logger *slog.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				logger.Error("panic middleware", "panic", err.(error).Error(), tools.GetPlace())
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				logger.Error("panic middleware", "panic", fmt.Sprint(err), "request_id", apierror.RequestID(r),
+					"place", tools.GetPlace())
+				apierror.Write(w, r, apierror.New(http.StatusInternalServerError, models.ErrCodeInternal,
+					"Internal server error"))
 			}
 		}()
 
@@ -106,12 +301,8 @@ func ShutdownMiddleware(exitChan chan struct{}, conns *sync.WaitGroup, next http
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		select {
 		case <-exitChan:
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			_ = json.NewEncoder(w).Encode(map[string]string{
-				"error":   "service_unavailable",
-				"message": "Service is shutting down",
-			})
+			apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable,
+				"Service is shutting down"))
 			return
 		default:
 			next.ServeHTTP(w, r)
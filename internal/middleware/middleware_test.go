@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/metrics"
+	"CloudStorageProject-FileServer/pkg/config"
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRequestAPIKey(t *testing.T) {
+	cases := []struct {
+		name      string
+		method    string
+		target    string
+		header    map[string]string
+		cookie    string
+		queryKeys bool
+		want      string
+	}{
+		{name: "bearer", method: http.MethodPost, target: "/", header: map[string]string{"Authorization": "Bearer key"}, want: "key"},
+		{name: "bearer case and spaces", method: http.MethodGet, target: "/", header: map[string]string{"Authorization": "bearer  key "}, want: "key"},
+		{name: "basic is not a key", method: http.MethodGet, target: "/", header: map[string]string{"Authorization": "Basic a2V5Og=="}, want: ""},
+		{name: "x-api-key", method: http.MethodDelete, target: "/", header: map[string]string{APIKeyHeader: "key"}, want: "key"},
+		{name: "bearer before x-api-key", method: http.MethodGet, target: "/", header: map[string]string{"Authorization": "Bearer first", APIKeyHeader: "second"}, want: "first"},
+		{name: "cookie on get", method: http.MethodGet, target: "/", cookie: "key", want: "key"},
+		{name: "cookie on head", method: http.MethodHead, target: "/", cookie: "key", want: "key"},
+		{name: "cookie on post", method: http.MethodPost, target: "/", cookie: "key", want: ""},
+		{name: "cookie on delete", method: http.MethodDelete, target: "/", cookie: "key", want: ""},
+		{name: "query disabled", method: http.MethodGet, target: "/?api=key", want: ""},
+		{name: "query enabled", method: http.MethodGet, target: "/?api=key", queryKeys: true, want: "key"},
+		{name: "header before query", method: http.MethodGet, target: "/?api=query", header: map[string]string{APIKeyHeader: "header"}, queryKeys: true, want: "header"},
+		{name: "nothing", method: http.MethodGet, target: "/", queryKeys: true, want: ""},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, c.target, nil)
+		for k, v := range c.header {
+			r.Header.Set(k, v)
+		}
+		if c.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: apiKeyCookie, Value: c.cookie})
+		}
+		if got := requestAPIKey(r, c.queryKeys); got != c.want {
+			t.Errorf("%s: requestAPIKey = %q; want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestWithoutAPIKey(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{"/files?api=key&path=docs", "/files?path=docs"},
+		{"/files?api=key", "/files"},
+		{"/files?api=a&api=b&sort=name", "/files?sort=name"},
+		{"/files?path=docs", "/files?path=docs"},
+		{"/files", "/files"},
+	}
+	for _, c := range cases {
+		u, err := url.Parse(c.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := withoutAPIKey(u).String(); got != c.want {
+			t.Errorf("withoutAPIKey(%q) = %q; want %q", c.raw, got, c.want)
+		}
+		if u.String() != c.raw {
+			t.Errorf("withoutAPIKey(%q) changed the original url to %q", c.raw, u.String())
+		}
+	}
+}
+
+// testAccounts - аккаунты по значению ключа вместо Postgres
+type testAccounts map[string]*models.APIPGS
+
+func (a testAccounts) CheckApiExists(api string) *models.APIPGS {
+	account, ok := a[api]
+	if !ok {
+		return nil
+	}
+	copied := *account
+	return &copied
+}
+
+func (a testAccounts) UpdateLastLogin(accountId int) error {
+	return nil
+}
+
+func testRedis(t *testing.T) *redis.Redis {
+	t.Helper()
+	server := miniredis.RunT(t)
+	ctx := context.WithValue(context.Background(), "config", &config.Config{RedisHost: server.Host(),
+		RedisPort: server.Port()})
+	rds, err := redis.NewRedis(ctx, &metrics.RedisMetrics{
+		QueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"operation"}),
+		QueryTotal:    prometheus.NewCounterVec(prometheus.CounterOpts{Name: "total"}, []string{"operation", "status"}),
+		ErrorsTotal:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: "errors"}, []string{"operation"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rds
+}
+
+// validateAPI - ValidateAPI перед обработчиком, который отвечает 200 и запоминает контекст запроса
+func validateAPI(t *testing.T, queryKeys bool) (http.Handler, *http.Request) {
+	t.Helper()
+	accounts := testAccounts{
+		"writer": {Id: 1, StorageId: "u-writer", Status: models.KeyStatusActive, Permissions: models.DefaultPermissions},
+		"reader": {Id: 2, StorageId: "u-reader", Status: models.KeyStatusActive, Permissions: models.PermissionRead},
+		"paused": {Id: 3, StorageId: "u-paused", Status: models.KeyStatusSuspended, Permissions: models.DefaultPermissions},
+	}
+	for key, account := range accounts {
+		account.KeyHash = models.HashAPIKey(key)
+	}
+	seen := &http.Request{}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*seen = *r
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return ValidateAPI(next, accounts, testRedis(t), nil, "", queryKeys, logger), seen
+}
+
+// errorCode - код ошибки из конверта models.ErrorResponse
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	resp := models.ErrorResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("response %d is not an error envelope: %v", w.Code, err)
+	}
+	return resp.Error.Code
+}
+
+func TestValidateAPIPermissions(t *testing.T) {
+	handler, _ := validateAPI(t, false)
+	cases := []struct {
+		key    string
+		method string
+		target string
+		status int
+		code   string
+	}{
+		{"writer", http.MethodPost, "/client/api/v1/upload-files", http.StatusOK, ""},
+		{"writer", http.MethodDelete, "/api/v2/files/a.txt", http.StatusOK, ""},
+		{"reader", http.MethodGet, "/client/api/v1/files", http.StatusOK, ""},
+		{"reader", http.MethodHead, "/api/v2/files/a.txt", http.StatusOK, ""},
+		{"reader", "PROPFIND", "/dav/", http.StatusOK, ""},
+		{"reader", http.MethodPost, "/client/api/v1/upload-files", http.StatusForbidden, models.ErrCodeForbidden},
+		{"reader", http.MethodPut, "/api/v2/files/a.txt", http.StatusForbidden, models.ErrCodeForbidden},
+		{"reader", http.MethodDelete, "/dav/a.txt", http.StatusForbidden, models.ErrCodeForbidden},
+		{"paused", http.MethodGet, "/client/api/v1/files", http.StatusUnauthorized, models.ErrCodeAPIKeyInvalid},
+		{"unknown", http.MethodGet, "/client/api/v1/files", http.StatusUnauthorized, models.ErrCodeAPIKeyInvalid},
+		{"", http.MethodGet, "/client/api/v1/files", http.StatusUnauthorized, models.ErrCodeAPIKeyRequired},
+		// вне /client, /api и /dav ключ не нужен
+		{"", http.MethodGet, "/health", http.StatusOK, ""},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, c.target, nil)
+		if c.key != "" {
+			if c.target == "/dav/" || c.target == "/dav/a.txt" {
+				r.SetBasicAuth("user", c.key)
+			} else {
+				r.Header.Set("Authorization", "Bearer "+c.key)
+			}
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s %s with %q: %d; want %d", c.method, c.target, c.key, w.Code, c.status)
+			continue
+		}
+		if c.code != "" {
+			if code := errorCode(t, w); code != c.code {
+				t.Errorf("%s %s with %q: code %q; want %q", c.method, c.target, c.key, code, c.code)
+			}
+		}
+	}
+}
+
+func TestValidateAPIContext(t *testing.T) {
+	handler, seen := validateAPI(t, true)
+	r := httptest.NewRequest(http.MethodGet, "/client/api/v1/files?api=reader&path=docs", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	// ключ из адреса не доходит до обработчиков и их логов
+	if seen.URL.String() != "/client/api/v1/files?path=docs" {
+		t.Errorf("url = %q", seen.URL.String())
+	}
+	if bucket := seen.Context().Value("bucket").(string); bucket != "u-reader" {
+		t.Errorf("bucket = %q", bucket)
+	}
+	if account := seen.Context().Value("account").(*models.APIPGS); account == nil || account.Id != 2 {
+		t.Errorf("account = %+v", account)
+	}
+}
+
+func TestValidateAPIQueryKeyDisabled(t *testing.T) {
+	handler, _ := validateAPI(t, false)
+	r := httptest.NewRequest(http.MethodGet, "/client/api/v1/files?api=reader", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized || errorCode(t, w) != models.ErrCodeAPIKeyRequired {
+		t.Fatalf("key in query string with API_KEY_IN_QUERY=false: %d", w.Code)
+	}
+}
@@ -3,10 +3,12 @@ package minio_client
 import (
 	"CloudStorageProject-FileServer/internal/metrics"
 	MinioConfig "CloudStorageProject-FileServer/internal/minio/config"
+	"CloudStorageProject-FileServer/internal/storage"
 	"CloudStorageProject-FileServer/pkg/config"
 	"CloudStorageProject-FileServer/pkg/models"
-	"CloudStorageProject-FileServer/pkg/tools"
 	"context"
+	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -14,6 +16,9 @@ import (
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
+// ChecksumMetaKey - пользовательские метаданные объекта (X-Amz-Meta-Sha256) с sha256 содержимого
+const ChecksumMetaKey = "Sha256"
+
 type MinioClient struct {
 	MinioClient *minio.Client
 	MinioConfig *MinioConfig.MinioConfig
@@ -69,17 +74,39 @@ func (mc *MinioClient) Init() error {
 	return nil
 }
 
+// mapError - переводит ответы MinIO в ошибки storage, чтобы обработчики не зависели от драйвера
+func mapError(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket":
+		return fmt.Errorf("%w: %w", storage.ErrNotFound, err)
+	}
+	return err
+}
+
 func (mc *MinioClient) CreateOne(apiBucket string, file models.FileMinio) error {
 
-	start := time.Now()
-	_, err := mc.MinioClient.PutObject(mc.ctx, apiBucket, file.FileName, file.Reader, file.Size, minio.PutObjectOptions{
+	// защищенный файл перезаписывать нельзя
+	if err := mc.checkNotLocked(apiBucket, file.FileName); err != nil {
+		mc.Metrics.UploadErrors.WithLabelValues(apiBucket, err.Error()).Inc()
+		return err
+	}
+	options := minio.PutObjectOptions{
 		ContentType: file.ContentType,
-	})
+	}
+	if file.Checksum != "" {
+		options.UserMetadata = map[string]string{ChecksumMetaKey: file.Checksum}
+	}
+	// размер неизвестен - PutObject режет поток на части, буфер в памяти не больше одной части
+	if file.Size < 0 {
+		options.PartSize = mc.MinioConfig.UploadPartSize
+	}
+	start := time.Now()
+	info, err := mc.MinioClient.PutObject(mc.ctx, apiBucket, file.FileName, file.Reader, file.Size, options)
 	end := time.Since(start)
 	if err != nil {
 		// если ошибка, добавляем метрики ошибок
 		mc.Metrics.UploadErrors.WithLabelValues(apiBucket, err.Error()).Inc()
-		return err
+		return mapError(err)
 	}
 	// Если добавление файла успешно, обновляем метрики
 	// +1 к общему количеству загрузок
@@ -87,58 +114,148 @@ func (mc *MinioClient) CreateOne(apiBucket string, file models.FileMinio) error
 	// Запоминаем время загрузки
 	mc.Metrics.UploadTime.WithLabelValues(apiBucket).Observe(end.Seconds())
 	// Запоминаем размер файла
-	mc.Metrics.UploadSize.WithLabelValues(apiBucket).Observe(float64(file.Size))
+	mc.Metrics.UploadSize.WithLabelValues(apiBucket).Observe(float64(info.Size))
 	return nil
 }
 
-// GetOne - берет файл с minio, взовращаем object потому что потом сразу в io.Writer, http.ResponseWriter
-func (mc *MinioClient) GetOne(apiBucket string, objectName string) (*minio.Object, error) {
+// GetOne - берет файл с minio, reader потом сразу копируется в io.Writer, http.ResponseWriter
+func (mc *MinioClient) GetOne(apiBucket string, objectName string) (io.ReadCloser, *models.ObjectInfo, error) {
 
 	obj, err := mc.MinioClient.GetObject(mc.ctx, apiBucket, objectName, minio.GetObjectOptions{})
 	if err != nil {
 		// Если ошибка выдачи файла
 		mc.Metrics.DownloadErrors.WithLabelValues(apiBucket, err.Error()).Inc()
-		return nil, err
+		return nil, nil, mapError(err)
+	}
+	// GetObject ленивый: ошибка "нет такого файла" приходит только при первом обращении
+	stat, err := obj.Stat()
+	if err != nil {
+		_ = obj.Close()
+		mc.Metrics.DownloadErrors.WithLabelValues(apiBucket, err.Error()).Inc()
+		return nil, nil, mapError(err)
 	}
 	// Если успешно, +1 к скачиваниям
 	mc.Metrics.DownloadsTotal.WithLabelValues(apiBucket).Inc()
-	return obj, nil
+	return obj, objectInfo(stat), nil
+}
+
+// Stat - характеристики файла
+func (mc *MinioClient) Stat(apiBucket string, objectName string) (*models.ObjectInfo, error) {
+	stat, err := mc.MinioClient.StatObject(mc.ctx, apiBucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return objectInfo(stat), nil
 }
 
-func (mc *MinioClient) FilesList(apiBucket string) ([]models.FileWebResponse, error) {
+func objectInfo(obj minio.ObjectInfo) *models.ObjectInfo {
+	return &models.ObjectInfo{
+		Key:          obj.Key,
+		Size:         obj.Size,
+		ContentType:  obj.ContentType,
+		ETag:         obj.ETag,
+		LastModified: obj.LastModified,
+		IsDir:        strings.HasSuffix(obj.Key, "/"),
+		Checksum:     MetaChecksum(obj.UserMetadata),
+	}
+}
 
-	var files []models.FileWebResponse
+// MetaChecksum - sha256 из метаданных: StatObject отдает ключ без префикса, список с метаданными - как X-Amz-Meta-Sha256
+func MetaChecksum(meta map[string]string) string {
+	for key, value := range meta {
+		if strings.EqualFold(strings.TrimPrefix(strings.ToLower(key), "x-amz-meta-"), ChecksumMetaKey) {
+			return value
+		}
+	}
+	return ""
+}
 
-	objs := mc.MinioClient.ListObjects(mc.ctx, apiBucket, minio.ListObjectsOptions{
-		Recursive: false,
-	})
-	for obj := range objs {
+// List - объекты бакета с префиксом
+func (mc *MinioClient) List(apiBucket string, prefix string, recursive bool) ([]models.ObjectInfo, error) {
+	// S3 сам за пределы бакета не выйдет, но префикс проверяется так же, как у остальных драйверов
+	if err := storage.ValidatePrefix(prefix); err != nil {
+		return nil, err
+	}
+	objects := []models.ObjectInfo{}
+	for obj := range mc.MinioClient.ListObjects(mc.ctx, apiBucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: recursive,
+		// расширение MinIO: пользовательские метаданные (sha256) приходят вместе со списком
+		WithMetadata: true,
+	}) {
 		if obj.Err != nil {
 			mc.Metrics.FilesListErrors.WithLabelValues(apiBucket, obj.Err.Error()).Inc()
-			return []models.FileWebResponse{}, obj.Err
+			return []models.ObjectInfo{}, mapError(obj.Err)
 		}
-		fileSize := tools.FormatFileSize(obj.Size)
-		fileName := obj.Key
-		fileNameSplit := strings.Split(fileName, ".")
-		fileType := fileNameSplit[len(fileNameSplit)-1]
-		files = append(files, models.FileWebResponse{
-			FileName:    fileName,
-			FileSize:    fileSize,
-			FileType:    fileType,
-			LastModTime: obj.LastModified.Format("02.01.2006 12:05"),
-		})
-	}
-	mc.Metrics.FilesListTotal.WithLabelValues(apiBucket).Add(float64(len(files)))
-	return files, nil
+		objects = append(objects, *objectInfo(obj))
+	}
+	mc.Metrics.FilesListTotal.WithLabelValues(apiBucket).Add(float64(len(objects)))
+	return objects, nil
+}
+
+// ObjectTags - теги объекта
+func (mc *MinioClient) ObjectTags(apiBucket string, objectName string) (map[string]string, error) {
+	objTags, err := mc.MinioClient.GetObjectTagging(mc.ctx, apiBucket, objectName, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return objTags.ToMap(), nil
 }
 
 func (mc *MinioClient) Delete(apiBucket string, objectName string) error {
 
+	if err := mc.checkNotLocked(apiBucket, objectName); err != nil {
+		mc.Metrics.DeleteErrors.WithLabelValues(apiBucket, err.Error()).Inc()
+		return err
+	}
 	err := mc.MinioClient.RemoveObject(mc.ctx, apiBucket, objectName, minio.RemoveObjectOptions{})
 	if err != nil {
 		mc.Metrics.DeleteErrors.WithLabelValues(apiBucket, err.Error()).Inc()
-		return err
+		return mapError(err)
 	}
 	mc.Metrics.DeletesTotal.WithLabelValues(apiBucket).Inc()
 	return nil
 }
+
+// Copy - серверное копирование объекта внутри бакета
+func (mc *MinioClient) Copy(apiBucket string, objectName string, newObjectName string) error {
+	// копирование перезаписывает целевой файл
+	if err := mc.checkNotLocked(apiBucket, newObjectName); err != nil {
+		return err
+	}
+	_, err := mc.MinioClient.CopyObject(mc.ctx, minio.CopyDestOptions{
+		Bucket: apiBucket,
+		Object: newObjectName,
+	}, minio.CopySrcOptions{
+		Bucket: apiBucket,
+		Object: objectName,
+	})
+	return mapError(err)
+}
+
+// Rename - переименовывает файл: в S3 нет переименования, поэтому копируем объект и удаляем исходный
+func (mc *MinioClient) Rename(apiBucket string, objectName string, newObjectName string) error {
+
+	// переименование удаляет исходный файл
+	if err := mc.checkNotLocked(apiBucket, objectName); err != nil {
+		mc.Metrics.RenameErrors.WithLabelValues(apiBucket, err.Error()).Inc()
+		return err
+	}
+	if err := mc.Copy(apiBucket, objectName, newObjectName); err != nil {
+		mc.Metrics.RenameErrors.WithLabelValues(apiBucket, err.Error()).Inc()
+		return err
+	}
+	if err := mc.MinioClient.RemoveObject(mc.ctx, apiBucket, objectName, minio.RemoveObjectOptions{}); err != nil {
+		mc.Metrics.RenameErrors.WithLabelValues(apiBucket, err.Error()).Inc()
+		return mapError(err)
+	}
+	mc.Metrics.RenamesTotal.WithLabelValues(apiBucket).Inc()
+	return nil
+}
+
+var (
+	_ storage.Storage      = (*MinioClient)(nil)
+	_ storage.Locker       = (*MinioClient)(nil)
+	_ storage.Provisioner  = (*MinioClient)(nil)
+	_ storage.BucketCopier = (*MinioClient)(nil)
+)
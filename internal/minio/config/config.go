@@ -10,6 +10,15 @@ type MinioConfig struct {
 	MinioRootUser      string
 	MinioRootPassword  string
 	MinioUserSSL       bool
+
+	// значения по умолчанию для новых бакетов пользователей
+	BucketObjectLocking bool
+	BucketVersioning    bool
+	BucketPolicy        string
+	BucketQuotaBytes    int64
+
+	// UploadPartSize - размер части multipart-загрузки файлов неизвестного размера
+	UploadPartSize uint64
 }
 
 func LoadMinioConfig(conf *config.Config) *MinioConfig {
@@ -19,5 +28,12 @@ func LoadMinioConfig(conf *config.Config) *MinioConfig {
 		MinioRootUser:      conf.MinIOUser,
 		MinioRootPassword:  conf.MinIOPassword,
 		MinioUserSSL:       conf.MinIOUseSSL,
+
+		BucketObjectLocking: conf.BucketObjectLocking,
+		BucketVersioning:    conf.BucketVersioning,
+		BucketPolicy:        conf.BucketPolicy,
+		BucketQuotaBytes:    conf.BucketQuotaMB * 1024 * 1024,
+
+		UploadPartSize: conf.UploadPartSizeMB * 1024 * 1024,
 	}
 }
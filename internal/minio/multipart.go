@@ -0,0 +1,80 @@
+package minio_client
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// multipartError - ошибки составной загрузки в виде ошибок storage
+func multipartError(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchUpload":
+		return fmt.Errorf("%w: %w", storage.ErrNoSuchUpload, err)
+	case "InvalidPart", "InvalidPartOrder", "EntityTooSmall":
+		return fmt.Errorf("%w: %w", storage.ErrInvalidPart, err)
+	}
+	return mapError(err)
+}
+
+func (mc *MinioClient) NewMultipartUpload(apiBucket string, objectName string, contentType string) (string, error) {
+	// защищенный файл не перезаписать и составной загрузкой
+	if err := mc.checkNotLocked(apiBucket, objectName); err != nil {
+		return "", err
+	}
+	core := minio.Core{Client: mc.MinioClient}
+	uploadId, err := core.NewMultipartUpload(mc.ctx, apiBucket, objectName, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		mc.Metrics.UploadErrors.WithLabelValues(apiBucket, err.Error()).Inc()
+		return "", multipartError(err)
+	}
+	return uploadId, nil
+}
+
+func (mc *MinioClient) PutObjectPart(apiBucket string, objectName string, uploadId string, partNumber int,
+	reader io.Reader, size int64) (string, error) {
+	core := minio.Core{Client: mc.MinioClient}
+	part, err := core.PutObjectPart(mc.ctx, apiBucket, objectName, uploadId, partNumber, reader, size,
+		minio.PutObjectPartOptions{})
+	if err != nil {
+		mc.Metrics.UploadErrors.WithLabelValues(apiBucket, err.Error()).Inc()
+		return "", multipartError(err)
+	}
+	mc.Metrics.UploadSize.WithLabelValues(apiBucket).Observe(float64(part.Size))
+	return part.ETag, nil
+}
+
+func (mc *MinioClient) CompleteMultipartUpload(apiBucket string, objectName string, uploadId string,
+	parts []models.CompletedPart) (string, error) {
+	if err := mc.checkNotLocked(apiBucket, objectName); err != nil {
+		return "", err
+	}
+	completed := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completed = append(completed, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	core := minio.Core{Client: mc.MinioClient}
+	start := time.Now()
+	info, err := core.CompleteMultipartUpload(mc.ctx, apiBucket, objectName, uploadId, completed, minio.PutObjectOptions{})
+	if err != nil {
+		mc.Metrics.UploadErrors.WithLabelValues(apiBucket, err.Error()).Inc()
+		return "", multipartError(err)
+	}
+	// загрузка считается одна на объект, как и в CreateOne
+	mc.Metrics.UploadsTotal.WithLabelValues(apiBucket).Inc()
+	mc.Metrics.UploadTime.WithLabelValues(apiBucket).Observe(time.Since(start).Seconds())
+	return info.ETag, nil
+}
+
+func (mc *MinioClient) AbortMultipartUpload(apiBucket string, objectName string, uploadId string) error {
+	core := minio.Core{Client: mc.MinioClient}
+	return multipartError(core.AbortMultipartUpload(mc.ctx, apiBucket, objectName, uploadId))
+}
+
+var _ storage.Multiparter = (*MinioClient)(nil)
@@ -0,0 +1,152 @@
+package minio_client
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/signer"
+)
+
+// Политики бакета, которые можно выбрать в BUCKET_POLICY
+const (
+	PolicyPrivate    = "private"
+	PolicyPublicRead = "public-read"
+)
+
+// EnsureBucket - создает бакет пользователя с object locking, версионированием, политикой и квотой по умолчанию.
+// Настройки применяются только к новому бакету, чтобы не затирать ручные изменения
+func (mc *MinioClient) EnsureBucket(bucket string) (bool, error) {
+	exists, err := mc.MinioClient.BucketExists(mc.ctx, bucket)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	conf := mc.MinioConfig
+	err = mc.MinioClient.MakeBucket(mc.ctx, bucket, minio.MakeBucketOptions{
+		// object locking можно включить только при создании бакета, он сам включает версионирование
+		ObjectLocking: conf.BucketObjectLocking,
+	})
+	if err != nil {
+		return false, fmt.Errorf("make bucket %s: %w", bucket, err)
+	}
+	if conf.BucketVersioning && !conf.BucketObjectLocking {
+		if err = mc.MinioClient.EnableVersioning(mc.ctx, bucket); err != nil {
+			return true, fmt.Errorf("enable versioning %s: %w", bucket, err)
+		}
+	}
+	if err = mc.setBucketPolicy(bucket, conf.BucketPolicy); err != nil {
+		return true, fmt.Errorf("set policy %s: %w", bucket, err)
+	}
+	if conf.BucketQuotaBytes > 0 {
+		if err = mc.SetBucketQuota(bucket, conf.BucketQuotaBytes); err != nil {
+			return true, fmt.Errorf("set quota %s: %w", bucket, err)
+		}
+	}
+	return true, nil
+}
+
+// Buckets - все бакеты MinIO
+func (mc *MinioClient) Buckets() ([]string, error) {
+	buckets, err := mc.MinioClient.ListBuckets(mc.ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		names = append(names, bucket.Name)
+	}
+	return names, nil
+}
+
+// RemoveBucket - удаляет пустой бакет
+func (mc *MinioClient) RemoveBucket(bucket string) error {
+	return mapError(mc.MinioClient.RemoveBucket(mc.ctx, bucket))
+}
+
+// CopyObject - серверное копирование объекта в другой бакет, теги и метаданные сохраняются
+func (mc *MinioClient) CopyObject(srcBucket string, objectName string, dstBucket string) error {
+	_, err := mc.MinioClient.CopyObject(mc.ctx, minio.CopyDestOptions{
+		Bucket: dstBucket,
+		Object: objectName,
+	}, minio.CopySrcOptions{
+		Bucket: srcBucket,
+		Object: objectName,
+	})
+	return mapError(err)
+}
+
+func (mc *MinioClient) setBucketPolicy(bucket string, policy string) error {
+	switch policy {
+	case PolicyPrivate, "":
+		// у нового бакета политики нет - доступ только с ключами
+		return nil
+	case PolicyPublicRead:
+		document, _ := json.Marshal(map[string]any{
+			"Version": "2012-10-17",
+			"Statement": []map[string]any{{
+				"Effect":    "Allow",
+				"Principal": map[string]any{"AWS": []string{"*"}},
+				"Action":    []string{"s3:GetObject"},
+				"Resource":  []string{"arn:aws:s3:::" + bucket + "/*"},
+			}},
+		})
+		return mc.MinioClient.SetBucketPolicy(mc.ctx, bucket, string(document))
+	}
+	return fmt.Errorf("unknown bucket policy: %s", policy)
+}
+
+// SetBucketQuota - жесткая квота бакета через admin API MinIO (PUT /minio/admin/v3/set-bucket-quota),
+// нулевая квота снимает ограничение. Запрос подписывается так же, как это делает madmin-go,
+// чтобы не тянуть его зависимости
+func (mc *MinioClient) SetBucketQuota(bucket string, quotaBytes int64) error {
+	body, err := json.Marshal(map[string]any{
+		"quota":     quotaBytes,
+		"size":      quotaBytes,
+		"quotatype": "hard",
+	})
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if mc.MinioConfig.MinioUserSSL {
+		scheme = "https"
+	}
+	target := url.URL{
+		Scheme:   scheme,
+		Host:     mc.MinioConfig.MinioEndPoint,
+		Path:     "/minio/admin/v3/set-bucket-quota",
+		RawQuery: url.Values{"bucket": []string{bucket}}.Encode(),
+	}
+	req, err := http.NewRequestWithContext(mc.ctx, http.MethodPut, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	req.ContentLength = int64(len(body))
+	req = signer.SignV4(*req, mc.MinioConfig.MinioRootUser, mc.MinioConfig.MinioRootPassword, "", "")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("set bucket quota: %s: %s", resp.Status, message)
+	}
+	return nil
+}
+
+var _ storage.Quoter = (*MinioClient)(nil)
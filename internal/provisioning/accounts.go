@@ -0,0 +1,297 @@
+package provisioning
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidPermissions - неизвестное право или набор прав без read
+	ErrInvalidPermissions = errors.New("permissions must be read or read,write")
+	// ErrInvalidQuota - отрицательная квота
+	ErrInvalidQuota = errors.New("quota must not be negative")
+	// ErrLegacyStorage - хранилище названо по ключу; после смены ключа старое значение осталось бы в имени бакета
+	ErrLegacyStorage = errors.New("account storage is named after its api key, run cmd/migrate-storage first")
+	// ErrQuotaNotSupported - драйвер хранилища не умеет квоты, квота сохранена только в базе
+	ErrQuotaNotSupported = storage.ErrQuotaNotSupported
+	// ErrAccountRevoked - отозванный ключ нельзя вернуть в работу или сменить
+	ErrAccountRevoked = errors.New("api key is revoked")
+)
+
+// NewAPIKey - случайный ключ вида prefix.secret: 6 байт открытой части и 32 байта секрета
+func NewAPIKey() string {
+	prefix := make([]byte, 6)
+	secret := make([]byte, 32)
+	_, _ = rand.Read(prefix)
+	_, _ = rand.Read(secret)
+	return hex.EncodeToString(prefix) + "." + hex.EncodeToString(secret)
+}
+
+// Account - аккаунт по id; ErrAccountNotFound, если его нет
+func (p *Provisioning) Account(id int) (*models.APIPGS, error) {
+	account, err := p.postgres.AccountById(id)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, postgres.ErrAccountNotFound
+	}
+	return account, nil
+}
+
+// Create - новый аккаунт с ключом, хранилищем, правами и квотой. Пустые права - DefaultPermissions.
+// Права записываются тем же INSERT, что и ключ. Если аккаунт создан, а хранилище или квоту применить
+// не удалось (в том числе ErrQuotaNotSupported), аккаунт возвращается вместе с ошибкой:
+// ключ показывается только один раз и не должен потеряться
+func (p *Provisioning) Create(email string, permissions string, quotaMB int64) (*models.APIPGS, error) {
+	if permissions == "" {
+		permissions = models.DefaultPermissions
+	}
+	normalized, ok := models.NormalizePermissions(permissions)
+	if !ok {
+		return nil, ErrInvalidPermissions
+	}
+	if quotaMB < 0 {
+		return nil, ErrInvalidQuota
+	}
+	account, err := p.CreateAccount(NewAPIKey(), email, normalized)
+	if account == nil {
+		return nil, err
+	}
+	if err != nil || quotaMB == 0 {
+		return account, err
+	}
+	// значение ключа есть только у только что созданной записи, повторные чтения из базы его не знают
+	key := account.Key
+	updated, err := p.SetQuota(account.Id, quotaMB)
+	if updated != nil {
+		account = updated
+		account.Key = key
+	}
+	return account, err
+}
+
+// Revoke - отзывает ключ. Запись в кэше удаляется сразу, иначе ValidateAPI пускал бы по ключу до ее истечения
+func (p *Provisioning) Revoke(id int) (*models.APIPGS, error) {
+	account, err := p.postgres.SetAccountStatus(id, models.KeyStatusRevoked)
+	if err != nil {
+		return nil, err
+	}
+	p.redis.DelAPIField(account.KeyHash)
+	return account, nil
+}
+
+// Suspend - временно отключает ключ, Resume возвращает его в работу
+func (p *Provisioning) Suspend(id int) (*models.APIPGS, error) {
+	account, err := p.Account(id)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == models.KeyStatusRevoked {
+		return nil, ErrAccountRevoked
+	}
+	account, err = p.postgres.SetAccountStatus(id, models.KeyStatusSuspended)
+	if err != nil {
+		return nil, err
+	}
+	p.redis.DelAPIField(account.KeyHash)
+	return account, nil
+}
+
+// Resume - возвращает в работу приостановленный ключ
+func (p *Provisioning) Resume(id int) (*models.APIPGS, error) {
+	account, err := p.Account(id)
+	if err != nil {
+		return nil, err
+	}
+	switch account.Status {
+	case models.KeyStatusActive:
+		return account, nil
+	case models.KeyStatusRevoked:
+		return nil, ErrAccountRevoked
+	}
+	return p.postgres.SetAccountStatus(id, models.KeyStatusActive)
+}
+
+// Rotate - выдает аккаунту новый ключ, старый перестает работать сразу. Вместе с ключом меняются ключи S3
+// и удаляются SSH-ключи аккаунта. Файлы привязаны к хранилищу и не меняются
+func (p *Provisioning) Rotate(id int) (*models.APIPGS, error) {
+	account, err := p.Account(id)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == models.KeyStatusRevoked {
+		return nil, ErrAccountRevoked
+	}
+	if account.LegacyStorage() {
+		return nil, ErrLegacyStorage
+	}
+	rotated, err := p.postgres.RotateAPIKey(id, NewAPIKey())
+	if err != nil {
+		return nil, err
+	}
+	p.redis.DelAPIField(account.KeyHash)
+	return rotated, nil
+}
+
+// LegacyAccountsError - аккаунты, не прошедшие cmd/migrate-storage: значение их ключа лежит в storage_id
+// и в имени бакета. errors.Is(err, ErrLegacyStorage) для нее выполняется
+type LegacyAccountsError struct {
+	Ids []int
+}
+
+func (e *LegacyAccountsError) Error() string {
+	return fmt.Sprintf("%d accounts have storage named after their api key, run cmd/migrate-storage first",
+		len(e.Ids))
+}
+
+func (e *LegacyAccountsError) Unwrap() error {
+	return ErrLegacyStorage
+}
+
+// ClearPlaintextKeys - завершает переход на хэши: стирает из базы открытые значения ключей старого формата
+// и их записи в кэше. Ключи продолжают работать - их хэши посчитаны при старте сервера. Возвращает,
+// сколько значений стерто. Пока есть неперенесенные аккаунты, ничего не стирает и возвращает
+// *LegacyAccountsError: у них значение ключа осталось бы в имени хранилища, и стирание ничего бы не скрыло
+func (p *Provisioning) ClearPlaintextKeys() (int, error) {
+	accounts, err := p.postgres.Accounts(models.AccountFilter{})
+	if err != nil {
+		return 0, err
+	}
+	legacy := &LegacyAccountsError{}
+	for i := range accounts {
+		if accounts[i].LegacyStorage() {
+			legacy.Ids = append(legacy.Ids, accounts[i].Id)
+		}
+	}
+	if len(legacy.Ids) > 0 {
+		return 0, legacy
+	}
+	keys, err := p.postgres.ClearPlaintextKeys()
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		// до перехода кэш хранил аккаунты под открытым значением ключа
+		p.redis.DelAPIField(key)
+	}
+	return len(keys), nil
+}
+
+// SetPermissions - права ключа, например "read" или "read,write"
+func (p *Provisioning) SetPermissions(id int, permissions string) (*models.APIPGS, error) {
+	normalized, ok := models.NormalizePermissions(permissions)
+	if !ok {
+		return nil, ErrInvalidPermissions
+	}
+	account, err := p.postgres.SetAccountPermissions(id, normalized)
+	if err != nil {
+		return nil, err
+	}
+	p.redis.DelAPIField(account.KeyHash)
+	return account, nil
+}
+
+// SetQuota - квота аккаунта в мегабайтах; 0 возвращает квоту по умолчанию BUCKET_QUOTA_MB.
+// Квота сохраняется в базе, даже если драйвер ее не поддерживает - тогда возвращается ErrQuotaNotSupported
+func (p *Provisioning) SetQuota(id int, quotaMB int64) (*models.APIPGS, error) {
+	if quotaMB < 0 {
+		return nil, ErrInvalidQuota
+	}
+	account, err := p.postgres.SetAccountQuota(id, quotaMB)
+	if err != nil {
+		return nil, err
+	}
+	p.redis.DelAPIField(account.KeyHash)
+	quoter, ok := p.storage.(storage.Quoter)
+	if !ok {
+		return account, ErrQuotaNotSupported
+	}
+	if quotaMB == 0 {
+		quotaMB = p.defaultQuotaMB
+	}
+	// обертки хранилища (журнал, репликация) реализуют Quoter всегда и сообщают ErrQuotaNotSupported сами
+	err = quoter.SetBucketQuota(account.StorageId, quotaMB*1024*1024)
+	if errors.Is(err, ErrQuotaNotSupported) {
+		return account, ErrQuotaNotSupported
+	}
+	if err != nil {
+		return account, fmt.Errorf("failed to set storage quota: %w", err)
+	}
+	return account, nil
+}
+
+// Usage - сколько файлов и места занимает аккаунт, отдельно - корзина
+func (p *Provisioning) Usage(id int) (*models.AccountUsage, error) {
+	account, err := p.Account(id)
+	if err != nil {
+		return nil, err
+	}
+	usage := &models.AccountUsage{Account: account}
+	objects, err := p.storage.List(account.StorageId, "", true)
+	if errors.Is(err, storage.ErrNotFound) {
+		// хранилище еще не создано - аккаунт ничего не занимает
+		return usage, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		switch {
+		case obj.IsDir:
+		case strings.HasPrefix(obj.Key, models.TrashPrefix):
+			usage.TrashFiles++
+			usage.TrashBytes += obj.Size
+		default:
+			usage.Files++
+			usage.Bytes += obj.Size
+		}
+	}
+	return usage, nil
+}
+
+// Purge - удаляет аккаунт целиком: файлы, хранилище и записи в базе. Если часть файлов удалить не удалось
+// (например, они под WORM-защитой), аккаунт не удаляется, а ключ остается отозванным
+func (p *Provisioning) Purge(id int) error {
+	account, err := p.Account(id)
+	if err != nil {
+		return err
+	}
+	// сначала отзываем ключ, чтобы во время удаления не появились новые файлы
+	if _, err = p.Revoke(id); err != nil {
+		return err
+	}
+	objects, errList := p.storage.List(account.StorageId, "", true)
+	if errList != nil && !errors.Is(errList, storage.ErrNotFound) {
+		return errList
+	}
+	var errs []error
+	for _, obj := range objects {
+		if obj.IsDir {
+			continue
+		}
+		if errDelete := p.storage.Delete(account.StorageId, obj.Key); errDelete != nil {
+			errs = append(errs, fmt.Errorf("delete %s: %w", obj.Key, errDelete))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("account %d is revoked, but %d files are left: %w", id, len(errs), errors.Join(errs...))
+	}
+	// служебный бакет не удаляем, даже если тестовый ключ совпадает с его именем. Бакет с версиями
+	// не удалится, пока в нем есть старые версии; тогда Reconcile покажет его среди сирот
+	provisioner, ok := p.storage.(storage.Provisioner)
+	if ok && errList == nil && account.StorageId != p.exampleBucket {
+		if err = provisioner.RemoveBucket(account.StorageId); err != nil {
+			p.logger.Warn("failed to remove purged storage", "error", err.Error(), "account", id,
+				"place", tools.GetPlace())
+		}
+	}
+	return p.postgres.DeleteAccount(id)
+}
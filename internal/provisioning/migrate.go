@@ -0,0 +1,97 @@
+package provisioning
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"errors"
+	"fmt"
+)
+
+// MigrateResult - итог переноса хранилища одного аккаунта
+type MigrateResult struct {
+	AccountId    int
+	OldStorageId string
+	NewStorageId string
+	Moved        int
+	// Left - файлы, которые не удалось удалить из старого хранилища (например, под WORM-защитой)
+	Left int
+}
+
+// MigrateLegacyStorage - переносит хранилища, названные по значению ключа, на неизменяемые storage_id
+func (p *Provisioning) MigrateLegacyStorage() ([]MigrateResult, error) {
+	keys, err := p.postgres.APIKeys()
+	if err != nil {
+		return nil, err
+	}
+	results := []MigrateResult{}
+	var errs []error
+	for _, key := range keys {
+		if !key.LegacyStorage() {
+			continue
+		}
+		result, errMigrate := p.migrateAccount(key)
+		if errMigrate != nil {
+			p.logger.Error("storage migration failed", "error", errMigrate.Error(), "account", key.Id,
+				"place", tools.GetPlace())
+			errs = append(errs, fmt.Errorf("account %d: %w", key.Id, errMigrate))
+			continue
+		}
+		results = append(results, *result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// migrateAccount - копирует файлы в новое хранилище, переключает аккаунт и только потом чистит старое.
+// Если копирование прервалось, аккаунт остается на старом хранилище, а недописанное новое попадет в сироты
+func (p *Provisioning) migrateAccount(account models.APIPGS) (*MigrateResult, error) {
+	result := &MigrateResult{
+		AccountId:    account.Id,
+		OldStorageId: account.StorageId,
+		NewStorageId: storage.NewStorageId(),
+	}
+	if _, err := p.Provision(result.NewStorageId); err != nil {
+		return nil, err
+	}
+
+	objects, err := p.storage.List(result.OldStorageId, "", true)
+	oldExists := err == nil
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	for _, obj := range objects {
+		if obj.IsDir {
+			continue
+		}
+		if err = storage.CopyObjectTo(p.storage, result.OldStorageId, obj.Key, result.NewStorageId); err != nil {
+			return nil, fmt.Errorf("copy %s: %w", obj.Key, err)
+		}
+		result.Moved++
+	}
+
+	if err = p.postgres.MoveStorage(account.Id, result.OldStorageId, result.NewStorageId); err != nil {
+		return nil, err
+	}
+	// в кэше ValidateAPI лежит старый storageId
+	p.redis.DelAPIField(account.KeyHash)
+
+	for _, obj := range objects {
+		if obj.IsDir {
+			continue
+		}
+		if err = p.storage.Delete(result.OldStorageId, obj.Key); err != nil {
+			result.Left++
+			p.logger.Warn("failed to remove migrated file", "error", err.Error(), "account", account.Id,
+				"object", obj.Key)
+		}
+	}
+	// служебный бакет не удаляем, даже если тестовый ключ совпадает с его именем
+	if oldExists && result.Left == 0 && result.OldStorageId != p.exampleBucket {
+		if provisioner, ok := p.storage.(storage.Provisioner); ok {
+			if err = provisioner.RemoveBucket(result.OldStorageId); err != nil {
+				p.logger.Warn("failed to remove old storage", "error", err.Error(), "account", account.Id)
+			}
+		}
+	}
+	return result, nil
+}
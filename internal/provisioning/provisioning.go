@@ -0,0 +1,136 @@
+package provisioning
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/config"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// AccountStore - записи аккаунтов, в работе это *postgres.Postgres
+type AccountStore interface {
+	AccountById(id int) (*models.APIPGS, error)
+	Accounts(filter models.AccountFilter) ([]models.APIPGS, error)
+	APIKeys() ([]models.APIPGS, error)
+	CreateAPIKey(key string, email string, permissions string) (*models.APIPGS, error)
+	SetAccountStatus(id int, status string) (*models.APIPGS, error)
+	SetAccountPermissions(id int, permissions string) (*models.APIPGS, error)
+	SetAccountQuota(id int, quotaMB int64) (*models.APIPGS, error)
+	RotateAPIKey(id int, newKey string) (*models.APIPGS, error)
+	MoveStorage(accountId int, oldStorageId string, newStorageId string) error
+	ClearPlaintextKeys() ([]string, error)
+	DeleteAccount(id int) error
+}
+
+var _ AccountStore = (*postgres.Postgres)(nil)
+
+// Provisioning - создает хранилища пользователей и сверяет их со списком ключей
+type Provisioning struct {
+	postgres AccountStore
+	redis    *redis.Redis
+	storage  storage.Storage
+	logger   *slog.Logger
+	// exampleBucket - служебный бакет, у него нет ключа, но сиротой он не считается
+	exampleBucket string
+	// defaultQuotaMB - квота BUCKET_QUOTA_MB, действует для аккаунтов без своей квоты
+	defaultQuotaMB int64
+}
+
+// ReconcileReport - итог сверки бакетов с minio_keys
+type ReconcileReport struct {
+	Created  []string
+	Failed   []string
+	Orphaned []string
+	// Legacy - аккаунты, чье хранилище все еще названо по ключу
+	Legacy []int
+}
+
+func NewProvisioning(ctx context.Context, pgs AccountStore, rds *redis.Redis, st storage.Storage) *Provisioning {
+	conf := ctx.Value("config").(*config.Config)
+	logger := ctx.Value("logger").(*slog.Logger)
+	return &Provisioning{
+		postgres:       pgs,
+		redis:          rds,
+		storage:        st,
+		logger:         logger,
+		exampleBucket:  conf.MinIOBucket,
+		defaultQuotaMB: conf.BucketQuotaMB,
+	}
+}
+
+// Provision - создает хранилище пользователя с настройками по умолчанию, если драйвер это умеет
+func (p *Provisioning) Provision(bucket string) (bool, error) {
+	provisioner, ok := p.storage.(storage.Provisioner)
+	if !ok {
+		return false, nil
+	}
+	return provisioner.EnsureBucket(bucket)
+}
+
+// CreateAccount - регистрирует ключ с заданными правами и сразу создает для него хранилище
+func (p *Provisioning) CreateAccount(key string, email string, permissions string) (*models.APIPGS, error) {
+	account, err := p.postgres.CreateAPIKey(key, email, permissions)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.Provision(account.StorageId); err != nil {
+		return account, fmt.Errorf("failed to provision storage: %w", err)
+	}
+	return account, nil
+}
+
+// Reconcile - создает недостающие бакеты для всех ключей и ищет бакеты без ключа
+func (p *Provisioning) Reconcile() (*ReconcileReport, error) {
+	provisioner, ok := p.storage.(storage.Provisioner)
+	if !ok {
+		return &ReconcileReport{}, nil
+	}
+	keys, err := p.postgres.APIKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconcileReport{Created: []string{}, Failed: []string{}, Orphaned: []string{}, Legacy: []int{}}
+	known := make(map[string]struct{}, len(keys)+1)
+	known[p.exampleBucket] = struct{}{}
+	for _, key := range keys {
+		known[key.StorageId] = struct{}{}
+		if key.LegacyStorage() {
+			report.Legacy = append(report.Legacy, key.Id)
+		}
+		created, err := provisioner.EnsureBucket(key.StorageId)
+		if err != nil {
+			report.Failed = append(report.Failed, key.StorageId)
+			p.logger.Error("failed to provision storage", "error", err.Error(), "account", key.Id, "place", tools.GetPlace())
+			continue
+		}
+		if created {
+			report.Created = append(report.Created, key.StorageId)
+			p.logger.Info("storage provisioned", "account", key.Id)
+		}
+	}
+
+	buckets, err := provisioner.Buckets()
+	if err != nil {
+		return report, err
+	}
+	for _, bucket := range buckets {
+		if _, ok := known[bucket]; !ok {
+			report.Orphaned = append(report.Orphaned, bucket)
+		}
+	}
+	slices.Sort(report.Orphaned)
+	if len(report.Orphaned) > 0 {
+		p.logger.Warn("orphaned buckets without api key", "buckets", report.Orphaned)
+	}
+	if len(report.Legacy) > 0 {
+		p.logger.Warn("storages named after api keys, run cmd/migrate-storage", "accounts", report.Legacy)
+	}
+	return report, nil
+}
@@ -0,0 +1,451 @@
+package replication
+
+import (
+	"CloudStorageProject-FileServer/internal/metrics"
+	minioClient "CloudStorageProject-FileServer/internal/minio"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/config"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Операции заданий репликации
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// сколько раз повторять задание, прежде чем оставить его на catch-up
+const maxAttempts = 5
+
+type job struct {
+	op         string
+	bucket     string
+	key        string
+	enqueuedAt time.Time
+}
+
+// Replicator - обертка над основным хранилищем: каждое успешное изменение ставится в очередь
+// и асинхронно повторяется на вторичном S3-совместимом хранилище. У каждого воркера своя очередь,
+// задания одного объекта всегда попадают к одному воркеру и выполняются в порядке изменений.
+// Очереди в памяти процесса, задания, потерянные при перезапуске или переполнении, догоняет
+// команда cmd/replicate
+type Replicator struct {
+	storage.Storage
+	replica  *minio.Client
+	metrics  *metrics.ReplicationMetrics
+	logger   *slog.Logger
+	failover bool
+	workers  int
+	queues   []chan job
+	mu       sync.RWMutex // защищает closed: после закрытия очередей задания не принимаются
+	closed   bool
+	ctx      context.Context
+	wg       sync.WaitGroup
+	buckets  sync.Map // бакеты, которые уже есть на реплике
+}
+
+func NewReplicator(ctx context.Context, primary storage.Storage, metric *metrics.ReplicationMetrics) (*Replicator, error) {
+	conf := ctx.Value("config").(*config.Config)
+	logger := ctx.Value("logger").(*slog.Logger)
+
+	replica, err := minio.New(conf.ReplicaEndpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(conf.ReplicaAccessKey, conf.ReplicaSecretKey, ""),
+		Secure: conf.ReplicaUseSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	workers := max(conf.ReplicaWorkers, 1)
+	queues := make([]chan job, workers)
+	for i := range queues {
+		queues[i] = make(chan job, max(conf.ReplicaQueueSize/workers, 1))
+	}
+	return &Replicator{
+		Storage:  primary,
+		replica:  replica,
+		metrics:  metric,
+		logger:   logger,
+		failover: conf.ReplicaReadFailover,
+		workers:  workers,
+		queues:   queues,
+		ctx:      ctx,
+	}, nil
+}
+
+// Start - запускает воркеров, каждый разбирает свою очередь
+func (rp *Replicator) Start() {
+	for _, queue := range rp.queues {
+		rp.wg.Add(1)
+		go func(queue chan job) {
+			defer rp.wg.Done()
+			for j := range queue {
+				rp.metrics.QueueDepth.Dec()
+				rp.process(j)
+			}
+		}(queue)
+	}
+}
+
+// CloseConnection - закрывает очереди, дожидается пока воркеры их разберут, и закрывает основное хранилище
+func (rp *Replicator) CloseConnection(ctx context.Context) error {
+	rp.mu.Lock()
+	rp.closed = true
+	for _, queue := range rp.queues {
+		close(queue)
+	}
+	rp.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		rp.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		left := 0
+		for _, queue := range rp.queues {
+			left += len(queue)
+		}
+		rp.logger.Warn("replication queue is not drained, run catch-up", "left", left)
+		return ctx.Err()
+	}
+	return rp.Storage.CloseConnection(ctx)
+}
+
+// shard - очередь объекта. Два воркера с заданиями одного объекта могли бы выполнить их в обратном
+// порядке: например, удаление после более поздней загрузки стерло бы объект на реплике
+func (rp *Replicator) shard(bucket string, key string) chan job {
+	h := fnv.New32a()
+	h.Write([]byte(bucket + "/" + key))
+	return rp.queues[h.Sum32()%uint32(len(rp.queues))]
+}
+
+// enqueue - ставит задание в очередь, не блокируя запрос пользователя
+func (rp *Replicator) enqueue(op string, bucket string, key string) {
+	j := job{op: op, bucket: bucket, key: key, enqueuedAt: time.Now()}
+	rp.mu.RLock()
+	defer rp.mu.RUnlock()
+	if rp.closed {
+		rp.metrics.JobsTotal.WithLabelValues(op, "dropped").Inc()
+		return
+	}
+	select {
+	case rp.shard(bucket, key) <- j:
+		rp.metrics.QueueDepth.Inc()
+	default:
+		rp.metrics.JobsTotal.WithLabelValues(op, "dropped").Inc()
+		rp.logger.Warn("replication queue is full, job dropped", "operation", op, "bucket", bucket,
+			"object", key, "place", tools.GetPlace())
+	}
+}
+
+func (rp *Replicator) process(j job) {
+	var err error
+retry:
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		switch j.op {
+		case opPut:
+			err = rp.copyToReplica(j.bucket, j.key)
+		case opDelete:
+			err = rp.replica.RemoveObject(rp.ctx, j.bucket, j.key, minio.RemoveObjectOptions{})
+		}
+		// файл успели удалить до репликации - его удаление тоже в очереди
+		if err == nil || errors.Is(err, storage.ErrNotFound) {
+			rp.metrics.JobsTotal.WithLabelValues(j.op, "success").Inc()
+			rp.metrics.Lag.WithLabelValues(j.op).Observe(time.Since(j.enqueuedAt).Seconds())
+			return
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		// при остановке сервера не ждем повторов, задание догонит catch-up
+		select {
+		case <-rp.ctx.Done():
+			err = fmt.Errorf("%w: %w", rp.ctx.Err(), err)
+			break retry
+		case <-time.After(time.Duration(attempt*attempt) * 200 * time.Millisecond):
+		}
+	}
+	rp.metrics.JobsTotal.WithLabelValues(j.op, "error").Inc()
+	rp.logger.Error("replication job failed", "error", err.Error(), "operation", j.op, "bucket", j.bucket,
+		"object", j.key, "place", tools.GetPlace())
+}
+
+// ensureBucket - создает бакет на реплике при первой записи в него
+func (rp *Replicator) ensureBucket(bucket string) error {
+	if _, ok := rp.buckets.Load(bucket); ok {
+		return nil
+	}
+	exists, err := rp.replica.BucketExists(rp.ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err = rp.replica.MakeBucket(rp.ctx, bucket, minio.MakeBucketOptions{})
+		if err != nil && minio.ToErrorResponse(err).Code != "BucketAlreadyOwnedByYou" {
+			return err
+		}
+	}
+	rp.buckets.Store(bucket, struct{}{})
+	return nil
+}
+
+// copyToReplica - перечитывает файл из основного хранилища и пишет его на реплику
+func (rp *Replicator) copyToReplica(bucket string, key string) error {
+	if err := rp.ensureBucket(bucket); err != nil {
+		return err
+	}
+	reader, info, err := rp.Storage.GetOne(bucket, key)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+	options := minio.PutObjectOptions{
+		ContentType: info.ContentType,
+	}
+	if info.Checksum != "" {
+		options.UserMetadata = map[string]string{minioClient.ChecksumMetaKey: info.Checksum}
+	}
+	_, err = rp.replica.PutObject(rp.ctx, bucket, key, reader, info.Size, options)
+	return err
+}
+
+func (rp *Replicator) CreateOne(bucket string, file models.FileMinio) error {
+	if err := rp.Storage.CreateOne(bucket, file); err != nil {
+		return err
+	}
+	rp.enqueue(opPut, bucket, file.FileName)
+	return nil
+}
+
+func (rp *Replicator) Delete(bucket string, objectName string) error {
+	if err := rp.Storage.Delete(bucket, objectName); err != nil {
+		return err
+	}
+	rp.enqueue(opDelete, bucket, objectName)
+	return nil
+}
+
+func (rp *Replicator) Copy(bucket string, objectName string, newObjectName string) error {
+	if err := rp.Storage.Copy(bucket, objectName, newObjectName); err != nil {
+		return err
+	}
+	rp.enqueue(opPut, bucket, newObjectName)
+	return nil
+}
+
+func (rp *Replicator) Rename(bucket string, objectName string, newObjectName string) error {
+	if err := rp.Storage.Rename(bucket, objectName, newObjectName); err != nil {
+		return err
+	}
+	rp.enqueue(opPut, bucket, newObjectName)
+	rp.enqueue(opDelete, bucket, objectName)
+	return nil
+}
+
+// primaryDown - ошибку основного хранилища можно закрыть чтением с реплики
+func (rp *Replicator) primaryDown(err error) bool {
+	if !rp.failover || err == nil {
+		return false
+	}
+	return !errors.Is(err, storage.ErrNotFound) && !errors.Is(err, storage.ErrInvalidObjectName)
+}
+
+func replicaInfo(obj minio.ObjectInfo) *models.ObjectInfo {
+	return &models.ObjectInfo{
+		Key:          obj.Key,
+		Size:         obj.Size,
+		ContentType:  obj.ContentType,
+		ETag:         obj.ETag,
+		LastModified: obj.LastModified,
+		IsDir:        strings.HasSuffix(obj.Key, "/"),
+		Checksum:     minioClient.MetaChecksum(obj.UserMetadata),
+	}
+}
+
+func replicaError(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket":
+		return fmt.Errorf("%w: %w", storage.ErrNotFound, err)
+	}
+	return err
+}
+
+func (rp *Replicator) GetOne(bucket string, objectName string) (io.ReadCloser, *models.ObjectInfo, error) {
+	reader, info, err := rp.Storage.GetOne(bucket, objectName)
+	if !rp.primaryDown(err) {
+		return reader, info, err
+	}
+	rp.metrics.Failovers.WithLabelValues("get").Inc()
+	rp.logger.Warn("primary storage read failed, reading from replica", "error", err.Error(),
+		"place", tools.GetPlace())
+	obj, errReplica := rp.replica.GetObject(rp.ctx, bucket, objectName, minio.GetObjectOptions{})
+	if errReplica != nil {
+		return nil, nil, replicaError(errReplica)
+	}
+	stat, errReplica := obj.Stat()
+	if errReplica != nil {
+		_ = obj.Close()
+		return nil, nil, replicaError(errReplica)
+	}
+	return obj, replicaInfo(stat), nil
+}
+
+func (rp *Replicator) Stat(bucket string, objectName string) (*models.ObjectInfo, error) {
+	info, err := rp.Storage.Stat(bucket, objectName)
+	if !rp.primaryDown(err) {
+		return info, err
+	}
+	rp.metrics.Failovers.WithLabelValues("stat").Inc()
+	stat, errReplica := rp.replica.StatObject(rp.ctx, bucket, objectName, minio.StatObjectOptions{})
+	if errReplica != nil {
+		return nil, replicaError(errReplica)
+	}
+	return replicaInfo(stat), nil
+}
+
+func (rp *Replicator) List(bucket string, prefix string, recursive bool) ([]models.ObjectInfo, error) {
+	objects, err := rp.Storage.List(bucket, prefix, recursive)
+	if !rp.primaryDown(err) {
+		return objects, err
+	}
+	rp.metrics.Failovers.WithLabelValues("list").Inc()
+	return rp.listReplica(bucket, prefix, recursive)
+}
+
+func (rp *Replicator) listReplica(bucket string, prefix string, recursive bool) ([]models.ObjectInfo, error) {
+	objects := []models.ObjectInfo{}
+	for obj := range rp.replica.ListObjects(rp.ctx, bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: recursive,
+		// sha256 нужен Backfill для сверки с основным хранилищем
+		WithMetadata: true,
+	}) {
+		if obj.Err != nil {
+			return []models.ObjectInfo{}, replicaError(obj.Err)
+		}
+		objects = append(objects, *replicaInfo(obj))
+	}
+	return objects, nil
+}
+
+// ObjectProtection, SetRetention, SetLegalHold - блокировки есть только на основном хранилище
+func (rp *Replicator) ObjectProtection(bucket string, objectName string) (*models.ObjectProtection, error) {
+	lock, ok := rp.Storage.(storage.Locker)
+	if !ok {
+		return nil, storage.ErrLockingNotSupported
+	}
+	return lock.ObjectProtection(bucket, objectName)
+}
+
+func (rp *Replicator) SetRetention(bucket string, objectName string, request models.RetentionRequest) error {
+	lock, ok := rp.Storage.(storage.Locker)
+	if !ok {
+		return storage.ErrLockingNotSupported
+	}
+	return lock.SetRetention(bucket, objectName, request)
+}
+
+func (rp *Replicator) SetLegalHold(bucket string, objectName string, enabled bool) error {
+	lock, ok := rp.Storage.(storage.Locker)
+	if !ok {
+		return storage.ErrLockingNotSupported
+	}
+	return lock.SetLegalHold(bucket, objectName, enabled)
+}
+
+// EnsureBucket - хранилище создается на основном сервере, на реплике бакет появится с первым заданием
+func (rp *Replicator) EnsureBucket(bucket string) (bool, error) {
+	provisioner, ok := rp.Storage.(storage.Provisioner)
+	if !ok {
+		return false, nil
+	}
+	return provisioner.EnsureBucket(bucket)
+}
+
+func (rp *Replicator) Buckets() ([]string, error) {
+	provisioner, ok := rp.Storage.(storage.Provisioner)
+	if !ok {
+		return []string{}, nil
+	}
+	return provisioner.Buckets()
+}
+
+func (rp *Replicator) RemoveBucket(bucket string) error {
+	provisioner, ok := rp.Storage.(storage.Provisioner)
+	if !ok {
+		return nil
+	}
+	return provisioner.RemoveBucket(bucket)
+}
+
+// SetBucketQuota - квота ставится только на основном хранилище, реплика получает уже принятые файлы
+func (rp *Replicator) SetBucketQuota(bucket string, quotaBytes int64) error {
+	quoter, ok := rp.Storage.(storage.Quoter)
+	if !ok {
+		return storage.ErrQuotaNotSupported
+	}
+	return quoter.SetBucketQuota(bucket, quotaBytes)
+}
+
+// NewMultipartUpload, PutObjectPart, AbortMultipartUpload - на реплику уходит только собранный объект
+func (rp *Replicator) NewMultipartUpload(bucket string, objectName string, contentType string) (string, error) {
+	multipart, ok := rp.Storage.(storage.Multiparter)
+	if !ok {
+		return "", storage.ErrMultipartNotSupported
+	}
+	return multipart.NewMultipartUpload(bucket, objectName, contentType)
+}
+
+func (rp *Replicator) PutObjectPart(bucket string, objectName string, uploadId string, partNumber int,
+	reader io.Reader, size int64) (string, error) {
+	multipart, ok := rp.Storage.(storage.Multiparter)
+	if !ok {
+		return "", storage.ErrMultipartNotSupported
+	}
+	return multipart.PutObjectPart(bucket, objectName, uploadId, partNumber, reader, size)
+}
+
+func (rp *Replicator) CompleteMultipartUpload(bucket string, objectName string, uploadId string,
+	parts []models.CompletedPart) (string, error) {
+	multipart, ok := rp.Storage.(storage.Multiparter)
+	if !ok {
+		return "", storage.ErrMultipartNotSupported
+	}
+	etag, err := multipart.CompleteMultipartUpload(bucket, objectName, uploadId, parts)
+	if err != nil {
+		return "", err
+	}
+	rp.enqueue(opPut, bucket, objectName)
+	return etag, nil
+}
+
+func (rp *Replicator) AbortMultipartUpload(bucket string, objectName string, uploadId string) error {
+	multipart, ok := rp.Storage.(storage.Multiparter)
+	if !ok {
+		return storage.ErrMultipartNotSupported
+	}
+	return multipart.AbortMultipartUpload(bucket, objectName, uploadId)
+}
+
+var (
+	_ storage.Storage     = (*Replicator)(nil)
+	_ storage.Locker      = (*Replicator)(nil)
+	_ storage.Provisioner = (*Replicator)(nil)
+	_ storage.Quoter      = (*Replicator)(nil)
+	_ storage.Multiparter = (*Replicator)(nil)
+)
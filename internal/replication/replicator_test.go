@@ -0,0 +1,60 @@
+package replication
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"testing"
+	"time"
+)
+
+func TestShardKeepsObjectOnOneQueue(t *testing.T) {
+	rp := &Replicator{queues: make([]chan job, 8)}
+	for i := range rp.queues {
+		rp.queues[i] = make(chan job, 1)
+	}
+	used := map[chan job]bool{}
+	for _, key := range []string{"a.txt", "b.txt", "dir/c.txt", "dir/d.txt", "e", "f", "g", "h"} {
+		queue := rp.shard("u-bucket", key)
+		for i := 0; i < 10; i++ {
+			if rp.shard("u-bucket", key) != queue {
+				t.Fatalf("object %s moved to another queue", key)
+			}
+		}
+		used[queue] = true
+	}
+	if len(used) < 2 {
+		t.Errorf("all objects went to one queue")
+	}
+}
+
+func TestUpToDate(t *testing.T) {
+	written := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	primary := models.ObjectInfo{Key: "a", Size: 10, ETag: "0123-2", LastModified: written}
+
+	cases := []struct {
+		name       string
+		primary    models.ObjectInfo
+		replicated models.ObjectInfo
+		want       bool
+	}{
+		{"multipart etag differs", primary,
+			models.ObjectInfo{Size: 10, ETag: "4567", LastModified: written.Add(time.Second)}, true},
+		{"size differs", primary,
+			models.ObjectInfo{Size: 11, LastModified: written.Add(time.Second)}, false},
+		{"replica older than primary", primary,
+			models.ObjectInfo{Size: 10, LastModified: written.Add(-time.Second)}, false},
+		{"same checksum, older replica",
+			models.ObjectInfo{Size: 10, Checksum: "aa", LastModified: written},
+			models.ObjectInfo{Size: 10, Checksum: "aa", LastModified: written.Add(-time.Hour)}, true},
+		{"checksum differs, newer replica",
+			models.ObjectInfo{Size: 10, Checksum: "aa", LastModified: written},
+			models.ObjectInfo{Size: 10, Checksum: "bb", LastModified: written.Add(time.Hour)}, false},
+		{"checksum only on primary",
+			models.ObjectInfo{Size: 10, Checksum: "aa", LastModified: written},
+			models.ObjectInfo{Size: 10, LastModified: written.Add(time.Second)}, true},
+	}
+	for _, c := range cases {
+		if got := upToDate(c.primary, c.replicated); got != c.want {
+			t.Errorf("%s: upToDate = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
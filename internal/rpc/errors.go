@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"CloudStorageProject-FileServer/internal/apierror"
+	"CloudStorageProject-FileServer/pkg/models"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain - домен машиночитаемых кодов ошибок в ErrorInfo
+const errorDomain = "cloudstorage"
+
+// newStatus - ошибка gRPC с кодом из models.ErrCode* в деталях ErrorInfo, как поле code в JSON-ошибках HTTP API
+func newStatus(code codes.Code, reason string, message string) error {
+	st := status.New(code, message)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func invalidArgument(message string) error {
+	return newStatus(codes.InvalidArgument, models.ErrCodeBadRequest, message)
+}
+
+// toStatus - ошибки хранилища и баз в статусы gRPC. Классификация та же, что у HTTP API (apierror.From)
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	apiErr := apierror.From(err)
+	return newStatus(grpcCode(apiErr), apiErr.Code, apiErr.Message)
+}
+
+func grpcCode(apiErr *apierror.Error) codes.Code {
+	switch apiErr.Code {
+	case models.ErrCodeObjectLocked, models.ErrCodeLockingNotSupported:
+		return codes.FailedPrecondition
+	case models.ErrCodeConflict:
+		return codes.AlreadyExists
+	}
+	switch apiErr.Status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusGone:
+		return codes.OutOfRange
+	case http.StatusRequestEntityTooLarge, http.StatusInsufficientStorage:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Internal
+}
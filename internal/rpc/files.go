@@ -0,0 +1,288 @@
+package rpc
+
+import (
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/internal/upload"
+	"CloudStorageProject-FileServer/pkg/models"
+	filesv1 "CloudStorageProject-FileServer/pkg/proto/files/v1"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// chunkSize - размер части содержимого в Download; лимит сообщения gRPC по умолчанию - 4 МБ
+	chunkSize = 256 << 10
+	// maxPageSize - больше ключей за страницу List не отдает
+	maxPageSize = 1000
+)
+
+var errSizeMismatch = errors.New("file size does not match the size in the header")
+
+// fileService - FileService поверх хранилища аккаунта. Загрузка идет тем же путем, что PUT /files/{path}:
+// через Spooler и с проверкой sha256, поэтому файлы попадают в журнал изменений и реплику
+type fileService struct {
+	filesv1.UnimplementedFileServiceServer
+	storage storage.Storage
+	spool   *upload.Spooler
+	rds     *redis.Redis
+	logger  *slog.Logger
+}
+
+func bucketOf(ctx context.Context) string {
+	return ctx.Value("bucket").(string)
+}
+
+// fileInfo - метаданные файла в том же виде, что models.FileInfo у HTTP API
+func fileInfo(info *models.ObjectInfo) *filesv1.FileInfo {
+	file := &filesv1.FileInfo{
+		Name:        info.Key,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		Sha256:      info.Checksum,
+		IsDir:       info.IsDir,
+	}
+	if info.ETag != "" {
+		file.Etag = `"` + strings.Trim(info.ETag, `"`) + `"`
+	}
+	// у "папок" нет времени изменения
+	if !info.LastModified.IsZero() {
+		file.LastModified = timestamppb.New(info.LastModified)
+	}
+	return file
+}
+
+// publishEvent - событие для подписчиков /events, как у HTTP-обработчиков
+func (s *fileService) publishEvent(bucket string, eventType string, fileName string) {
+	event := models.StorageEvent{Type: eventType, FileName: fileName, Time: time.Now()}
+	if err := s.rds.PublishEvent(bucket, event); err != nil {
+		s.logger.Error("publish storage event error", "error", err.Error(), "event", eventType,
+			"place", tools.GetPlace())
+	}
+}
+
+// readError - ключ, который нельзя сохранить, не может и существовать
+func readError(err error) error {
+	if errors.Is(err, storage.ErrInvalidObjectName) {
+		return toStatus(storage.ErrNotFound)
+	}
+	return toStatus(err)
+}
+
+func (s *fileService) Upload(stream grpc.ClientStreamingServer[filesv1.UploadRequest, filesv1.UploadResponse]) error {
+	bucket := bucketOf(stream.Context())
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return invalidArgument("header is required")
+	}
+	if err != nil {
+		return err
+	}
+	header := first.GetHeader()
+	if header == nil || header.GetName() == "" {
+		return invalidArgument("the first message must be a header with the file name")
+	}
+	name, err := storage.SanitizeObjectName(header.GetName())
+	if err != nil {
+		return toStatus(err)
+	}
+	size := int64(-1)
+	if header.Size != nil {
+		if header.GetSize() < 0 {
+			return invalidArgument("size must not be negative")
+		}
+		size = header.GetSize()
+	}
+
+	created, err := s.spool.Store(s.storage, bucket, models.FileMinio{
+		FileName:    name,
+		Reader:      &chunkReader{stream: stream, size: size},
+		Size:        size,
+		ContentType: header.GetContentType(),
+		Checksum:    header.GetSha256(),
+	})
+	switch {
+	case errors.Is(err, upload.ErrChecksumMismatch):
+		return newStatus(codes.InvalidArgument, models.ErrCodeChecksumMismatch, err.Error())
+	case errors.Is(err, errSizeMismatch):
+		return invalidArgument(err.Error())
+	case err != nil:
+		return toStatus(err)
+	}
+	s.publishEvent(bucket, models.EventUpload, name)
+
+	info, err := s.storage.Stat(bucket, name)
+	if err != nil {
+		return toStatus(err)
+	}
+	return stream.SendAndClose(&filesv1.UploadResponse{File: fileInfo(info), Created: created})
+}
+
+// chunkReader - содержимое файла из сообщений Upload. Если размер объявлен в заголовке, поток ему сверяется:
+// хранилище с известным размером иначе молча обрезало бы лишнее
+type chunkReader struct {
+	stream grpc.ClientStreamingServer[filesv1.UploadRequest, filesv1.UploadResponse]
+	chunk  []byte
+	size   int64
+	read   int64
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.chunk) == 0 {
+		msg, err := c.stream.Recv()
+		if errors.Is(err, io.EOF) {
+			if c.size >= 0 && c.read != c.size {
+				return 0, errSizeMismatch
+			}
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		if msg.GetHeader() != nil {
+			return 0, invalidArgument("header must be sent only once")
+		}
+		c.chunk = msg.GetChunk()
+	}
+	n := copy(p, c.chunk)
+	c.chunk = c.chunk[n:]
+	c.read += int64(n)
+	if c.size >= 0 && c.read > c.size {
+		return n, errSizeMismatch
+	}
+	return n, nil
+}
+
+func (s *fileService) Download(req *filesv1.DownloadRequest, stream grpc.ServerStreamingServer[filesv1.DownloadResponse]) error {
+	if req.GetName() == "" {
+		return invalidArgument("name is required")
+	}
+	if req.GetOffset() < 0 || req.GetLength() < 0 {
+		return invalidArgument("offset and length must not be negative")
+	}
+	reader, info, err := s.storage.GetOne(bucketOf(stream.Context()), req.GetName())
+	if err != nil {
+		return readError(err)
+	}
+	defer func() { _ = reader.Close() }()
+	if req.GetOffset() > info.Size {
+		return newStatus(codes.OutOfRange, models.ErrCodeBadRequest, "offset is beyond the end of the file")
+	}
+	if err = stream.Send(&filesv1.DownloadResponse{Data: &filesv1.DownloadResponse_Info{Info: fileInfo(info)}}); err != nil {
+		return err
+	}
+
+	if offset := req.GetOffset(); offset > 0 {
+		if seeker, ok := reader.(io.Seeker); ok {
+			_, err = seeker.Seek(offset, io.SeekStart)
+		} else {
+			_, err = io.CopyN(io.Discard, reader, offset)
+		}
+		if err != nil {
+			return toStatus(err)
+		}
+	}
+	var body io.Reader = reader
+	if req.GetLength() > 0 {
+		body = io.LimitReader(reader, req.GetLength())
+	}
+	// Send сериализует сообщение до возврата, поэтому буфер можно переиспользовать
+	buf := make([]byte, chunkSize)
+	for {
+		n, errRead := io.ReadFull(body, buf)
+		if n > 0 {
+			if err = stream.Send(&filesv1.DownloadResponse{Data: &filesv1.DownloadResponse_Chunk{Chunk: buf[:n]}}); err != nil {
+				return err
+			}
+		}
+		if errors.Is(errRead, io.EOF) || errors.Is(errRead, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if errRead != nil {
+			return toStatus(errRead)
+		}
+	}
+}
+
+// List - страница списка; токен - последний отданный ключ, как у ListObjectsV2 S3-шлюза
+func (s *fileService) List(ctx context.Context, req *filesv1.ListRequest) (*filesv1.ListResponse, error) {
+	pageSize := int(req.GetPageSize())
+	if pageSize < 0 {
+		return nil, invalidArgument("page_size must not be negative")
+	}
+	if pageSize == 0 || pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	after := ""
+	if req.GetPageToken() != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(req.GetPageToken())
+		if err != nil {
+			return nil, invalidArgument("page_token is invalid")
+		}
+		after = string(decoded)
+	}
+	prefix := req.GetPrefix()
+	if err := storage.ValidatePrefix(prefix); err != nil {
+		return nil, invalidArgument(err.Error())
+	}
+	objects, err := s.storage.List(bucketOf(ctx), prefix, req.GetRecursive())
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, toStatus(err)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	resp := &filesv1.ListResponse{}
+	for i := range objects {
+		if objects[i].Key <= after {
+			continue
+		}
+		// корзину показываем, только если ее запросили явно
+		if strings.HasPrefix(objects[i].Key, models.TrashPrefix) && !strings.HasPrefix(prefix, models.TrashPrefix) {
+			continue
+		}
+		if len(resp.Files) == pageSize {
+			resp.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(resp.Files[pageSize-1].GetName()))
+			break
+		}
+		resp.Files = append(resp.Files, fileInfo(&objects[i]))
+	}
+	return resp, nil
+}
+
+func (s *fileService) Stat(ctx context.Context, req *filesv1.StatRequest) (*filesv1.StatResponse, error) {
+	if req.GetName() == "" {
+		return nil, invalidArgument("name is required")
+	}
+	info, err := s.storage.Stat(bucketOf(ctx), req.GetName())
+	if err != nil {
+		return nil, readError(err)
+	}
+	return &filesv1.StatResponse{File: fileInfo(info)}, nil
+}
+
+func (s *fileService) Delete(ctx context.Context, req *filesv1.DeleteRequest) (*filesv1.DeleteResponse, error) {
+	if req.GetName() == "" {
+		return nil, invalidArgument("name is required")
+	}
+	bucket := bucketOf(ctx)
+	// S3 удаляет несуществующий ключ без ошибки, поэтому NotFound проверяем сами
+	if _, err := s.storage.Stat(bucket, req.GetName()); err != nil {
+		return nil, readError(err)
+	}
+	if err := s.storage.Delete(bucket, req.GetName()); err != nil {
+		return nil, toStatus(err)
+	}
+	s.publishEvent(bucket, models.EventDelete, req.GetName())
+	return &filesv1.DeleteResponse{}, nil
+}
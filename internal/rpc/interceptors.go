@@ -0,0 +1,125 @@
+package rpc
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/middleware"
+	"CloudStorageProject-FileServer/pkg/models"
+	filesv1 "CloudStorageProject-FileServer/pkg/proto/files/v1"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyHeader - метаданные с API-ключом
+const apiKeyHeader = "x-api-key"
+
+// interceptors - то же, что цепочка middleware у HTTP-сервера: паника, лог, проверка ключа
+type interceptors struct {
+	pgs    *postgres.Postgres
+	rds    *redis.Redis
+	logger *slog.Logger
+}
+
+// public - служебные сервисы без ключа: по ним балансировщики и grpcurl проверяют сервер
+func public(method string) bool {
+	return strings.HasPrefix(method, "/grpc.health.v1.Health/") || strings.HasPrefix(method, "/grpc.reflection.")
+}
+
+// writes - методы, которые меняют хранилище; ключу без права write они недоступны
+func writes(method string) bool {
+	return method == filesv1.FileService_Upload_FullMethodName || method == filesv1.FileService_Delete_FullMethodName
+}
+
+// authenticate - хранилище аккаунта по ключу из метаданных кладется в контекст под "bucket", как в ValidateAPI
+func (i *interceptors) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if public(method) {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(apiKeyHeader)
+	if len(keys) == 0 || keys[0] == "" {
+		return nil, newStatus(codes.Unauthenticated, models.ErrCodeAPIKeyRequired, "api key is required")
+	}
+	account := middleware.Authenticate(keys[0], i.pgs, i.rds, i.logger)
+	if account == nil {
+		i.logger.Warn("grpc bad api", "client", clientAddr(ctx), "method", method, "place", tools.GetPlace())
+		return nil, newStatus(codes.Unauthenticated, models.ErrCodeAPIKeyInvalid, "api key is invalid")
+	}
+	if writes(method) && !account.CanWrite() {
+		return nil, newStatus(codes.PermissionDenied, models.ErrCodeForbidden, "api key has no write permission")
+	}
+	ctx = context.WithValue(ctx, "api", keys[0])
+	return context.WithValue(ctx, "bucket", account.StorageId), nil
+}
+
+func clientAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// finish - лог вызова; сбои сервера пишутся с ошибкой, ошибки клиента - нет
+func (i *interceptors) finish(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	switch code {
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+		i.logger.Error("grpc call failed", "method", method, "client", clientAddr(ctx), "code", code.String(),
+			"error", err.Error(), "duration", time.Since(start).String(), "place", tools.GetPlace())
+	default:
+		i.logger.Info("grpc call", "method", method, "client", clientAddr(ctx), "code", code.String(),
+			"duration", time.Since(start).String())
+	}
+}
+
+// recovered - паника обработчика не роняет сервер, клиент получает Internal
+func (i *interceptors) recovered(err *error) {
+	if r := recover(); r != nil {
+		i.logger.Error("grpc panic", "panic", fmt.Sprint(r), "place", tools.GetPlace())
+		*err = newStatus(codes.Internal, models.ErrCodeInternal, "internal server error")
+	}
+}
+
+func (i *interceptors) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (resp any, err error) {
+	start := time.Now()
+	defer func() { i.finish(ctx, info.FullMethod, start, err) }()
+	defer i.recovered(&err)
+	authCtx, err := i.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(authCtx, req)
+}
+
+func (i *interceptors) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	defer func() { i.finish(ss.Context(), info.FullMethod, start, err) }()
+	defer i.recovered(&err)
+	ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// serverStream - поток с контекстом, в который положено хранилище аккаунта
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,75 @@
+package rpc
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/internal/upload"
+	"CloudStorageProject-FileServer/pkg/config"
+	filesv1 "CloudStorageProject-FileServer/pkg/proto/files/v1"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server - gRPC API на отдельном порту: FileService, health и reflection для grpcurl
+type Server struct {
+	Port   string
+	Logger *slog.Logger
+	server *grpc.Server
+	health *health.Server
+}
+
+func NewServer(conf *config.Config, logs *slog.Logger, pgs *postgres.Postgres, rds *redis.Redis,
+	st storage.Storage, spool *upload.Spooler) *Server {
+	chain := &interceptors{pgs: pgs, rds: rds, logger: logs}
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(chain.unary),
+		grpc.StreamInterceptor(chain.stream),
+	)
+	filesv1.RegisterFileServiceServer(server, &fileService{storage: st, spool: spool, rds: rds, logger: logs})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(filesv1.FileService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	return &Server{
+		Port:   conf.GRPCPort,
+		Logger: logs,
+		server: server,
+		health: healthServer,
+	}
+}
+
+func (s *Server) Run() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", s.Port))
+	if err != nil {
+		return err
+	}
+	return s.server.Serve(listener)
+}
+
+// Shutdown - health сразу отвечает NOT_SERVING, открытые вызовы дорабатывают до истечения ctx
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+	finished := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,64 @@
+package sftpd
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"errors"
+	"log/slog"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// storageExtension - хранилище аккаунта в ssh.Permissions, его читает обработчик сессии
+const storageExtension = "storage"
+
+// readOnlyExtension - есть у сессий ключей без права write
+const readOnlyExtension = "read-only"
+
+var errAccessDenied = errors.New("access denied")
+
+// authenticator - вход по API-ключу в качестве пароля или по зарегистрированному публичному ключу.
+// Имя пользователя не проверяется: аккаунт определяет сам ключ
+type authenticator struct {
+	// account - аккаунт по API-ключу, nil - ключа нет или он не действует (middleware.Authenticate)
+	account func(api string) *models.APIPGS
+	// bySSHKey - аккаунт по отпечатку публичного ключа; nil, nil - ключ не зарегистрирован
+	bySSHKey func(fingerprint string) (*models.APIPGS, error)
+	logger   *slog.Logger
+}
+
+// password - ключ проверяется так же, как в ValidateAPI
+func (a *authenticator) password(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	api := string(password)
+	if api == "" {
+		return nil, errAccessDenied
+	}
+	account := a.account(api)
+	if account == nil || !account.Active() {
+		a.logger.Warn("sftp bad api", "client", conn.RemoteAddr().String(), "user", conn.User(),
+			"place", tools.GetPlace())
+		return nil, errAccessDenied
+	}
+	return permissions(account), nil
+}
+
+// publicKey - аккаунт по отпечатку ключа из ssh_keys
+func (a *authenticator) publicKey(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	account, err := a.bySSHKey(ssh.FingerprintSHA256(key))
+	if err != nil {
+		a.logger.Error("sftp public key lookup error", "error", err.Error(), "place", tools.GetPlace())
+		return nil, errAccessDenied
+	}
+	if account == nil || !account.Active() {
+		return nil, errAccessDenied
+	}
+	return permissions(account), nil
+}
+
+func permissions(account *models.APIPGS) *ssh.Permissions {
+	extensions := map[string]string{storageExtension: account.StorageId}
+	if !account.CanWrite() {
+		extensions[readOnlyExtension] = ""
+	}
+	return &ssh.Permissions{Extensions: extensions}
+}
@@ -0,0 +1,112 @@
+package sftpd
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testConn - метаданные SSH-соединения для колбэков входа
+type testConn struct{}
+
+func (testConn) User() string          { return "anyone" }
+func (testConn) SessionID() []byte     { return nil }
+func (testConn) ClientVersion() []byte { return []byte("SSH-2.0-test") }
+func (testConn) ServerVersion() []byte { return []byte("SSH-2.0-test") }
+func (testConn) RemoteAddr() net.Addr  { return &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 50000} }
+func (testConn) LocalAddr() net.Addr   { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2022} }
+
+func testAccount(status string, permissions string) *models.APIPGS {
+	return &models.APIPGS{Id: 1, StorageId: "u-alice", Status: status, Permissions: permissions}
+}
+
+func testPublicKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testAuthenticator(accounts map[string]*models.APIPGS, lookupErr error) *authenticator {
+	return &authenticator{
+		account: func(api string) *models.APIPGS {
+			return accounts[api]
+		},
+		bySSHKey: func(fingerprint string) (*models.APIPGS, error) {
+			return accounts[fingerprint], lookupErr
+		},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestPasswordAuth(t *testing.T) {
+	auth := testAuthenticator(map[string]*models.APIPGS{
+		"active":    testAccount(models.KeyStatusActive, models.DefaultPermissions),
+		"read-only": testAccount(models.KeyStatusActive, models.PermissionRead),
+		"suspended": testAccount(models.KeyStatusSuspended, models.DefaultPermissions),
+		"revoked":   testAccount(models.KeyStatusRevoked, models.DefaultPermissions),
+	}, nil)
+
+	for _, api := range []string{"", "unknown", "suspended", "revoked"} {
+		if perms, err := auth.password(testConn{}, []byte(api)); !errors.Is(err, errAccessDenied) || perms != nil {
+			t.Errorf("password %q: %v, %v; want access denied", api, perms, err)
+		}
+	}
+
+	perms, err := auth.password(testConn{}, []byte("active"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perms.Extensions[storageExtension] != "u-alice" {
+		t.Errorf("storage = %q", perms.Extensions[storageExtension])
+	}
+	if _, readOnly := perms.Extensions[readOnlyExtension]; readOnly {
+		t.Error("read,write key got a read-only session")
+	}
+	perms, err = auth.password(testConn{}, []byte("read-only"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, readOnly := perms.Extensions[readOnlyExtension]; !readOnly {
+		t.Error("read key got a writable session")
+	}
+}
+
+func TestPublicKeyAuth(t *testing.T) {
+	active, suspended, revoked, unknown := testPublicKey(t), testPublicKey(t), testPublicKey(t), testPublicKey(t)
+	accounts := map[string]*models.APIPGS{
+		ssh.FingerprintSHA256(active):    testAccount(models.KeyStatusActive, models.DefaultPermissions),
+		ssh.FingerprintSHA256(suspended): testAccount(models.KeyStatusSuspended, models.DefaultPermissions),
+		ssh.FingerprintSHA256(revoked):   testAccount(models.KeyStatusRevoked, models.DefaultPermissions),
+	}
+	auth := testAuthenticator(accounts, nil)
+
+	for name, key := range map[string]ssh.PublicKey{"unknown": unknown, "suspended": suspended, "revoked": revoked} {
+		if perms, err := auth.publicKey(testConn{}, key); !errors.Is(err, errAccessDenied) || perms != nil {
+			t.Errorf("%s key: %v, %v; want access denied", name, perms, err)
+		}
+	}
+	perms, err := auth.publicKey(testConn{}, active)
+	if err != nil || perms.Extensions[storageExtension] != "u-alice" {
+		t.Errorf("active key: %v, %v", perms, err)
+	}
+
+	// сбой базы не пускает даже зарегистрированный ключ
+	failing := testAuthenticator(accounts, errors.New("connection refused"))
+	if _, err = failing.publicKey(testConn{}, active); !errors.Is(err, errAccessDenied) {
+		t.Errorf("lookup error: %v; want access denied", err)
+	}
+}
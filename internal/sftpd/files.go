@@ -0,0 +1,192 @@
+package sftpd
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	// readWindow - сколько уже прочитанных байт держит streamReader для запросов, пришедших не по порядку
+	readWindow = 8 << 20
+	// readChunk - сколько читать из потока за раз
+	readChunk = 256 << 10
+)
+
+// notExist - ошибки хранилища "нет файла" в виде, который pkg/sftp превращает в SSH_FX_NO_SUCH_FILE
+func notExist(err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return os.ErrNotExist
+	}
+	return err
+}
+
+// openReader - файл на чтение. MinIO и локальный диск отдают файл с произвольным доступом,
+// для остальных драйверов смещения читаются из потока
+func openReader(st storage.Storage, bucket string, key string) (io.ReaderAt, error) {
+	body, _, err := st.GetOne(bucket, key)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	if at, ok := body.(io.ReaderAt); ok {
+		return struct {
+			io.ReaderAt
+			io.Closer
+		}{at, body}, nil
+	}
+	return &streamReader{storage: st, bucket: bucket, key: key, body: body}, nil
+}
+
+// streamReader - ReaderAt поверх потока. Клиент читает файл пачкой запросов вперед, а сервер обрабатывает
+// их параллельно, поэтому смещения приходят почти по порядку: последние байты потока держатся в окне,
+// а поток переоткрывается, только если клиент вернулся назад дальше окна
+type streamReader struct {
+	mu      sync.Mutex
+	storage storage.Storage
+	bucket  string
+	key     string
+	body    io.ReadCloser
+	// window - байты потока с позиции start, уже прочитанные из body
+	window []byte
+	start  int64
+	eof    bool
+}
+
+func (s *streamReader) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if off < s.start {
+		if err := s.reopen(off); err != nil {
+			return 0, err
+		}
+	}
+	end := off + int64(len(p))
+	for !s.eof && s.position() < end {
+		// далеко вперед - пропускаем, не запоминая
+		if gap := off - s.position(); gap > readWindow {
+			skipped, err := io.CopyN(io.Discard, s.body, gap)
+			s.start, s.window = s.position()+skipped, s.window[:0]
+			if err == io.EOF {
+				s.eof = true
+			} else if err != nil {
+				return 0, err
+			}
+			continue
+		}
+		size := len(s.window)
+		s.window = append(s.window, make([]byte, min(end-s.position(), readChunk))...)
+		n, err := io.ReadFull(s.body, s.window[size:])
+		s.window = s.window[:size+n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			s.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+		// запрошенные байты из окна не выбрасываются
+		if drop := min(int64(len(s.window))-readWindow, off-s.start); drop > 0 {
+			s.window = s.window[drop:]
+			s.start += drop
+		}
+	}
+	if off >= s.position() {
+		return 0, io.EOF
+	}
+	n := copy(p, s.window[off-s.start:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// position - смещение следующего байта потока
+func (s *streamReader) position() int64 {
+	return s.start + int64(len(s.window))
+}
+
+func (s *streamReader) reopen(off int64) error {
+	_ = s.body.Close()
+	body, _, err := s.storage.GetOne(s.bucket, s.key)
+	if err != nil {
+		s.body = io.NopCloser(eofReader{})
+		return notExist(err)
+	}
+	s.body, s.start, s.window, s.eof = body, 0, nil, false
+	skipped, err := io.CopyN(io.Discard, body, off)
+	s.start = skipped
+	if err == io.EOF {
+		s.eof = true
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *streamReader) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.body.Close()
+}
+
+type eofReader struct{}
+
+func (eofReader) Read(_ []byte) (int, error) {
+	return 0, io.EOF
+}
+
+// spool - файл на запись. Клиенты шлют блоки параллельно и не по порядку, а хранилище принимает только
+// поток, поэтому блоки собираются во временном файле и уходят в хранилище при закрытии
+type spool struct {
+	file *os.File
+	dst  io.WriteCloser
+	once sync.Once
+	err  error
+}
+
+func newSpool(dir string, dst io.WriteCloser) (*spool, error) {
+	file, err := os.CreateTemp(dir, "sftp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &spool{file: file, dst: dst}, nil
+}
+
+// preload - текущее содержимое файла, поверх которого пойдет запись
+func (s *spool) preload(st storage.Storage, bucket string, key string) error {
+	body, _, err := st.GetOne(bucket, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = body.Close() }()
+	_, err = io.Copy(s.file, body)
+	return err
+}
+
+func (s *spool) WriteAt(p []byte, off int64) (int, error) {
+	return s.file.WriteAt(p, off)
+}
+
+// Close - pkg/sftp закрывает файл и при обрыве соединения: недописанный файл сохраняется,
+// как на обычном SFTP-сервере, и клиент может дозаписать его
+func (s *spool) Close() error {
+	s.once.Do(func() {
+		defer s.discard()
+		if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+			s.err = err
+			return
+		}
+		_, errCopy := io.Copy(s.dst, s.file)
+		s.err = errors.Join(errCopy, s.dst.Close())
+	})
+	return s.err
+}
+
+// discard - удаляет временный файл; в хранилище ничего не попадает, пока не вызван Close
+func (s *spool) discard() {
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
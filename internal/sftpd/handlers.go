@@ -0,0 +1,200 @@
+package sftpd
+
+import (
+	"CloudStorageProject-FileServer/internal/dav"
+	"CloudStorageProject-FileServer/internal/storage"
+	"errors"
+	"io"
+	"os"
+	"path"
+
+	"github.com/pkg/sftp"
+)
+
+var (
+	errIsDirectory = errors.New("is a directory")
+	errNotDir      = errors.New("not a directory")
+	errNotEmpty    = errors.New("directory not empty")
+)
+
+// handlers - операции SFTP над хранилищем аккаунта. Пути, папки и проверка имен - те же, что у WebDAV,
+// поэтому файл, загруженный по SFTP, виден в API и на сетевом диске под тем же ключом
+type handlers struct {
+	fs      *dav.FileSystem
+	storage storage.Storage
+	bucket  string
+	tempDir string
+}
+
+func newHandlers(st storage.Storage, bucket string, tempDir string, readOnly bool) sftp.Handlers {
+	h := &handlers{fs: dav.NewFileSystem(st, bucket), storage: st, bucket: bucket, tempDir: tempDir}
+	if readOnly {
+		return sftp.Handlers{FileGet: h, FilePut: denyWrites{}, FileCmd: denyWrites{}, FileList: h}
+	}
+	return sftp.Handlers{FileGet: h, FilePut: h, FileCmd: h, FileList: h}
+}
+
+// denyWrites - запись и изменение файлов для ключей без права write
+type denyWrites struct{}
+
+func (denyWrites) Filewrite(*sftp.Request) (io.WriterAt, error) {
+	return nil, sftp.ErrSSHFxPermissionDenied
+}
+
+func (denyWrites) Filecmd(*sftp.Request) error {
+	return sftp.ErrSSHFxPermissionDenied
+}
+
+// objectKey - ключ объекта из пути SFTP; пути клиента всегда от корня хранилища
+func objectKey(filepath string) string {
+	return path.Clean("/" + filepath)[1:]
+}
+
+func (h *handlers) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	switch r.Method {
+	case "List":
+		file, err := h.fs.OpenFile(r.Context(), r.Filepath, os.O_RDONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = file.Close() }()
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, errNotDir
+		}
+		children, err := file.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt(children), nil
+	case "Stat":
+		info, err := h.fs.Stat(r.Context(), r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	}
+	// ссылок в хранилище нет
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+func (h *handlers) Filecmd(r *sftp.Request) error {
+	switch r.Method {
+	case "Setstat":
+		// права и время изменения у объектов не хранятся; клиенты выставляют их после загрузки
+		return nil
+	case "Mkdir":
+		return h.fs.Mkdir(r.Context(), r.Filepath, 0)
+	case "Remove":
+		info, err := h.fs.Stat(r.Context(), r.Filepath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return errIsDirectory
+		}
+		return h.fs.RemoveAll(r.Context(), r.Filepath)
+	case "Rmdir":
+		return h.rmdir(r)
+	case "Rename":
+		// по протоколу SFTP v3 существующий файл не перезаписывается
+		if _, err := h.fs.Stat(r.Context(), r.Target); err == nil {
+			return os.ErrExist
+		}
+		return h.fs.Rename(r.Context(), r.Filepath, r.Target)
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// PosixRename - rename с заменой файла (posix-rename@openssh.com), им OpenSSH и rclone завершают загрузку
+// во временный файл. Атомарной замены в хранилище нет: старый файл удаляется перед переименованием
+func (h *handlers) PosixRename(r *sftp.Request) error {
+	if _, err := h.fs.Stat(r.Context(), r.Filepath); err != nil {
+		return err
+	}
+	info, err := h.fs.Stat(r.Context(), r.Target)
+	if err == nil && info.IsDir() {
+		return os.ErrExist
+	}
+	if err == nil {
+		if err = h.fs.RemoveAll(r.Context(), r.Target); err != nil {
+			return err
+		}
+	}
+	return h.fs.Rename(r.Context(), r.Filepath, r.Target)
+}
+
+// rmdir - удаляется только пустая папка, как в POSIX; рекурсивное удаление клиенты делают сами
+func (h *handlers) rmdir(r *sftp.Request) error {
+	key := objectKey(r.Filepath)
+	if key == "" {
+		return os.ErrPermission
+	}
+	info, err := h.fs.Stat(r.Context(), r.Filepath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errNotDir
+	}
+	children, err := h.storage.List(h.bucket, key+"/", false)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	for _, child := range children {
+		if child.Key != key+"/" {
+			return errNotEmpty
+		}
+	}
+	return h.fs.RemoveAll(r.Context(), r.Filepath)
+}
+
+func (h *handlers) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	info, err := h.fs.Stat(r.Context(), r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errIsDirectory
+	}
+	return openReader(h.storage, h.bucket, objectKey(r.Filepath))
+}
+
+// Filewrite - файл появляется в хранилище целиком, когда клиент закрывает его
+func (h *handlers) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	// имя и родительская папка проверяются до того, как клиент начнет передавать данные
+	dst, err := h.fs.OpenFile(r.Context(), r.Filepath, os.O_WRONLY|os.O_CREATE, 0)
+	if err != nil {
+		return nil, err
+	}
+	file, err := newSpool(h.tempDir, dst)
+	if err != nil {
+		_ = dst.Close()
+		return nil, err
+	}
+	// без O_TRUNC запись идет поверх текущего содержимого: так работают дозапись (reput) и правка на месте
+	if !r.Pflags().Trunc {
+		if err = file.preload(h.storage, h.bucket, objectKey(r.Filepath)); err != nil {
+			file.discard()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// listerAt - готовый список для ответа на List и Stat
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(dst []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(dst, l[offset:])
+	if n < len(dst) {
+		return n, io.EOF
+	}
+	return n, nil
+}
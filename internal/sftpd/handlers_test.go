@@ -0,0 +1,110 @@
+package sftpd
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/internal/storage/local"
+	"CloudStorageProject-FileServer/pkg/models"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// testSession - SFTP-сессия хранилища u-attacker. Драйвер local: хранилища - соседние папки на диске,
+// и путь с ".." без проверки действительно вышел бы в u-victim
+func testSession(t *testing.T) (*sftp.Client, storage.Storage) {
+	t.Helper()
+	st, err := local.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for bucket, name := range map[string]string{"u-victim": "secret.txt", "u-attacker": "own.txt"} {
+		err = st.CreateOne(bucket, models.FileMinio{FileName: name, Size: 4, Reader: strings.NewReader("data")})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	serverConn, clientConn := net.Pipe()
+	server := sftp.NewRequestServer(serverConn, newHandlers(st, "u-attacker", t.TempDir(), false))
+	go func() { _ = server.Serve() }()
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client, st
+}
+
+func TestSessionStaysInBucket(t *testing.T) {
+	client, st := testSession(t)
+	paths := []string{"../u-victim/secret.txt", "/../u-victim/secret.txt", "../../u-victim/secret.txt",
+		"dir/../../u-victim/secret.txt", "./../u-victim/secret.txt", `..\u-victim\secret.txt`}
+
+	for _, p := range paths {
+		if file, err := client.Open(p); err == nil {
+			data, _ := io.ReadAll(file)
+			_ = file.Close()
+			t.Errorf("open %s: read %q from outside the bucket", p, data)
+		}
+		if info, err := client.Stat(p); err == nil {
+			t.Errorf("stat %s: %s is visible", p, info.Name())
+		}
+		if file, err := client.Create(p); err == nil {
+			_, _ = file.Write([]byte("owned"))
+			_ = file.Close()
+		}
+		_ = client.Remove(p)
+		_ = client.Rename("own.txt", p)
+		_ = client.PosixRename("own.txt", p)
+	}
+	for _, dir := range []string{"..", "/..", "../..", "../u-victim"} {
+		entries, err := client.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Name() == "u-victim" || entry.Name() == "secret.txt" {
+				t.Errorf("readdir %s: lists %s", dir, entry.Name())
+			}
+		}
+	}
+
+	objects, err := st.List("u-victim", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Key != "secret.txt" || objects[0].Size != 4 {
+		t.Errorf("victim bucket changed: %+v", objects)
+	}
+	reader, _, err := st.GetOne("u-victim", "secret.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = reader.Close() }()
+	if data, _ := io.ReadAll(reader); string(data) != "data" {
+		t.Errorf("victim file overwritten: %q", data)
+	}
+}
+
+func TestSessionRootIsBucket(t *testing.T) {
+	client, _ := testSession(t)
+	// выше корня подняться нельзя: ".." от корня - снова корень хранилища
+	for _, dir := range []string{"/", "..", "/../.."} {
+		entries, err := client.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("readdir %s: %v", dir, err)
+		}
+		if len(entries) != 1 || entries[0].Name() != "own.txt" {
+			names := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			t.Errorf("readdir %s = %v, want [own.txt]", dir, names)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package sftpd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// loadHostKey - ключ хоста из файла. Если файла нет, ключ создается и сохраняется, чтобы клиенты
+// не видели смену ключа после перезапуска. Пустой путь - ключ только на время работы процесса
+func loadHostKey(path string) (signer ssh.Signer, generated bool, err error) {
+	if path != "" {
+		data, errRead := os.ReadFile(path)
+		if errRead == nil {
+			signer, err = ssh.ParsePrivateKey(data)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to parse sftp host key %s: %w", path, err)
+			}
+			return signer, false, nil
+		}
+		if !errors.Is(errRead, os.ErrNotExist) {
+			return nil, false, fmt.Errorf("failed to read sftp host key: %w", errRead)
+		}
+	}
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to generate sftp host key: %w", err)
+	}
+	signer, err = ssh.NewSignerFromKey(private)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create sftp host key: %w", err)
+	}
+	if path == "" {
+		return signer, true, nil
+	}
+	block, err := ssh.MarshalPrivateKey(private, "cloud storage sftp host key")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode sftp host key: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, false, fmt.Errorf("failed to save sftp host key: %w", err)
+	}
+	if err = os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		return nil, false, fmt.Errorf("failed to save sftp host key: %w", err)
+	}
+	return signer, true, nil
+}
@@ -0,0 +1,215 @@
+package sftpd
+
+import (
+	"CloudStorageProject-FileServer/internal/database/postgres"
+	"CloudStorageProject-FileServer/internal/database/redis"
+	"CloudStorageProject-FileServer/internal/middleware"
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/config"
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// handshakeTimeout - сколько ждать завершения входа, чтобы брошенные соединения не занимали сервер
+const handshakeTimeout = 30 * time.Second
+
+// Server - SFTP на отдельном порту. Хранилище то же, что у основного сервера, поэтому загрузки
+// попадают в журнал изменений и реплику. Разрешена только подсистема sftp: ни shell, ни проброса портов
+type Server struct {
+	Port     string
+	Logger   *slog.Logger
+	config   *ssh.ServerConfig
+	storage  storage.Storage
+	tempDir  string
+	listener net.Listener
+
+	mu          sync.Mutex
+	conns       map[net.Conn]struct{}
+	closed      bool
+	connections sync.WaitGroup
+}
+
+// NewServer - generated сообщает, что ключ хоста создан заново
+func NewServer(conf *config.Config, logs *slog.Logger, pgs *postgres.Postgres, rds *redis.Redis,
+	st storage.Storage) (server *Server, generated bool, err error) {
+	hostKey, generated, err := loadHostKey(conf.SFTPHostKey)
+	if err != nil {
+		return nil, false, err
+	}
+	auth := &authenticator{
+		account: func(api string) *models.APIPGS {
+			return middleware.Authenticate(api, pgs, rds, logs)
+		},
+		bySSHKey: pgs.AccountBySSHKey,
+		logger:   logs,
+	}
+	sshConfig := &ssh.ServerConfig{
+		PasswordCallback:  auth.password,
+		PublicKeyCallback: auth.publicKey,
+		MaxAuthTries:      6,
+	}
+	sshConfig.AddHostKey(hostKey)
+	return &Server{
+		Port:    conf.SFTPPort,
+		Logger:  logs,
+		config:  sshConfig,
+		storage: st,
+		tempDir: conf.UploadTempDir,
+		conns:   make(map[net.Conn]struct{}),
+	}, generated, nil
+}
+
+func (s *Server) Run() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", s.Port))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = listener.Close()
+		return nil
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, errAccept := listener.Accept()
+		if errAccept != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return errAccept
+		}
+		if !s.track(conn) {
+			_ = conn.Close()
+			continue
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// track - запоминает соединение, чтобы Shutdown мог его дождаться или закрыть
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.connections.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	s.connections.Done()
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.untrack(conn)
+	defer func() { _ = conn.Close() }()
+	defer func() {
+		if r := recover(); r != nil {
+			s.Logger.Error("sftp connection panic", "panic", fmt.Sprint(r), "place", tools.GetPlace())
+		}
+	}()
+
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		// сканеры портов и неверные ключи - не ошибка сервера
+		s.Logger.Debug("sftp handshake failed", "client", conn.RemoteAddr().String(), "error", err.Error())
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+	defer func() { _ = sshConn.Close() }()
+	bucket := sshConn.Permissions.Extensions[storageExtension]
+	_, readOnly := sshConn.Permissions.Extensions[readOnlyExtension]
+	s.Logger.Info("sftp session started", "client", conn.RemoteAddr().String(), "user", sshConn.User(),
+		"bucket", bucket, "read_only", readOnly)
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, channelRequests, errAccept := newChannel.Accept()
+		if errAccept != nil {
+			s.Logger.Warn("sftp channel accept error", "error", errAccept.Error(), "place", tools.GetPlace())
+			continue
+		}
+		go s.serveChannel(channel, channelRequests, bucket, readOnly)
+	}
+	s.Logger.Info("sftp session finished", "client", conn.RemoteAddr().String(), "bucket", bucket)
+}
+
+// serveChannel - ждет запрос подсистемы sftp и обслуживает ее до закрытия канала
+func (s *Server) serveChannel(channel ssh.Channel, requests <-chan *ssh.Request, bucket string, readOnly bool) {
+	defer func() { _ = channel.Close() }()
+	for req := range requests {
+		// полезная нагрузка subsystem - строка в формате SSH: длина и имя
+		isSFTP := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+		if req.WantReply {
+			_ = req.Reply(isSFTP, nil)
+		}
+		if !isSFTP {
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		server := sftp.NewRequestServer(channel, newHandlers(s.storage, bucket, s.tempDir, readOnly))
+		if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			s.Logger.Warn("sftp session error", "bucket", bucket, "error", err.Error(), "place", tools.GetPlace())
+		}
+		_ = server.Close()
+		return
+	}
+}
+
+// Shutdown - перестает принимать соединения и ждет открытые сессии; по истечении ctx закрывает их
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	s.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		s.connections.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			_ = conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,402 @@
+package local
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// metaDir - каталог в корне, где лежат метаданные объектов (content-type, etag, теги).
+// Имя начинается с точки, поэтому не пересекается с именами бакетов
+const metaDir = ".meta"
+
+// meta - метаданные объекта, хранятся рядом в отдельном дереве
+type meta struct {
+	ContentType string            `json:"content_type"`
+	ETag        string            `json:"etag"`
+	Sha256      string            `json:"sha256,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+// Local - драйвер хранилища на локальной файловой системе: бакет - каталог, ключ - путь внутри него
+type Local struct {
+	root string
+}
+
+func NewLocal(root string) (*Local, error) {
+	if err := os.MkdirAll(filepath.Join(root, metaDir), 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{root: root}, nil
+}
+
+// validBucket - имя бакета - один каталог в корне; имена с точки заняты служебными каталогами
+func validBucket(bucket string) bool {
+	return bucket != "" && !strings.ContainsAny(bucket, `/\`) && !strings.HasPrefix(bucket, ".")
+}
+
+// paths - путь к файлу и к его метаданным
+func (l *Local) paths(bucket string, objectName string) (string, string, error) {
+	if !validBucket(bucket) {
+		return "", "", storage.ErrInvalidObjectName
+	}
+	key, err := storage.CleanObjectName(objectName)
+	if err != nil {
+		return "", "", err
+	}
+	file := filepath.Join(l.root, bucket, filepath.FromSlash(key))
+	metaFile := filepath.Join(l.root, metaDir, bucket, filepath.FromSlash(key)+".json")
+	return file, metaFile, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
+func (l *Local) readMeta(metaFile string) meta {
+	var m meta
+	data, err := os.ReadFile(metaFile)
+	if err == nil {
+		_ = json.Unmarshal(data, &m)
+	}
+	return m
+}
+
+func (l *Local) writeMeta(metaFile string, m meta) error {
+	if err := os.MkdirAll(filepath.Dir(metaFile), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaFile, data, 0o644)
+}
+
+func (l *Local) info(key string, stat os.FileInfo, m meta) *models.ObjectInfo {
+	contentType := m.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &models.ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  contentType,
+		ETag:         m.ETag,
+		LastModified: stat.ModTime(),
+		Checksum:     m.Sha256,
+	}
+}
+
+// CreateOne - пишет во временный файл и атомарно переименовывает, чтобы читатели не видели половину файла
+func (l *Local) CreateOne(bucket string, file models.FileMinio) error {
+	target, metaFile, err := l.paths(bucket, file.FileName)
+	if err != nil {
+		return err
+	}
+	// маркер пустой папки (ключ с "/" в конце) на диске - обычный каталог
+	if strings.HasSuffix(file.FileName, "/") {
+		return os.MkdirAll(target, 0o755)
+	}
+	if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	hash := md5.New()
+	checksum := sha256.New()
+	reader := file.Reader
+	if reader == nil {
+		reader = strings.NewReader(string(file.Data))
+	}
+	if _, err = io.Copy(io.MultiWriter(tmp, hash, checksum), reader); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+	return l.writeMeta(metaFile, meta{
+		ContentType: file.ContentType,
+		ETag:        hex.EncodeToString(hash.Sum(nil)),
+		Sha256:      hex.EncodeToString(checksum.Sum(nil)),
+	})
+}
+
+func (l *Local) GetOne(bucket string, objectName string) (io.ReadCloser, *models.ObjectInfo, error) {
+	file, metaFile, err := l.paths(bucket, objectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		_ = f.Close()
+		return nil, nil, storage.ErrNotFound
+	}
+	key, _ := storage.CleanObjectName(objectName)
+	return f, l.info(key, stat, l.readMeta(metaFile)), nil
+}
+
+func (l *Local) Stat(bucket string, objectName string) (*models.ObjectInfo, error) {
+	file, metaFile, err := l.paths(bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	key, _ := storage.CleanObjectName(objectName)
+	if strings.HasSuffix(objectName, "/") != stat.IsDir() {
+		return nil, storage.ErrNotFound
+	}
+	if stat.IsDir() {
+		return &models.ObjectInfo{Key: key + "/", LastModified: stat.ModTime(), IsDir: true}, nil
+	}
+	return l.info(key, stat, l.readMeta(metaFile)), nil
+}
+
+func (l *Local) List(bucket string, prefix string, recursive bool) ([]models.ObjectInfo, error) {
+	if !validBucket(bucket) {
+		return nil, storage.ErrInvalidObjectName
+	}
+	if err := storage.ValidatePrefix(prefix); err != nil {
+		return nil, err
+	}
+	objects := []models.ObjectInfo{}
+	bucketDir := filepath.Join(l.root, bucket)
+	if _, err := os.Stat(bucketDir); err != nil {
+		// пустое хранилище пользователя, который еще ничего не загружал
+		if errors.Is(err, fs.ErrNotExist) {
+			return objects, nil
+		}
+		return nil, err
+	}
+
+	// обходим только каталог, в котором может начинаться префикс
+	startDir := bucketDir
+	if dir := path.Dir(prefix); prefix != "" && dir != "." {
+		startDir = filepath.Join(bucketDir, filepath.FromSlash(dir))
+	}
+	// префикс уже проверен, но обход за пределами бакета открыл бы чужие файлы - проверяем и сам путь
+	if rel, err := filepath.Rel(bucketDir, startDir); err != nil || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, storage.ErrInvalidObjectName
+	}
+	err := filepath.WalkDir(startDir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if file == bucketDir {
+			return nil
+		}
+		rel, _ := filepath.Rel(bucketDir, file)
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+		if entry.IsDir() {
+			key += "/"
+			// каталог, ведущий к префиксу (или равный ему)
+			if strings.HasPrefix(prefix, key) {
+				return nil
+			}
+			// каталог вне префикса пропускаем целиком
+			if !strings.HasPrefix(key, prefix) {
+				return fs.SkipDir
+			}
+			if !recursive {
+				objects = append(objects, models.ObjectInfo{Key: key, IsDir: true})
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		stat, errStat := entry.Info()
+		if errStat != nil {
+			return errStat
+		}
+		_, metaFile, _ := l.paths(bucket, key)
+		objects = append(objects, *l.info(key, stat, l.readMeta(metaFile)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (l *Local) Delete(bucket string, objectName string) error {
+	file, metaFile, err := l.paths(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(objectName, "/") {
+		return l.deleteDir(bucket, objectName, file)
+	}
+	if err = os.Remove(file); err != nil {
+		return notFound(err)
+	}
+	_ = os.Remove(metaFile)
+	l.removeEmptyDirs(filepath.Join(l.root, bucket), filepath.Dir(file))
+	return nil
+}
+
+// deleteDir - маркер папки: каталог удаляется, только если в нем не осталось файлов
+func (l *Local) deleteDir(bucket string, objectName string, dir string) error {
+	if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+		return storage.ErrNotFound
+	}
+	key, _ := storage.CleanObjectName(objectName)
+	files, err := l.List(bucket, key+"/", true)
+	if err != nil {
+		return err
+	}
+	if len(files) > 0 {
+		return errors.New("folder is not empty")
+	}
+	return os.RemoveAll(dir)
+}
+
+// removeEmptyDirs - убирает опустевшие "папки", как это происходит с префиксами в S3
+func (l *Local) removeEmptyDirs(bucketDir string, dir string) {
+	for dir != bucketDir && strings.HasPrefix(dir, bucketDir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func (l *Local) Copy(bucket string, objectName string, newObjectName string) error {
+	reader, info, err := l.GetOne(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+	return l.CreateOne(bucket, models.FileMinio{
+		FileName:    newObjectName,
+		Reader:      reader,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+	})
+}
+
+func (l *Local) Rename(bucket string, objectName string, newObjectName string) error {
+	file, metaFile, err := l.paths(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	newFile, newMetaFile, err := l.paths(bucket, newObjectName)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(file); err != nil {
+		return notFound(err)
+	}
+	if err = os.MkdirAll(filepath.Dir(newFile), 0o755); err != nil {
+		return err
+	}
+	if err = os.Rename(file, newFile); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(newMetaFile), 0o755); err == nil {
+		_ = os.Rename(metaFile, newMetaFile)
+	}
+	l.removeEmptyDirs(filepath.Join(l.root, bucket), filepath.Dir(file))
+	return nil
+}
+
+func (l *Local) ObjectTags(bucket string, objectName string) (map[string]string, error) {
+	_, metaFile, err := l.paths(bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return l.readMeta(metaFile).Tags, nil
+}
+
+func (l *Local) CloseConnection(_ context.Context) error {
+	return nil
+}
+
+// EnsureBucket - создает каталог хранилища. Политики, версии и квоты на диске не поддерживаются
+func (l *Local) EnsureBucket(bucket string) (bool, error) {
+	if !validBucket(bucket) {
+		return false, storage.ErrInvalidObjectName
+	}
+	dir := filepath.Join(l.root, bucket)
+	if _, err := os.Stat(dir); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Buckets - каталоги в корне, кроме служебных
+func (l *Local) Buckets() ([]string, error) {
+	entries, err := os.ReadDir(l.root)
+	if err != nil {
+		return nil, err
+	}
+	buckets := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			buckets = append(buckets, entry.Name())
+		}
+	}
+	return buckets, nil
+}
+
+// RemoveBucket - удаляет пустой каталог хранилища и его метаданные
+func (l *Local) RemoveBucket(bucket string) error {
+	if !validBucket(bucket) {
+		return storage.ErrInvalidObjectName
+	}
+	if err := os.Remove(filepath.Join(l.root, bucket)); err != nil {
+		return notFound(err)
+	}
+	return os.RemoveAll(filepath.Join(l.root, metaDir, bucket))
+}
+
+var (
+	_ storage.Storage     = (*Local)(nil)
+	_ storage.Provisioner = (*Local)(nil)
+)
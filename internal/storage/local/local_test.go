@@ -0,0 +1,78 @@
+package local
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestLocal(t *testing.T) *Local {
+	t.Helper()
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func put(t *testing.T, l *Local, bucket string, name string) {
+	t.Helper()
+	err := l.CreateOne(bucket, models.FileMinio{FileName: name, Size: 4, Reader: strings.NewReader("data")})
+	if err != nil {
+		t.Fatalf("put %s/%s: %v", bucket, name, err)
+	}
+}
+
+func TestListDoesNotLeaveBucket(t *testing.T) {
+	l := newTestLocal(t)
+	put(t, l, "u-victim", "secret/passwords.txt")
+	put(t, l, "u-attacker", "own.txt")
+
+	for _, prefix := range []string{"../", "..", "../u-victim/", "a/../../", "/", "/u-victim/", `..\u-victim\`, "./", "a/./b"} {
+		objects, err := l.List("u-attacker", prefix, true)
+		if !errors.Is(err, storage.ErrInvalidObjectName) {
+			t.Errorf("List(%q) = %v, %v; want ErrInvalidObjectName", prefix, objects, err)
+		}
+	}
+	for _, bucket := range []string{"..", "../u-victim", ".meta", ""} {
+		if _, err := l.List(bucket, "", true); !errors.Is(err, storage.ErrInvalidObjectName) {
+			t.Errorf("List bucket %q: err = %v, want ErrInvalidObjectName", bucket, err)
+		}
+	}
+}
+
+func TestListPrefix(t *testing.T) {
+	l := newTestLocal(t)
+	put(t, l, "u-a", "photos/2024/a.png")
+	put(t, l, "u-a", "photos/b.png")
+	put(t, l, "u-a", "docs/c.txt")
+	put(t, l, "u-a", ".bashrc")
+
+	tests := []struct {
+		prefix    string
+		recursive bool
+		want      []string
+	}{
+		{"", true, []string{".bashrc", "docs/c.txt", "photos/2024/a.png", "photos/b.png"}},
+		{"", false, []string{".bashrc", "docs/", "photos/"}},
+		{"photos/", false, []string{"photos/2024/", "photos/b.png"}},
+		{"photos/2", true, []string{"photos/2024/a.png"}},
+		{".ba", true, []string{".bashrc"}},
+		{"missing/", true, []string{}},
+	}
+	for _, tt := range tests {
+		objects, err := l.List("u-a", tt.prefix, tt.recursive)
+		if err != nil {
+			t.Fatalf("List(%q): %v", tt.prefix, err)
+		}
+		keys := []string{}
+		for _, obj := range objects {
+			keys = append(keys, obj.Key)
+		}
+		if strings.Join(keys, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("List(%q, %v) = %v, want %v", tt.prefix, tt.recursive, keys, tt.want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package memory
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type object struct {
+	data         []byte
+	contentType  string
+	etag         string
+	sha256       string
+	lastModified time.Time
+	tags         map[string]string
+}
+
+// Memory - драйвер хранилища в памяти процесса. Данные пропадают при перезапуске,
+// нужен для локальной разработки и тестов обработчиков
+type Memory struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string]*object
+	uploads map[string]*upload
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]map[string]*object),
+		uploads: make(map[string]*upload),
+	}
+}
+
+// objectKey - ключ объекта; "/" в конце сохраняется: как в S3, это маркер пустой папки
+func objectKey(objectName string) (string, error) {
+	key, err := storage.CleanObjectName(objectName)
+	if err == nil && strings.HasSuffix(objectName, "/") {
+		key += "/"
+	}
+	return key, err
+}
+
+func (m *Memory) CreateOne(bucket string, file models.FileMinio) error {
+	key, err := objectKey(file.FileName)
+	if err != nil {
+		return err
+	}
+	data := file.Data
+	if file.Reader != nil {
+		if data, err = io.ReadAll(file.Reader); err != nil {
+			return err
+		}
+	}
+	sum := md5.Sum(data)
+	checksum := sha256.Sum256(data)
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.buckets[bucket] == nil {
+		m.buckets[bucket] = make(map[string]*object)
+	}
+	m.buckets[bucket][key] = &object{
+		data:         data,
+		contentType:  contentType,
+		etag:         hex.EncodeToString(sum[:]),
+		sha256:       hex.EncodeToString(checksum[:]),
+		lastModified: time.Now(),
+	}
+	return nil
+}
+
+// get - объект под read-блокировкой
+func (m *Memory) get(bucket string, objectName string) (string, *object, error) {
+	key, err := objectKey(objectName)
+	if err != nil {
+		return "", nil, err
+	}
+	obj, ok := m.buckets[bucket][key]
+	if !ok {
+		return "", nil, storage.ErrNotFound
+	}
+	return key, obj, nil
+}
+
+func info(key string, obj *object) *models.ObjectInfo {
+	return &models.ObjectInfo{
+		Key:          key,
+		Size:         int64(len(obj.data)),
+		ContentType:  obj.contentType,
+		ETag:         obj.etag,
+		LastModified: obj.lastModified,
+		IsDir:        strings.HasSuffix(key, "/"),
+		Checksum:     obj.sha256,
+	}
+}
+
+func (m *Memory) GetOne(bucket string, objectName string) (io.ReadCloser, *models.ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, obj, err := m.get(bucket, objectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	// данные объекта не меняются после записи (перезапись создает новый object), поэтому копия не нужна
+	return io.NopCloser(bytes.NewReader(obj.data)), info(key, obj), nil
+}
+
+func (m *Memory) Stat(bucket string, objectName string) (*models.ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, obj, err := m.get(bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return info(key, obj), nil
+}
+
+func (m *Memory) List(bucket string, prefix string, recursive bool) ([]models.ObjectInfo, error) {
+	if err := storage.ValidatePrefix(prefix); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	objects := []models.ObjectInfo{}
+	dirs := make(map[string]bool)
+	for key, obj := range m.buckets[bucket] {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if !recursive {
+			// как в S3: всё после первого "/" за префиксом сворачивается в одну "папку"
+			if i := strings.Index(key[len(prefix):], "/"); i >= 0 {
+				dir := key[:len(prefix)+i+1]
+				if !dirs[dir] {
+					dirs[dir] = true
+					objects = append(objects, models.ObjectInfo{Key: dir, IsDir: true})
+				}
+				continue
+			}
+		}
+		objects = append(objects, *info(key, obj))
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (m *Memory) Delete(bucket string, objectName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, _, err := m.get(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	delete(m.buckets[bucket], key)
+	return nil
+}
+
+func (m *Memory) Copy(bucket string, objectName string, newObjectName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, obj, err := m.get(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	newKey, err := objectKey(newObjectName)
+	if err != nil {
+		return err
+	}
+	copied := *obj
+	copied.lastModified = time.Now()
+	copied.tags = maps.Clone(obj.tags)
+	m.buckets[bucket][newKey] = &copied
+	return nil
+}
+
+func (m *Memory) Rename(bucket string, objectName string, newObjectName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, obj, err := m.get(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	newKey, err := objectKey(newObjectName)
+	if err != nil {
+		return err
+	}
+	delete(m.buckets[bucket], key)
+	m.buckets[bucket][newKey] = obj
+	return nil
+}
+
+func (m *Memory) ObjectTags(bucket string, objectName string) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, obj, err := m.get(bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return maps.Clone(obj.tags), nil
+}
+
+func (m *Memory) CloseConnection(_ context.Context) error {
+	return nil
+}
+
+func (m *Memory) EnsureBucket(bucket string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.buckets[bucket]; ok {
+		return false, nil
+	}
+	m.buckets[bucket] = make(map[string]*object)
+	return true, nil
+}
+
+func (m *Memory) Buckets() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	buckets := slices.Sorted(maps.Keys(m.buckets))
+	return buckets, nil
+}
+
+func (m *Memory) RemoveBucket(bucket string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	objects, ok := m.buckets[bucket]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if len(objects) > 0 {
+		return errors.New("bucket is not empty")
+	}
+	delete(m.buckets, bucket)
+	return nil
+}
+
+var (
+	_ storage.Storage     = (*Memory)(nil)
+	_ storage.Provisioner = (*Memory)(nil)
+)
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// upload - незавершенная составная загрузка
+type upload struct {
+	bucket      string
+	key         string
+	contentType string
+	parts       map[int][]byte
+}
+
+func (m *Memory) NewMultipartUpload(bucket string, objectName string, contentType string) (string, error) {
+	key, err := objectKey(objectName)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	id := hex.EncodeToString(buf)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uploads[id] = &upload{bucket: bucket, key: key, contentType: contentType, parts: make(map[int][]byte)}
+	return id, nil
+}
+
+// findUpload - загрузка под блокировкой, принадлежащая этому объекту
+func (m *Memory) findUpload(bucket string, objectName string, uploadId string) (*upload, error) {
+	key, err := objectKey(objectName)
+	if err != nil {
+		return nil, err
+	}
+	up, ok := m.uploads[uploadId]
+	if !ok || up.bucket != bucket || up.key != key {
+		return nil, storage.ErrNoSuchUpload
+	}
+	return up, nil
+}
+
+func (m *Memory) PutObjectPart(bucket string, objectName string, uploadId string, partNumber int,
+	reader io.Reader, _ int64) (string, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	up, err := m.findUpload(bucket, objectName, uploadId)
+	if err != nil {
+		return "", err
+	}
+	up.parts[partNumber] = data
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (m *Memory) CompleteMultipartUpload(bucket string, objectName string, uploadId string,
+	parts []models.CompletedPart) (string, error) {
+	m.mu.Lock()
+	up, err := m.findUpload(bucket, objectName, uploadId)
+	if err != nil {
+		m.mu.Unlock()
+		return "", err
+	}
+	var body bytes.Buffer
+	for i, part := range parts {
+		data, ok := up.parts[part.PartNumber]
+		sum := md5.Sum(data)
+		if !ok || (i > 0 && part.PartNumber <= parts[i-1].PartNumber) ||
+			strings.Trim(part.ETag, `"`) != hex.EncodeToString(sum[:]) {
+			m.mu.Unlock()
+			return "", storage.ErrInvalidPart
+		}
+		body.Write(data)
+	}
+	delete(m.uploads, uploadId)
+	m.mu.Unlock()
+
+	if err = m.CreateOne(bucket, models.FileMinio{FileName: up.key, Data: body.Bytes(), ContentType: up.contentType}); err != nil {
+		return "", err
+	}
+	// в памяти собранный объект - обычный файл, его ETag - md5 содержимого
+	info, err := m.Stat(bucket, up.key)
+	if err != nil {
+		return "", err
+	}
+	return info.ETag, nil
+}
+
+func (m *Memory) AbortMultipartUpload(bucket string, objectName string, uploadId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.findUpload(bucket, objectName, uploadId); err != nil {
+		return err
+	}
+	delete(m.uploads, uploadId)
+	return nil
+}
+
+var _ storage.Multiparter = (*Memory)(nil)
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxObjectNameLength - предел длины ключа объекта в S3, в байтах
+	MaxObjectNameLength = 1024
+	// MaxSegmentLength - предел длины одного имени в пути, как у большинства файловых систем
+	MaxSegmentLength = 255
+	// maxRenameAttempts - сколько вариантов "name (N).ext" перебираем, прежде чем сдаться
+	maxRenameAttempts = 1000
+)
+
+// Политики загрузки файла, имя которого уже занято
+const (
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+	ConflictFail      = "fail"
+)
+
+// ErrObjectExists - файл уже есть, а политика загрузки запрещает его перезаписывать
+var ErrObjectExists = errors.New("object already exists")
+
+// NameError - причина, по которой имя файла отклонено. errors.Is(err, ErrInvalidObjectName) для нее true
+type NameError struct {
+	Name   string
+	Reason string
+}
+
+func (e *NameError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidObjectName.Error(), e.Reason)
+}
+
+func (e *NameError) Is(target error) bool {
+	return target == ErrInvalidObjectName
+}
+
+// SanitizeObjectName - приводит имя файла от клиента к ключу объекта: Unicode NFC, разделитель "/",
+// без ведущего слеша. Имена с "..", управляющими символами или сверх пределов длины отклоняются
+func SanitizeObjectName(name string) (string, error) {
+	if !utf8.ValidString(name) {
+		return "", &NameError{Name: name, Reason: "name is not valid UTF-8"}
+	}
+	// одинаково выглядящие имена в NFC и NFD должны быть одним файлом
+	name = norm.NFC.String(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimLeft(name, "/")
+	if name == "" {
+		return "", &NameError{Name: name, Reason: "name is empty"}
+	}
+	if len(name) > MaxObjectNameLength {
+		return "", &NameError{Name: name, Reason: fmt.Sprintf("name is longer than %d bytes", MaxObjectNameLength)}
+	}
+	for _, c := range name {
+		if unsafeRune(c) {
+			return "", &NameError{Name: name, Reason: fmt.Sprintf("name contains forbidden character %U", c)}
+		}
+	}
+	for _, segment := range strings.Split(name, "/") {
+		switch {
+		case segment == "":
+			return "", &NameError{Name: name, Reason: "name contains an empty path segment"}
+		case segment == "." || segment == "..":
+			return "", &NameError{Name: name, Reason: "name contains a relative path segment"}
+		case strings.TrimSpace(segment) != segment:
+			return "", &NameError{Name: name, Reason: "path segment starts or ends with a space"}
+		case len(segment) > MaxSegmentLength:
+			return "", &NameError{Name: name, Reason: fmt.Sprintf("path segment is longer than %d bytes", MaxSegmentLength)}
+		}
+	}
+	return name, nil
+}
+
+// ValidatePrefix - проверяет префикс для List. Пустой префикс - все хранилище. Префикс сравнивается с ключами
+// как строка, но локальный драйвер строит по нему путь, поэтому ведущий "/", "\\", сегменты "." и ".."
+// и управляющие символы отклоняются у всех драйверов одинаково
+func ValidatePrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if !utf8.ValidString(prefix) {
+		return &NameError{Name: prefix, Reason: "prefix is not valid UTF-8"}
+	}
+	if len(prefix) > MaxObjectNameLength {
+		return &NameError{Name: prefix, Reason: fmt.Sprintf("prefix is longer than %d bytes", MaxObjectNameLength)}
+	}
+	if strings.HasPrefix(prefix, "/") || strings.Contains(prefix, "\\") {
+		return &NameError{Name: prefix, Reason: "prefix must be relative and use / as a separator"}
+	}
+	for _, c := range prefix {
+		if unsafeRune(c) {
+			return &NameError{Name: prefix, Reason: fmt.Sprintf("prefix contains forbidden character %U", c)}
+		}
+	}
+	for _, segment := range strings.Split(prefix, "/") {
+		if segment == "." || segment == ".." {
+			return &NameError{Name: prefix, Reason: "prefix contains a relative path segment"}
+		}
+	}
+	return nil
+}
+
+// unsafeRune - управляющие символы и символы смены направления текста, которыми подделывают расширение
+func unsafeRune(c rune) bool {
+	if unicode.IsControl(c) || c == utf8.RuneError {
+		return true
+	}
+	switch {
+	case c >= '\u202a' && c <= '\u202e', c >= '\u2066' && c <= '\u2069', c == '\u200e', c == '\u200f':
+		return true
+	}
+	return false
+}
+
+// ValidConflictPolicy - политика из запроса; пустая значит перезапись, как было раньше
+func ValidConflictPolicy(policy string) (string, bool) {
+	switch policy {
+	case "":
+		return ConflictOverwrite, true
+	case ConflictOverwrite, ConflictRename, ConflictFail:
+		return policy, true
+	}
+	return "", false
+}
+
+// ResolveConflict - имя, под которым файл будет сохранен по политике. exists - файл с таким именем уже был.
+// Проверка не атомарна с последующей записью: если тот же файл одновременно загружается другим запросом,
+// fail и rename его не защищают и одна из загрузок перезапишет другую. Политика - защита от случайной
+// перезаписи, а не блокировка
+func ResolveConflict(st Storage, bucket string, name string, policy string) (string, bool, error) {
+	_, err := st.Stat(bucket, name)
+	if errors.Is(err, ErrNotFound) {
+		return name, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	switch policy {
+	case ConflictFail:
+		return "", true, ErrObjectExists
+	case ConflictRename:
+		free, errFree := freeName(st, bucket, name)
+		return free, true, errFree
+	}
+	return name, true, nil
+}
+
+// freeName - первое свободное имя вида "name (1).ext", "name (2).ext"... Если с номером имя длиннее
+// MaxSegmentLength, основа имени укорачивается, расширение сохраняется
+func freeName(st Storage, bucket string, name string) (string, error) {
+	dir, base := path.Split(name)
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	// у ".bashrc" расширения нет, это имя целиком
+	if stem == "" {
+		stem, ext = base, ""
+	}
+	for i := 1; i <= maxRenameAttempts; i++ {
+		suffix := fmt.Sprintf(" (%d)%s", i, ext)
+		short, ok := truncateName(stem, MaxSegmentLength-len(suffix))
+		if !ok {
+			break
+		}
+		candidate := dir + short + suffix
+		if len(candidate) > MaxObjectNameLength {
+			break
+		}
+		_, err := st.Stat(bucket, candidate)
+		if errors.Is(err, ErrNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", ErrObjectExists
+}
+
+// truncateName - name не длиннее limit байт, обрезанное по границе символа. false - от имени ничего не осталось
+func truncateName(name string, limit int) (string, bool) {
+	if len(name) <= limit {
+		return name, true
+	}
+	for limit > 0 && !utf8.RuneStart(name[limit]) {
+		limit--
+	}
+	return name[:limit], limit > 0
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeObjectName(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{"docs/2026/report.pdf", "docs/2026/report.pdf"},
+		{"/etc/passwd", "etc/passwd"},
+		{"///abs/path.txt", "abs/path.txt"},
+		{`docs\2026\report.pdf`, "docs/2026/report.pdf"},
+		{`\windows\file.txt`, "windows/file.txt"},
+		{"file..txt", "file..txt"},
+		{"...", "..."},
+		{".bashrc", ".bashrc"},
+		{"e\u0301te\u0301.txt", "\u00e9t\u00e9.txt"}, // NFD -> NFC
+		{"\u00e9t\u00e9.txt", "\u00e9t\u00e9.txt"},
+		{"A\u030angstr\u00f6m/\ufb01le.txt", "\u00c5ngstr\u00f6m/\ufb01le.txt"}, // NFKC не применяется, лигатура остается
+		{"отчет 2026.docx", "отчет 2026.docx"},
+		{strings.Repeat("a", MaxSegmentLength), strings.Repeat("a", MaxSegmentLength)},
+	}
+	for _, c := range cases {
+		got, err := SanitizeObjectName(c.name)
+		if err != nil || got != c.want {
+			t.Errorf("SanitizeObjectName(%q) = %q, %v; want %q", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestSanitizeObjectNameRejects(t *testing.T) {
+	long := strings.Repeat(strings.Repeat("a", 100)+"/", 11)
+	cases := []string{
+		"",
+		"/",
+		`\`,
+		"..",
+		".",
+		"../secret.txt",
+		"docs/../../secret.txt",
+		"docs/./file.txt",
+		`..\..\secret.txt`,
+		`docs\..\..\secret.txt`,
+		"/../secret.txt",
+		"docs//file.txt",
+		"docs/",
+		"file\x00.txt",
+		"file\n.txt",
+		"file\r.txt",
+		"file\t.txt",
+		"file\x1b[31m.txt",
+		"file\x7f.txt",
+		"file\u0085.txt",
+		"invoice\u202efdp.exe", // RLO: отображается как invoiceexe.pdf
+		"file\u2066.txt",
+		"file\u200f.txt",
+		"bad\xffutf8.txt",
+		" leading.txt",
+		"trailing.txt ",
+		"docs /file.txt",
+		strings.Repeat("a", MaxSegmentLength+1),
+		long[:MaxObjectNameLength+1],
+	}
+	for _, name := range cases {
+		got, err := SanitizeObjectName(name)
+		if !errors.Is(err, ErrInvalidObjectName) {
+			t.Errorf("SanitizeObjectName(%q) = %q, %v; want ErrInvalidObjectName", name, got, err)
+		}
+		var nameErr *NameError
+		if err != nil && (!errors.As(err, &nameErr) || nameErr.Reason == "") {
+			t.Errorf("SanitizeObjectName(%q): error without reason: %v", name, err)
+		}
+	}
+}
+
+func TestSanitizeObjectNameIdempotent(t *testing.T) {
+	for _, name := range []string{"/a/b.txt", `a\b.txt`, "é.txt", "docs/2026/report.pdf"} {
+		once, err := SanitizeObjectName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		twice, err := SanitizeObjectName(once)
+		if err != nil || twice != once {
+			t.Errorf("SanitizeObjectName(%q) = %q, again %q, %v", name, once, twice, err)
+		}
+	}
+}
+
+func TestValidatePrefix(t *testing.T) {
+	for _, prefix := range []string{"", "docs", "docs/", "docs/2026/", "file..", "отчет", "a b"} {
+		if err := ValidatePrefix(prefix); err != nil {
+			t.Errorf("ValidatePrefix(%q) = %v", prefix, err)
+		}
+	}
+	for _, prefix := range []string{"..", "../", "docs/../", "./", "docs/./", "/", "/docs", `docs\`, `..\`,
+		"docs\x00", "docs\n", "\u202e", "bad\xff", strings.Repeat("a", MaxObjectNameLength+1)} {
+		if err := ValidatePrefix(prefix); !errors.Is(err, ErrInvalidObjectName) {
+			t.Errorf("ValidatePrefix(%q) = %v; want ErrInvalidObjectName", prefix, err)
+		}
+	}
+}
+
+// existingObjects - Storage, в котором есть только перечисленные объекты
+type existingObjects struct {
+	Storage
+	names map[string]bool
+}
+
+func (s existingObjects) Stat(bucket string, objectName string) (*models.ObjectInfo, error) {
+	if !s.names[objectName] {
+		return nil, ErrNotFound
+	}
+	return &models.ObjectInfo{Key: objectName}, nil
+}
+
+func TestResolveConflictRename(t *testing.T) {
+	long := strings.Repeat("я", MaxSegmentLength/2-2) + ".txt"
+	if len(long) > MaxSegmentLength {
+		t.Fatalf("test name is %d bytes", len(long))
+	}
+	st := existingObjects{names: map[string]bool{
+		"report.pdf": true, "report (1).pdf": true, ".bashrc": true, "docs/" + long: true,
+	}}
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", "report (2).pdf"},
+		{".bashrc", ".bashrc (1)"},
+		{"new.txt", "new.txt"},
+	}
+	for _, c := range cases {
+		got, _, err := ResolveConflict(st, "bucket", c.name, ConflictRename)
+		if err != nil || got != c.want {
+			t.Errorf("ResolveConflict(%q) = %q, %v; want %q", c.name, got, err, c.want)
+		}
+	}
+
+	got, exists, err := ResolveConflict(st, "bucket", "docs/"+long, ConflictRename)
+	if err != nil || !exists {
+		t.Fatalf("ResolveConflict(long) = %q, %v, %v", got, exists, err)
+	}
+	base := path.Base(got)
+	if len(base) > MaxSegmentLength || !strings.HasSuffix(base, " (1).txt") || !strings.HasPrefix(got, "docs/") {
+		t.Errorf("ResolveConflict(long) = %q (%d bytes)", got, len(base))
+	}
+	if _, err = SanitizeObjectName(got); err != nil {
+		t.Errorf("renamed name is rejected: %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"CloudStorageProject-FileServer/pkg/tools"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Драйверы хранилища, выбираются через STORAGE_DRIVER
+const (
+	DriverMinio  = "minio"
+	DriverLocal  = "local"
+	DriverMemory = "memory"
+)
+
+var (
+	// ErrNotFound - файл (или хранилище пользователя) не найден
+	ErrNotFound = errors.New("object not found")
+	// ErrObjectLocked - файл защищен сроком хранения или legal hold и не может быть удален или перезаписан
+	ErrObjectLocked = errors.New("object is protected by retention or legal hold")
+	// ErrInvalidRetention - неверный режим или дата срока хранения
+	ErrInvalidRetention = errors.New("retention mode must be GOVERNANCE or COMPLIANCE and retain_until must be in the future")
+	// ErrLockingNotSupported - драйвер или бакет не поддерживает object locking
+	ErrLockingNotSupported = errors.New("object locking is not enabled for this storage")
+	// ErrInvalidObjectName - имя файла нельзя использовать как ключ объекта
+	ErrInvalidObjectName = errors.New("invalid object name")
+	// ErrMultipartNotSupported - драйвер не умеет составную загрузку (локальный диск)
+	ErrMultipartNotSupported = errors.New("multipart upload is not supported by this storage")
+	// ErrQuotaNotSupported - драйвер не умеет квоты (локальный диск, память)
+	ErrQuotaNotSupported = errors.New("storage driver does not support quotas")
+	// ErrNoSuchUpload - составная загрузка не найдена: завершена, отменена или не начиналась
+	ErrNoSuchUpload = errors.New("multipart upload not found")
+	// ErrInvalidPart - список частей при завершении загрузки не совпадает с загруженными частями
+	ErrInvalidPart = errors.New("one or more parts are missing, out of order or have a wrong ETag")
+)
+
+// Storage - бэкенд хранения файлов. bucket - хранилище конкретного пользователя
+type Storage interface {
+	// CreateOne - сохраняет файл, перезаписывая существующий
+	CreateOne(bucket string, file models.FileMinio) error
+	// GetOne - открывает файл на чтение, reader нужно закрыть
+	GetOne(bucket string, objectName string) (io.ReadCloser, *models.ObjectInfo, error)
+	// Stat - характеристики файла без чтения содержимого
+	Stat(bucket string, objectName string) (*models.ObjectInfo, error)
+	// List - объекты с префиксом; без recursive вложенные "папки" возвращаются одним элементом с IsDir.
+	// Префикс проверяется ValidatePrefix, недопустимый - ErrInvalidObjectName
+	List(bucket string, prefix string, recursive bool) ([]models.ObjectInfo, error)
+	// Delete - удаляет файл
+	Delete(bucket string, objectName string) error
+	// Copy - копирует файл внутри хранилища пользователя
+	Copy(bucket string, objectName string, newObjectName string) error
+	// Rename - переименовывает файл
+	Rename(bucket string, objectName string, newObjectName string) error
+	// ObjectTags - теги файла
+	ObjectTags(bucket string, objectName string) (map[string]string, error)
+	// CloseConnection - освобождает ресурсы драйвера
+	CloseConnection(ctx context.Context) error
+}
+
+// Locker - драйверы с поддержкой WORM-защиты файлов (сейчас только MinIO)
+type Locker interface {
+	ObjectProtection(bucket string, objectName string) (*models.ObjectProtection, error)
+	SetRetention(bucket string, objectName string, request models.RetentionRequest) error
+	SetLegalHold(bucket string, objectName string, enabled bool) error
+}
+
+// Provisioner - драйверы, которые умеют создавать хранилище пользователя заранее
+type Provisioner interface {
+	// EnsureBucket - создает хранилище с настройками по умолчанию, если его еще нет. true - хранилище создано
+	EnsureBucket(bucket string) (bool, error)
+	// Buckets - все существующие хранилища
+	Buckets() ([]string, error)
+	// RemoveBucket - удаляет пустое хранилище
+	RemoveBucket(bucket string) error
+}
+
+// Quoter - драйверы с жесткой квотой на хранилище (сейчас только MinIO)
+type Quoter interface {
+	// SetBucketQuota - квота хранилища в байтах, 0 снимает ограничение
+	SetBucketQuota(bucket string, quotaBytes int64) error
+}
+
+// Multiparter - драйверы с составной загрузкой как в S3: части загружаются отдельно,
+// объект появляется только после Complete. Нужен S3-шлюзу
+type Multiparter interface {
+	NewMultipartUpload(bucket string, objectName string, contentType string) (string, error)
+	// PutObjectPart - загружает часть с номером 1..10000, возвращает ее ETag
+	PutObjectPart(bucket string, objectName string, uploadId string, partNumber int, reader io.Reader, size int64) (string, error)
+	// CompleteMultipartUpload - собирает объект из частей в указанном порядке, возвращает ETag объекта
+	CompleteMultipartUpload(bucket string, objectName string, uploadId string, parts []models.CompletedPart) (string, error)
+	AbortMultipartUpload(bucket string, objectName string, uploadId string) error
+}
+
+// BucketCopier - драйверы, которые умеют копировать объект между хранилищами на своей стороне
+type BucketCopier interface {
+	CopyObject(srcBucket string, objectName string, dstBucket string) error
+}
+
+// NewStorageId - имя нового хранилища аккаунта: из UUID, поэтому всегда допустимое имя бакета S3
+func NewStorageId() string {
+	return "u-" + strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// CopyObjectTo - копирует объект в другое хранилище под тем же ключом
+func CopyObjectTo(st Storage, srcBucket string, objectName string, dstBucket string) error {
+	if copier, ok := st.(BucketCopier); ok {
+		return copier.CopyObject(srcBucket, objectName, dstBucket)
+	}
+	reader, info, err := st.GetOne(srcBucket, objectName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+	return st.CreateOne(dstBucket, models.FileMinio{
+		FileName:    objectName,
+		Reader:      reader,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+	})
+}
+
+// FilesList - список файлов пользователя для web-интерфейса, rules нужны чтобы показать
+// ближайшее срабатывание правил жизненного цикла
+func FilesList(st Storage, bucket string, rules ...models.LifecycleRule) ([]models.FileWebResponse, error) {
+	objects, err := st.List(bucket, "", false)
+	if err != nil {
+		return []models.FileWebResponse{}, err
+	}
+
+	// теги запрашиваем только если есть правила с фильтром по тегу - это отдельный запрос на каждый файл
+	needTags := false
+	for i := range rules {
+		if rules[i].HasTag() {
+			needTags = true
+			break
+		}
+	}
+
+	var files []models.FileWebResponse
+	for _, obj := range objects {
+		// корзина - служебная папка, в списке файлов её не показываем
+		if obj.Key == models.TrashPrefix {
+			continue
+		}
+		fileNameSplit := strings.Split(obj.Key, ".")
+		file := models.FileWebResponse{
+			FileName:    obj.Key,
+			FileSize:    tools.FormatFileSize(obj.Size),
+			FileType:    fileNameSplit[len(fileNameSplit)-1],
+			LastModTime: obj.LastModified.Format("02.01.2006 12:05"),
+		}
+		if len(rules) > 0 {
+			var tags map[string]string
+			if needTags {
+				tags, _ = st.ObjectTags(bucket, obj.Key)
+			}
+			if expires := models.NextExpiry(rules, obj.Key, obj.LastModified, tags); expires != nil {
+				file.ExpiresAt = expires.Format("02.01.2006 15:04")
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// MoveToTrash - переносит файл в корзину пользователя
+func MoveToTrash(st Storage, bucket string, objectName string) error {
+	return st.Rename(bucket, objectName, models.TrashPrefix+objectName)
+}
+
+// CleanObjectName - приводит ключ объекта к виду a/b/c и отсекает выход за пределы хранилища.
+// Нужен драйверам, которые кладут ключи в файловую систему
+func CleanObjectName(objectName string) (string, error) {
+	if objectName == "" || strings.ContainsRune(objectName, 0) {
+		return "", ErrInvalidObjectName
+	}
+	cleaned := path.Clean("/" + strings.ReplaceAll(objectName, "\\", "/"))
+	cleaned = strings.TrimPrefix(cleaned, "/")
+	if cleaned == "" || cleaned == "." {
+		return "", ErrInvalidObjectName
+	}
+	for _, segment := range strings.Split(objectName, "/") {
+		if segment == ".." {
+			return "", ErrInvalidObjectName
+		}
+	}
+	return cleaned, nil
+}
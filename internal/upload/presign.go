@@ -0,0 +1,82 @@
+package upload
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaxPresignExpiry - дольше недели ссылка на загрузку не живет
+const MaxPresignExpiry = 7 * 24 * time.Hour
+
+// ErrInvalidTarget - подпись ссылки не сходится или срок ее действия истек
+var ErrInvalidTarget = errors.New("upload link is invalid or expired")
+
+// ActionDownload - ссылка на скачивание файла; у ссылок на загрузку Action пустой
+const ActionDownload = "get"
+
+// Target - куда и что разрешает загрузить (или, с ActionDownload, скачать) подписанная ссылка
+type Target struct {
+	Bucket    string `json:"b"`
+	Path      string `json:"p"`
+	Size      int64  `json:"s"`
+	Sha256    string `json:"h"`
+	ExpiresAt int64  `json:"e"`
+	Action    string `json:"a,omitempty"`
+}
+
+// Signer - подписывает ссылки на загрузку одного файла без API-ключа. Токен в hex,
+// поэтому в пути ссылки не появится ничего, что middleware примет за защищенный адрес
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner - пустой секрет заменяется случайным: ссылки будут действовать до перезапуска
+// и только на этом экземпляре сервера. Второе значение - был ли секрет сгенерирован
+func NewSigner(secret string) (*Signer, bool) {
+	if secret != "" {
+		return &Signer{secret: []byte(secret)}, false
+	}
+	random := make([]byte, 32)
+	_, _ = rand.Read(random)
+	return &Signer{secret: random}, true
+}
+
+// Sign - токен для ссылки на загрузку
+func (s *Signer) Sign(target Target) string {
+	payload, _ := json.Marshal(target)
+	encoded := hex.EncodeToString(payload)
+	return encoded + "." + hex.EncodeToString(s.mac(encoded))
+}
+
+// Verify - проверяет подпись и срок действия токена
+func (s *Signer) Verify(token string) (*Target, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidTarget
+	}
+	sum, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(sum, s.mac(encoded)) {
+		return nil, ErrInvalidTarget
+	}
+	payload, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidTarget
+	}
+	target := &Target{}
+	if err = json.Unmarshal(payload, target); err != nil || time.Now().Unix() > target.ExpiresAt {
+		return nil, ErrInvalidTarget
+	}
+	return target, nil
+}
+
+func (s *Signer) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,111 @@
+package upload
+
+import (
+	"CloudStorageProject-FileServer/internal/metrics"
+	"CloudStorageProject-FileServer/pkg/config"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// Способы передачи файла в хранилище
+const (
+	ModeMemory = "memory" // файл меньше порога, целиком в памяти
+	ModeStream = "stream" // файл больше порога, идет в хранилище потоком без известного размера
+	ModeSpill  = "spill"  // файл больше порога, сначала пишется во временный файл
+)
+
+// Spooler - решает, как передать загружаемый файл в хранилище: маленькие файлы буферизуются
+// в памяти, большие идут потоком (multipart PutObject) или, если включено, через временный файл
+type Spooler struct {
+	tempDir   string
+	threshold int64
+	spill     bool
+	metrics   *metrics.UploadMetrics
+}
+
+// Body - содержимое файла, подготовленное для CreateOne
+type Body struct {
+	Reader io.Reader
+	// Size - размер файла, -1 если он неизвестен до конца передачи
+	Size    int64
+	Mode    string
+	cleanup func()
+}
+
+// Close - освобождает временный файл, если он был
+func (b *Body) Close() {
+	if b.cleanup != nil {
+		b.cleanup()
+		b.cleanup = nil
+	}
+}
+
+func NewSpooler(conf *config.Config, metric *metrics.UploadMetrics) *Spooler {
+	if conf.UploadTempDir != "" {
+		_ = os.MkdirAll(conf.UploadTempDir, 0o700)
+	}
+	return &Spooler{
+		tempDir:   conf.UploadTempDir,
+		threshold: conf.UploadSpillThresholdMB * 1024 * 1024,
+		spill:     conf.UploadSpillToDisk,
+		metrics:   metric,
+	}
+}
+
+// Prepare - читает начало файла до порога и выбирает способ передачи
+func (s *Spooler) Prepare(reader io.Reader) (*Body, error) {
+	buffer := &bytes.Buffer{}
+	n, err := io.CopyN(buffer, reader, s.threshold+1)
+	if errors.Is(err, io.EOF) {
+		s.metrics.PartsTotal.WithLabelValues(ModeMemory).Inc()
+		return &Body{Reader: bytes.NewReader(buffer.Bytes()), Size: n, Mode: ModeMemory}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rest := io.MultiReader(buffer, reader)
+	if !s.spill {
+		s.metrics.PartsTotal.WithLabelValues(ModeStream).Inc()
+		return &Body{Reader: rest, Size: -1, Mode: ModeStream}, nil
+	}
+	return s.spillToDisk(rest)
+}
+
+// spillToDisk - пишет файл во временный каталог, чтобы передать его в хранилище одним запросом с известным размером
+func (s *Spooler) spillToDisk(reader io.Reader) (*Body, error) {
+	tempFile, err := os.CreateTemp(s.tempDir, "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	counter := &diskCounter{metrics: s.metrics}
+	cleanup := func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+		s.metrics.TempDiskBytes.Sub(float64(counter.written))
+	}
+	size, err := io.Copy(io.MultiWriter(tempFile, counter), reader)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	if _, err = tempFile.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, err
+	}
+	s.metrics.PartsTotal.WithLabelValues(ModeSpill).Inc()
+	return &Body{Reader: tempFile, Size: size, Mode: ModeSpill, cleanup: cleanup}, nil
+}
+
+// diskCounter - учитывает занятое временными файлами место по мере записи
+type diskCounter struct {
+	metrics *metrics.UploadMetrics
+	written int64
+}
+
+func (c *diskCounter) Write(p []byte) (int, error) {
+	c.written += int64(len(p))
+	c.metrics.TempDiskBytes.Add(float64(len(p)))
+	return len(p), nil
+}
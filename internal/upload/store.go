@@ -0,0 +1,71 @@
+package upload
+
+import (
+	"CloudStorageProject-FileServer/internal/storage"
+	"CloudStorageProject-FileServer/pkg/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrChecksumMismatch - присланный клиентом sha256 не совпал с содержимым
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Store - загружает file.Reader в хранилище как один файл и проверяет контрольную сумму.
+// file.Checksum - ожидаемый sha256 в hex (пусто - не проверять), file.Size - размер из запроса или -1.
+// true - файл был создан, false - перезаписан
+func (s *Spooler) Store(st storage.Storage, bucket string, file models.FileMinio) (bool, error) {
+	expected := strings.ToLower(file.Checksum)
+	if expected != "" {
+		if sum, err := hex.DecodeString(expected); err != nil || len(sum) != sha256.Size {
+			return false, ErrChecksumMismatch
+		}
+	}
+	if file.ContentType == "" {
+		file.ContentType = "application/octet-stream"
+	}
+	_, errStat := st.Stat(bucket, file.FileName)
+	created := errors.Is(errStat, storage.ErrNotFound)
+
+	body, err := s.Prepare(file.Reader)
+	if err != nil {
+		return false, err
+	}
+	defer body.Close()
+	// размер известен из запроса - большой файл уйдет в хранилище одним потоком без частей неизвестной длины
+	if body.Size < 0 && file.Size >= 0 {
+		body.Size = file.Size
+	}
+
+	file.Reader, file.Size, file.Checksum = body.Reader, body.Size, expected
+	hasher := sha256.New()
+	if seeker, ok := body.Reader.(io.ReadSeeker); ok {
+		// файл в памяти или во временном файле - сумму можно посчитать до загрузки
+		if _, err = io.Copy(hasher, seeker); err != nil {
+			return false, err
+		}
+		if _, err = seeker.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		actual := hex.EncodeToString(hasher.Sum(nil))
+		if expected != "" && expected != actual {
+			return false, ErrChecksumMismatch
+		}
+		file.Checksum = actual
+		hasher = nil
+	} else if expected != "" {
+		// поток проверяем по ходу загрузки
+		file.Reader = io.TeeReader(body.Reader, hasher)
+	}
+
+	if err = st.CreateOne(bucket, file); err != nil {
+		return false, err
+	}
+	if hasher != nil && expected != "" && hex.EncodeToString(hasher.Sum(nil)) != expected {
+		_ = st.Delete(bucket, file.FileName)
+		return false, ErrChecksumMismatch
+	}
+	return created, nil
+}
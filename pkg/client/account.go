@@ -0,0 +1,100 @@
+package client
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// FetchURL - фоновая загрузка файла по URL (POST /client/api/v1/upload-url). Прогресс - FetchJob
+func (c *Client) FetchURL(ctx context.Context, fetch models.FetchRequest) (*models.FetchJob, error) {
+	req, err := jsonRequest(http.MethodPost, "/client/api/v1/upload-url", nil, fetch)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetchJob(ctx, req)
+}
+
+// FetchJob - состояние загрузки по URL (GET /client/api/v1/upload-jobs/{id})
+func (c *Client) FetchJob(ctx context.Context, id string) (*models.FetchJob, error) {
+	return c.fetchJob(ctx, &request{method: http.MethodGet, path: "/client/api/v1/upload-jobs/" + url.PathEscape(id)})
+}
+
+// WaitFetchJob - опрашивает задачу раз в interval, пока она не завершится (done или failed)
+func (c *Client) WaitFetchJob(ctx context.Context, id string, interval time.Duration) (*models.FetchJob, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.FetchJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status == models.FetchDone || job.Status == models.FetchFailed {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) fetchJob(ctx context.Context, req *request) (*models.FetchJob, error) {
+	job := &models.FetchJob{}
+	if err := c.call(ctx, req, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// S3Credentials - ключи S3-шлюза для хранилища (GET /client/api/v1/s3-credentials)
+func (c *Client) S3Credentials(ctx context.Context) (*models.S3Credentials, error) {
+	credentials := &models.S3Credentials{}
+	if err := c.call(ctx, &request{method: http.MethodGet, path: "/client/api/v1/s3-credentials"}, credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// SSHKeys - публичные ключи для входа по SFTP (GET /client/api/v1/ssh-keys)
+func (c *Client) SSHKeys(ctx context.Context) ([]models.SSHKey, error) {
+	var keys []models.SSHKey
+	if err := c.call(ctx, &request{method: http.MethodGet, path: "/client/api/v1/ssh-keys"}, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// AddSSHKey - регистрация ключа в формате authorized_keys (POST /client/api/v1/ssh-keys)
+func (c *Client) AddSSHKey(ctx context.Context, publicKey string) (*models.SSHKey, error) {
+	req, err := jsonRequest(http.MethodPost, "/client/api/v1/ssh-keys", nil, models.SSHKeyRequest{PublicKey: publicKey})
+	if err != nil {
+		return nil, err
+	}
+	key := &models.SSHKey{}
+	if err = c.call(ctx, req, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DeleteSSHKey - удаление ключа (DELETE /client/api/v1/ssh-keys)
+func (c *Client) DeleteSSHKey(ctx context.Context, id int) error {
+	return c.call(ctx, &request{
+		method: http.MethodDelete,
+		path:   "/client/api/v1/ssh-keys",
+		query:  url.Values{"id": {itoa(id)}},
+	}, nil)
+}
+
+// Health - состояние сервера (GET /health), ключ не нужен
+func (c *Client) Health(ctx context.Context) (*models.HealthResponse, error) {
+	health := &models.HealthResponse{}
+	if err := c.call(ctx, &request{method: http.MethodGet, path: c.baseURL + "/health"}, health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
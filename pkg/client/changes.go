@@ -0,0 +1,91 @@
+package client
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangesOptions - параметры GET /api/v2/changes
+type ChangesOptions struct {
+	Cursor string        // пусто - только текущий курсор, без изменений
+	Wait   time.Duration // long polling: сколько ждать изменений, если их нет (до минуты)
+	Limit  int           // не больше 1000, 0 - 1000
+}
+
+// Changes - изменения файлов после курсора. Если курсор старше сжатой части журнала, ошибка - ErrCursorExpired,
+// новый курсор для продолжения после полного списка файлов - ExpiredCursor(err)
+func (c *Client) Changes(ctx context.Context, opts ChangesOptions) (*models.ChangeFeed, error) {
+	query := url.Values{}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+	if opts.Wait > 0 {
+		query.Set("wait", strconv.Itoa(int(opts.Wait.Seconds())))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	feed := &models.ChangeFeed{}
+	if err := c.call(ctx, &request{method: http.MethodGet, path: "/api/v2/changes", query: query}, feed); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// Events - поток событий хранилища (SSE, GET /client/api/v1/events). handle вызывается на каждое событие,
+// пока не отменят ctx, не оборвется соединение или handle не вернет ошибку. Пропущенные при обрыве события
+// не повторяются: для надежной синхронизации нужен Changes
+func (c *Client) Events(ctx context.Context, handle func(models.StorageEvent) error) error {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		path:   "/client/api/v1/events",
+		header: http.Header{"Accept": {"text/event-stream"}},
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// пустая строка завершает событие
+			if data.Len() == 0 {
+				continue
+			}
+			var event models.StorageEvent
+			if err = json.Unmarshal([]byte(data.String()), &event); err != nil {
+				return fmt.Errorf("failed to decode event: %w", err)
+			}
+			data.Reset()
+			if err = handle(event); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// "event:" дублирует type из данных, строки ":" - heartbeat
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("event stream closed by server")
+}
@@ -0,0 +1,277 @@
+// Package client - Go-клиент HTTP API файлового сервера.
+//
+//	c := client.New("http://localhost:11682", apiKey)
+//	files, err := c.List(ctx, "photos/", false)
+//	_, err = c.Upload(ctx, "photos/a.png", file, &client.UploadOptions{ContentType: "image/png"})
+//	if errors.Is(err, client.ErrObjectLocked) { ... }
+//
+// Все методы принимают context: отмена прерывает запрос и ожидание между повторами.
+// Временные ошибки (сеть, 429, 5xx) повторяются с экспоненциальной задержкой, см. WithRetries
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBackoff - потолок задержки между повторами
+const maxBackoff = 30 * time.Second
+
+// Client - клиент одного хранилища: хранилище определяется API-ключом. Безопасен для параллельного использования
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	userAgent  string
+	retries    int
+	backoff    time.Duration
+}
+
+// Option - настройка клиента в New
+type Option func(*Client)
+
+// WithHTTPClient - свой http.Client (таймауты, прокси, TLS). Таймаут клиента ограничивает и скачивание целиком,
+// поэтому для больших файлов лучше ограничивать время через context
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetries - сколько раз повторить запрос после временной ошибки и начальная задержка,
+// которая удваивается с каждой попыткой. retries = 0 - без повторов
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = max(retries, 0)
+		c.backoff = backoff
+	}
+}
+
+// WithUserAgent - User-Agent запросов, чтобы отличать сервисы в логах сервера
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// New - клиент сервера baseURL (http://host:port) с ключом apiKey
+func New(baseURL, apiKey string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: http.DefaultClient,
+		userAgent:  "cloudstorage-go-client",
+		retries:    3,
+		backoff:    500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// request - описание запроса к API. body вызывается на каждую попытку и должен вернуть тело с начала
+type request struct {
+	method string
+	path   string // путь API или абсолютный адрес (подписанные ссылки, без ключа)
+	query  url.Values
+	header http.Header
+	body   func() (io.Reader, error)
+	size   int64 // длина тела, -1 - неизвестна
+}
+
+// absolute - запрос по готовому адресу (подписанная ссылка), ключ к нему не прикладывается
+func (req *request) absolute() bool {
+	return strings.Contains(req.path, "://")
+}
+
+// url - адрес запроса; ключ идет в заголовке Authorization, в адресе его нет
+func (c *Client) url(req *request) string {
+	if req.absolute() {
+		return req.path
+	}
+	if len(req.query) == 0 {
+		return c.baseURL + req.path
+	}
+	return c.baseURL + req.path + "?" + req.query.Encode()
+}
+
+// do - выполняет запрос с повторами. Ответ с ошибкой превращается в *Error, успешный возвращается открытым
+func (c *Client) do(ctx context.Context, req *request) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := c.wait(ctx, attempt, lastErr); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := c.send(ctx, req)
+		if errors.Is(err, errBodyNotRewindable) {
+			return nil, lastErr
+		}
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+		if err == nil {
+			err = decodeError(resp)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		lastErr = err
+		if attempt >= c.retries || !c.retryable(req, err) {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	var body io.Reader
+	if req.body != nil {
+		reader, err := req.body()
+		if err != nil {
+			return nil, err
+		}
+		body = reader
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.url(req), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil && req.size >= 0 {
+		httpReq.ContentLength = req.size
+	}
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if !req.absolute() {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return c.httpClient.Do(httpReq)
+}
+
+// retryable - стоит ли повторять запрос после ошибки. Неидемпотентные POST и PATCH повторяются, только если
+// сервер явно отказался их выполнять (429, 503), сетевая ошибка могла случиться уже после выполнения
+func (c *Client) retryable(req *request, err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return idempotent(req.method)
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(req.method)
+	}
+	return false
+}
+
+func idempotent(method string) bool {
+	return method != http.MethodPost && method != http.MethodPatch
+}
+
+// wait - задержка перед попыткой attempt: экспонента с разбросом или Retry-After от сервера
+func (c *Client) wait(ctx context.Context, attempt int, lastErr error) error {
+	delay := min(c.backoff<<(attempt-1), maxBackoff)
+	delay = delay/2 + rand.N(delay/2+1)
+	var apiErr *Error
+	if errors.As(lastErr, &apiErr) && apiErr.RetryAfter > 0 {
+		delay = min(apiErr.RetryAfter, maxBackoff)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// call - запрос с JSON-ответом в out (nil - тело ответа не нужно)
+func (c *Client) call(ctx context.Context, req *request, out any) error {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	return decode(resp, out)
+}
+
+// decode - читает JSON успешного ответа в out и закрывает тело
+func decode(resp *http.Response, out any) error {
+	defer func() { _ = resp.Body.Close() }()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// jsonRequest - запрос с телом value в JSON
+func jsonRequest(method, path string, query url.Values, value any) (*request, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	return &request{
+		method: method,
+		path:   path,
+		query:  query,
+		header: http.Header{"Content-Type": {"application/json"}},
+		body:   func() (io.Reader, error) { return bytes.NewReader(data), nil },
+		size:   int64(len(data)),
+	}, nil
+}
+
+// errBodyNotRewindable - тело уже отправлено и не поддерживает Seek, повторить запрос нельзя
+var errBodyNotRewindable = errors.New("request body cannot be sent again")
+
+// readerBody - тело из r для повторяемых попыток: io.Seeker возвращается к началу,
+// обычный io.Reader отправляется один раз
+func readerBody(r io.Reader) func() (io.Reader, error) {
+	seeker, ok := r.(io.Seeker)
+	start := int64(0)
+	if ok {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			ok = false
+		}
+	}
+	sent := false
+	return func() (io.Reader, error) {
+		if !sent {
+			sent = true
+			return r, nil
+		}
+		if !ok {
+			return nil, errBodyNotRewindable
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+}
+
+// filePath - путь файла в URL: каждый сегмент экранируется отдельно, слеши остаются
+func filePath(name string) string {
+	segments := strings.Split(name, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+func itoa(value int) string {
+	return strconv.Itoa(value)
+}
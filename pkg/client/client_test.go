@@ -0,0 +1,192 @@
+package client
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testKey = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(server.URL, testKey, WithRetries(3, time.Millisecond))
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: models.APIError{
+		Code: code, Message: code, RequestId: "req-1",
+	}})
+}
+
+func TestListSendsKeyAndDecodesFiles(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/files" || r.URL.Query().Has("api") || r.Header.Get("Authorization") != "Bearer "+testKey {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.URL.Query().Get("prefix") != "photos/" || r.URL.Query().Get("recursive") != "true" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		_ = json.NewEncoder(w).Encode(models.FileList{Prefix: "photos/", Files: []models.FileInfo{
+			{Name: "photos/a.png", Size: 3},
+		}})
+	})
+	files, err := c.List(context.Background(), "photos/", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "photos/a.png" || files[0].Size != 3 {
+		t.Fatalf("unexpected files %+v", files)
+	}
+}
+
+func TestErrorsMatchByCode(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			// у HEAD нет тела, код определяется по статусу
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeError(w, http.StatusForbidden, models.ErrCodeObjectLocked)
+	})
+	err := c.Delete(context.Background(), "a.txt")
+	if !errors.Is(err, ErrObjectLocked) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected object_locked, got %v", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || apiErr.RequestId != "req-1" {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	if _, err = c.Stat(context.Background(), "a.txt"); !IsNotFound(err) {
+		t.Fatalf("expected not_found, got %v", err)
+	}
+}
+
+func TestRetriesTemporaryErrors(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			writeError(w, http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(models.FileList{})
+	})
+	if _, err := c.List(context.Background(), "", false); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls.Load())
+	}
+}
+
+func TestDoesNotRetryPostAfterServerError(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeError(w, http.StatusInternalServerError, models.ErrCodeInternal)
+	})
+	_, err := c.Share(context.Background(), "a.txt", time.Hour)
+	if !errors.Is(err, ErrInternal) {
+		t.Fatalf("expected internal, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("POST must not be retried after 500, got %d calls", calls.Load())
+	}
+}
+
+func TestDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeError(w, http.StatusBadRequest, models.ErrCodeBadRequest)
+	})
+	if _, err := c.List(context.Background(), "", false); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("expected bad_request, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestRetryAfterIsParsed(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		writeError(w, http.StatusTooManyRequests, models.ErrCodeServiceUnavailable)
+	})
+	c.retries = 0
+	_, err := c.List(context.Background(), "", false)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 7*time.Second || !apiErr.Temporary() {
+		t.Fatalf("unexpected error %#v", err)
+	}
+}
+
+func TestContextCancelsBackoff(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable)
+	})
+	c.retries, c.backoff = 10, time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.List(ctx, "", false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("backoff was not interrupted by context")
+	}
+}
+
+func TestChangesExpiredCursor(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") != "5" || r.URL.Query().Get("wait") != "30" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		w.WriteHeader(http.StatusGone)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: models.APIError{
+			Code: models.ErrCodeCursorExpired, Details: map[string]any{"cursor": "1050"},
+		}})
+	})
+	_, err := c.Changes(context.Background(), ChangesOptions{Cursor: "5", Wait: 30 * time.Second})
+	if !errors.Is(err, ErrCursorExpired) {
+		t.Fatalf("expected cursor_expired, got %v", err)
+	}
+	if cursor, ok := ExpiredCursor(err); !ok || cursor != "1050" {
+		t.Fatalf("unexpected cursor %q", cursor)
+	}
+}
+
+func TestEventsStream(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(": ping\n\n"))
+		_, _ = w.Write([]byte("event: upload\ndata: {\"type\":\"upload\",\"file_name\":\"a.txt\"}\n\n"))
+		_, _ = w.Write([]byte("event: rename\ndata: {\"type\":\"rename\",\"file_name\":\"a.txt\",\"new_name\":\"b.txt\"}\n\n"))
+	})
+	var events []models.StorageEvent
+	stop := errors.New("stop")
+	err := c.Events(context.Background(), func(event models.StorageEvent) error {
+		events = append(events, event)
+		if len(events) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+	if events[0].Type != models.EventUpload || events[1].NewName != "b.txt" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
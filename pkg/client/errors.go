@@ -0,0 +1,126 @@
+package client
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error - ответ сервера с ошибкой (models.ErrorResponse). Сравнивается с ErrNotFound и другими
+// ошибками ниже через errors.Is по коду:
+//
+//	if errors.Is(err, client.ErrNotFound) { ... }
+//	var apiErr *client.Error
+//	if errors.As(err, &apiErr) { log.Println(apiErr.RequestId) }
+type Error struct {
+	StatusCode int
+	RetryAfter time.Duration // из заголовка Retry-After, если сервер его прислал
+	models.APIError
+}
+
+func (e *Error) Error() string {
+	if e.RequestId != "" {
+		return fmt.Sprintf("%s: %s (status %d, request %s)", e.Code, e.Message, e.StatusCode, e.RequestId)
+	}
+	return fmt.Sprintf("%s: %s (status %d)", e.Code, e.Message, e.StatusCode)
+}
+
+// Is - ошибки с одинаковым кодом считаются одной ошибкой
+func (e *Error) Is(target error) bool {
+	var other *Error
+	return errors.As(target, &other) && other.Code == e.Code
+}
+
+// Temporary - ошибку может не быть при повторе запроса
+func (e *Error) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+func codeError(code string) *Error {
+	return &Error{APIError: models.APIError{Code: code}}
+}
+
+// Ошибки API по кодам models.ErrCode*, для errors.Is
+var (
+	ErrBadRequest          = codeError(models.ErrCodeBadRequest)
+	ErrInvalidName         = codeError(models.ErrCodeInvalidName)
+	ErrChecksumMismatch    = codeError(models.ErrCodeChecksumMismatch)
+	ErrAPIKeyRequired      = codeError(models.ErrCodeAPIKeyRequired)
+	ErrAPIKeyInvalid       = codeError(models.ErrCodeAPIKeyInvalid)
+	ErrForbidden           = codeError(models.ErrCodeForbidden)
+	ErrObjectLocked        = codeError(models.ErrCodeObjectLocked)
+	ErrNotFound            = codeError(models.ErrCodeNotFound)
+	ErrStorageNotFound     = codeError(models.ErrCodeStorageNotFound)
+	ErrConflict            = codeError(models.ErrCodeConflict)
+	ErrLockingNotSupported = codeError(models.ErrCodeLockingNotSupported)
+	ErrCursorExpired       = codeError(models.ErrCodeCursorExpired)
+	ErrTooLarge            = codeError(models.ErrCodeTooLarge)
+	ErrQuotaExceeded       = codeError(models.ErrCodeQuotaExceeded)
+	ErrInternal            = codeError(models.ErrCodeInternal)
+	ErrServiceUnavailable  = codeError(models.ErrCodeServiceUnavailable)
+)
+
+// IsNotFound - сервер ответил, что файла или ресурса нет
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// ExpiredCursor - новый курсор из ошибки cursor_expired: журнал изменений сжат,
+// нужно заново получить список файлов и продолжить с этого курсора
+func ExpiredCursor(err error) (string, bool) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != models.ErrCodeCursorExpired {
+		return "", false
+	}
+	cursor, ok := apiErr.Details["cursor"].(string)
+	return cursor, ok
+}
+
+// decodeError - *Error из ответа с ошибкой. Ответ без JSON (HEAD, прокси) получает код по статусу
+func decodeError(resp *http.Response) error {
+	defer func() { _ = resp.Body.Close() }()
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	var body models.ErrorResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err == nil && body.Error.Code != "" {
+		apiErr.APIError = body.Error
+		return apiErr
+	}
+	apiErr.Code = statusCode(resp.StatusCode)
+	apiErr.Message = strings.ToLower(http.StatusText(resp.StatusCode))
+	apiErr.RequestId = resp.Header.Get("X-Request-Id")
+	return apiErr
+}
+
+// statusCode - код ошибки для ответа без тела, как его выбрал бы сервер
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return models.ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return models.ErrCodeAPIKeyInvalid
+	case http.StatusForbidden:
+		return models.ErrCodeForbidden
+	case http.StatusNotFound:
+		return models.ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return models.ErrCodeMethodNotAllowed
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return models.ErrCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return models.ErrCodeTooLarge
+	case http.StatusServiceUnavailable:
+		return models.ErrCodeServiceUnavailable
+	case http.StatusGatewayTimeout:
+		return models.ErrCodeTimeout
+	}
+	return models.ErrCodeInternal
+}
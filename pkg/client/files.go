@@ -0,0 +1,236 @@
+package client
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// checksumHeader - sha256 содержимого в hex: ожидаемый при загрузке, известный серверу при скачивании
+const checksumHeader = "X-Checksum-Sha256"
+
+// UploadOptions - необязательные параметры загрузки
+type UploadOptions struct {
+	ContentType string
+	// Size - длина содержимого, если известна: сервер пишет большой файл в хранилище одним потоком.
+	// 0 - неизвестна (для пустого файла передайте пустой *bytes.Reader, его длина определится сама)
+	Size int64
+	// Sha256 - ожидаемый sha256 в hex; при несовпадении файл не сохраняется, ошибка - ErrChecksumMismatch
+	Sha256 string
+}
+
+// List - файлы и папки по префиксу (GET /api/v2/files). Без recursive вложенные папки приходят одним элементом с IsDir
+func (c *Client) List(ctx context.Context, prefix string, recursive bool) ([]models.FileInfo, error) {
+	query := url.Values{"prefix": {prefix}}
+	if recursive {
+		query.Set("recursive", "true")
+	}
+	var list models.FileList
+	if err := c.call(ctx, &request{method: http.MethodGet, path: "/api/v2/files", query: query}, &list); err != nil {
+		return nil, err
+	}
+	return list.Files, nil
+}
+
+// Stat - метаданные файла без содержимого (HEAD /api/v2/files/{path})
+func (c *Client) Stat(ctx context.Context, name string) (*models.FileInfo, error) {
+	resp, err := c.do(ctx, &request{method: http.MethodHead, path: "/api/v2/files/" + filePath(name)})
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	return fileInfo(name, resp), nil
+}
+
+// Upload - загрузка файла из r (PUT /api/v2/files/{path}), существующий файл перезаписывается.
+// Тело читается потоком. Если r реализует io.Seeker (*os.File, *bytes.Reader), после временной ошибки
+// загрузка повторяется с той же позиции, иначе повторять ее должен вызывающий
+func (c *Client) Upload(ctx context.Context, name string, r io.Reader, opts *UploadOptions) (*models.FileInfo, error) {
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+	req := &request{
+		method: http.MethodPut,
+		path:   "/api/v2/files/" + filePath(name),
+		header: http.Header{},
+		body:   readerBody(r),
+		size:   -1,
+	}
+	if opts.Size > 0 {
+		req.size = opts.Size
+	}
+	if opts.ContentType != "" {
+		req.header.Set("Content-Type", opts.ContentType)
+	}
+	if opts.Sha256 != "" {
+		req.header.Set(checksumHeader, opts.Sha256)
+	}
+	info := &models.FileInfo{}
+	if err := c.call(ctx, req, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Open - содержимое файла потоком (GET /api/v2/files/{path}). Тело закрывает вызывающий
+func (c *Client) Open(ctx context.Context, name string) (io.ReadCloser, *models.FileInfo, error) {
+	return c.OpenRange(ctx, name, 0, 0)
+}
+
+// OpenRange - length байт файла начиная с offset; length <= 0 - до конца файла.
+// Если хранилище не умеет отдавать диапазоны и сервер прислал файл целиком, лишнее отбрасывается на клиенте
+func (c *Client) OpenRange(ctx context.Context, name string, offset, length int64) (io.ReadCloser, *models.FileInfo, error) {
+	req := &request{method: http.MethodGet, path: "/api/v2/files/" + filePath(name), header: http.Header{}}
+	if offset > 0 || length > 0 {
+		value := "bytes=" + strconv.FormatInt(offset, 10) + "-"
+		if length > 0 {
+			value += strconv.FormatInt(offset+length-1, 10)
+		}
+		req.header.Set("Range", value)
+	}
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	info := fileInfo(name, resp)
+	body, err := skipTo(resp, offset, length)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, info, nil
+}
+
+// skipTo - тело ответа с позиции offset: 206 уже начинается с нее, у 200 начало пропускается
+func skipTo(resp *http.Response, offset, length int64) (io.ReadCloser, error) {
+	if resp.StatusCode == http.StatusPartialContent || (offset == 0 && length <= 0) {
+		return resp.Body, nil
+	}
+	if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("failed to skip to offset %d: %w", offset, err)
+	}
+	if length <= 0 {
+		return resp.Body, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, length), resp.Body}, nil
+}
+
+// Download - скачивание файла в w. Если соединение оборвалось, скачивание продолжается с того же места
+// запросом Range; если файл за это время изменился (другой ETag), возвращается ErrConflict
+func (c *Client) Download(ctx context.Context, name string, w io.Writer) (*models.FileInfo, error) {
+	body, info, err := c.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	out := &countingWriter{writer: w}
+	for attempt := 0; ; attempt++ {
+		_, err = io.Copy(out, body)
+		_ = body.Close()
+		if err == nil || out.err != nil || ctx.Err() != nil || attempt >= c.retries {
+			break
+		}
+		if errWait := c.wait(ctx, attempt+1, err); errWait != nil {
+			return nil, errWait
+		}
+		body, err = c.resume(ctx, name, info.ETag, out.count)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	return info, nil
+}
+
+// resume - продолжение скачивания с offset, только если файл не изменился
+func (c *Client) resume(ctx context.Context, name, etag string, offset int64) (io.ReadCloser, error) {
+	req := &request{
+		method: http.MethodGet,
+		path:   "/api/v2/files/" + filePath(name),
+		header: http.Header{"Range": {"bytes=" + strconv.FormatInt(offset, 10) + "-"}},
+	}
+	if etag != "" {
+		req.header.Set("If-Match", etag)
+	}
+	resp, err := c.do(ctx, req)
+	if errors.Is(err, ErrConflict) || (err == nil && resp.Header.Get("ETag") != etag) {
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return nil, fmt.Errorf("file %s changed during download: %w", name, ErrConflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return skipTo(resp, offset, 0)
+}
+
+// Delete - удаление файла (DELETE /api/v2/files/{path})
+func (c *Client) Delete(ctx context.Context, name string) error {
+	return c.call(ctx, &request{method: http.MethodDelete, path: "/api/v2/files/" + filePath(name)}, nil)
+}
+
+// Copy - копия файла внутри хранилища (POST /api/v2/files/{path}:copy)
+func (c *Client) Copy(ctx context.Context, name, destination string) (*models.FileInfo, error) {
+	req, err := jsonRequest(http.MethodPost, "/api/v2/files/"+filePath(name)+":copy", nil,
+		models.CopyRequest{Destination: destination})
+	if err != nil {
+		return nil, err
+	}
+	info := &models.FileInfo{}
+	if err = c.call(ctx, req, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// fileInfo - метаданные файла из заголовков ответа GET/HEAD
+func fileInfo(name string, resp *http.Response) *models.FileInfo {
+	info := &models.FileInfo{
+		Name:        name,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+		Sha256:      resp.Header.Get(checksumHeader),
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = modified.UTC().Format(time.RFC3339)
+	}
+	// для части файла полный размер - в Content-Range: bytes 0-99/1234
+	if resp.StatusCode == http.StatusPartialContent {
+		var start, end, total int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total); err == nil {
+			info.Size = total
+		}
+	}
+	return info
+}
+
+// countingWriter - считает записанное, чтобы продолжить скачивание, и отличает ошибку записи от ошибки сети
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+	err    error
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.writer.Write(b)
+	w.count += int64(n)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUploadRetriesSeekableBody(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "hello" {
+			t.Errorf("unexpected body %q", body)
+		}
+		if r.Header.Get(checksumHeader) != "abc" || r.Header.Get("Content-Type") != "text/plain" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		if calls.Add(1) == 1 {
+			writeError(w, http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(models.FileInfo{Name: "docs/a b.txt", Size: int64(len(body))})
+	})
+	info, err := c.Upload(context.Background(), "docs/a b.txt", bytes.NewReader([]byte("hello")),
+		&UploadOptions{ContentType: "text/plain", Sha256: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != 5 || calls.Load() != 2 {
+		t.Fatalf("unexpected result %+v after %d calls", info, calls.Load())
+	}
+}
+
+func TestUploadDoesNotRetryStream(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = io.Copy(io.Discard, r.Body)
+		writeError(w, http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable)
+	})
+	// io.MultiReader скрывает Seek: тело можно прочитать только один раз
+	_, err := c.Upload(context.Background(), "a.txt", io.MultiReader(strings.NewReader("hello")), nil)
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("expected service_unavailable, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestUploadEscapesPath(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v2/files/docs/a%20b%3F.txt" {
+			t.Errorf("unexpected path %s", r.URL.EscapedPath())
+		}
+		_ = json.NewEncoder(w).Encode(models.FileInfo{})
+	})
+	if _, err := c.Upload(context.Background(), "docs/a b?.txt", strings.NewReader("x"), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadResumesAfterBrokenConnection(t *testing.T) {
+	content := strings.Repeat("0123456789", 100000)
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if calls.Add(1) == 1 {
+			// первый ответ обрывается на середине
+			w.Header().Set("Content-Length", "1000000")
+			_, _ = w.Write([]byte(content[:300000]))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		if r.Header.Get("If-Match") != `"v1"` || r.Header.Get("Range") != "bytes=300000-" {
+			t.Errorf("unexpected resume headers %v", r.Header)
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	})
+	var out bytes.Buffer
+	info, err := c.Download(context.Background(), "big.bin", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != content || info.ETag != `"v1"` || calls.Load() != 2 {
+		t.Fatalf("downloaded %d bytes after %d calls", out.Len(), calls.Load())
+	}
+}
+
+func TestDownloadFailsIfFileChanged(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", "100")
+			_, _ = w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(strings.Repeat("x", 100)))
+	})
+	_, err := c.Download(context.Background(), "a.txt", io.Discard)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+}
+
+func TestUploadFilesMultipart(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("on_conflict") != "fail" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := models.FileResponse{Status: http.StatusConflict}
+		for {
+			part, errPart := reader.NextPart()
+			if errPart != nil {
+				break
+			}
+			data, _ := io.ReadAll(part)
+			status := models.UploadCreated
+			if part.FileName() == "exists.txt" {
+				status = models.UploadConflict
+			}
+			response.Files = append(response.Files, models.UploadResult{Name: part.FileName() + ":" + string(data),
+				Status: status})
+		}
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(response)
+	})
+	result, err := c.UploadFiles(context.Background(), []FormFile{
+		{Name: "new.txt", Reader: strings.NewReader("one")},
+		{Name: "exists.txt", Reader: strings.NewReader("two")},
+	}, "fail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 2 || result.Files[0].Name != "new.txt:one" || result.Files[1].Status != models.UploadConflict {
+		t.Fatalf("unexpected result %+v", result.Files)
+	}
+}
+
+func TestSignedUploadHasNoKey(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("api") || r.Header.Get("Authorization") != "" || r.Header.Get(checksumHeader) != "abc" ||
+			r.ContentLength != 5 {
+			t.Errorf("unexpected signed request %s %v", r.URL, r.Header)
+		}
+		_ = json.NewEncoder(w).Encode(models.FileInfo{Name: "backup/a.txt"})
+	})
+	upload := models.SyncUpload{
+		Path:    "a.txt",
+		Method:  http.MethodPut,
+		Url:     c.baseURL + "/upload/token",
+		Headers: map[string]string{checksumHeader: "abc"},
+	}
+	if _, err := c.UploadSigned(context.Background(), upload, strings.NewReader("hello"), 5); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenRangeWithoutServerSupport(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// сервер отдает файл целиком, как при хранилище без Seek
+		_, _ = w.Write([]byte("0123456789"))
+	})
+	body, _, err := c.OpenRange(context.Background(), "a.txt", 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = body.Close() }()
+	data, _ := io.ReadAll(body)
+	if string(data) != "3456" {
+		t.Fatalf("unexpected range %q", data)
+	}
+}
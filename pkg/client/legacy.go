@@ -0,0 +1,120 @@
+package client
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+// Методы API v1 (/client/api/v1). Для новых сервисов удобнее методы v2 из files.go
+
+// FormFile - файл для UploadFiles
+type FormFile struct {
+	Name   string // имя файла в хранилище
+	Reader io.Reader
+}
+
+// FileList - все файлы хранилища в формате веб-интерфейса (GET /client/api/v1/get-files-list)
+func (c *Client) FileList(ctx context.Context) ([]models.FileWebResponse, error) {
+	var files []models.FileWebResponse
+	if err := c.call(ctx, &request{method: http.MethodGet, path: "/client/api/v1/get-files-list"}, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// GetFile - скачивание файла в w (GET /client/api/v1/get-file). Без Range: после обрыва файл скачивается заново,
+// поэтому для больших файлов лучше Download
+func (c *Client) GetFile(ctx context.Context, name string, w io.Writer) error {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		path:   "/client/api/v1/get-file",
+		query:  url.Values{"filename": {name}},
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// UploadFiles - загрузка нескольких файлов одной multipart-формой (POST /client/api/v1/upload-files).
+// onConflict - overwrite, rename или fail, пусто - overwrite. Итог по каждому файлу - в FileResponse.Files;
+// если часть файлов пропущена (conflict, locked), сервер отвечает 409, а метод возвращает ответ без ошибки.
+// Форма передается потоком и не повторяется
+func (c *Client) UploadFiles(ctx context.Context, files []FormFile, onConflict string) (*models.FileResponse, error) {
+	query := url.Values{}
+	if onConflict != "" {
+		query.Set("on_conflict", onConflict)
+	}
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		for _, file := range files {
+			part, err := form.CreateFormFile("file", file.Name)
+			if err == nil {
+				_, err = io.Copy(part, file.Reader)
+			}
+			if err != nil {
+				_ = writer.CloseWithError(err)
+				return
+			}
+		}
+		_ = writer.CloseWithError(form.Close())
+	}()
+	defer func() { _ = body.Close() }()
+
+	resp, err := c.send(ctx, &request{
+		method: http.MethodPost,
+		path:   "/client/api/v1/upload-files",
+		query:  query,
+		header: http.Header{"Content-Type": {form.FormDataContentType()}},
+		body:   func() (io.Reader, error) { return body, nil },
+		size:   -1,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	// 409 - форма обработана, но часть файлов пропущена: тело - тот же FileResponse
+	if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode != http.StatusConflict {
+		return nil, decodeError(resp)
+	}
+	result := &models.FileResponse{}
+	if err = decode(resp, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteFile - удаление файла (DELETE /client/api/v1/delete-file), ответ - обновленный список файлов
+func (c *Client) DeleteFile(ctx context.Context, name string) (*models.FileResponse, error) {
+	return c.fileResponse(ctx, &request{
+		method: http.MethodDelete,
+		path:   "/client/api/v1/delete-file",
+		query:  url.Values{"filename": {name}},
+	})
+}
+
+// RenameFile - переименование файла (PATCH /client/api/v1/rename-file), ответ - обновленный список файлов
+func (c *Client) RenameFile(ctx context.Context, name, newName string) (*models.FileResponse, error) {
+	return c.fileResponse(ctx, &request{
+		method: http.MethodPatch,
+		path:   "/client/api/v1/rename-file",
+		query:  url.Values{"filename": {name}, "new_name": {newName}},
+	})
+}
+
+func (c *Client) fileResponse(ctx context.Context, req *request) (*models.FileResponse, error) {
+	result := &models.FileResponse{}
+	if err := c.call(ctx, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
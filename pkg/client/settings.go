@@ -0,0 +1,86 @@
+package client
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// LifecycleRules - правила жизненного цикла хранилища (GET /client/api/v1/lifecycle-rules)
+func (c *Client) LifecycleRules(ctx context.Context) ([]models.LifecycleRule, error) {
+	var rules []models.LifecycleRule
+	if err := c.call(ctx, &request{method: http.MethodGet, path: "/client/api/v1/lifecycle-rules"}, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CreateLifecycleRule - новое правило (POST /client/api/v1/lifecycle-rules), Id в rule игнорируется
+func (c *Client) CreateLifecycleRule(ctx context.Context, rule models.LifecycleRule) (*models.LifecycleRule, error) {
+	return c.lifecycleRule(ctx, http.MethodPost, nil, rule)
+}
+
+// UpdateLifecycleRule - замена правила rule.Id (PUT /client/api/v1/lifecycle-rules)
+func (c *Client) UpdateLifecycleRule(ctx context.Context, rule models.LifecycleRule) (*models.LifecycleRule, error) {
+	return c.lifecycleRule(ctx, http.MethodPut, url.Values{"id": {itoa(rule.Id)}}, rule)
+}
+
+func (c *Client) lifecycleRule(ctx context.Context, method string, query url.Values,
+	rule models.LifecycleRule) (*models.LifecycleRule, error) {
+	req, err := jsonRequest(method, "/client/api/v1/lifecycle-rules", query, rule)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.LifecycleRule{}
+	if err = c.call(ctx, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteLifecycleRule - удаление правила (DELETE /client/api/v1/lifecycle-rules)
+func (c *Client) DeleteLifecycleRule(ctx context.Context, id int) error {
+	return c.call(ctx, &request{
+		method: http.MethodDelete,
+		path:   "/client/api/v1/lifecycle-rules",
+		query:  url.Values{"id": {itoa(id)}},
+	}, nil)
+}
+
+// Retention - WORM-защита файла (GET /client/api/v1/retention)
+func (c *Client) Retention(ctx context.Context, name string) (*models.ObjectProtection, error) {
+	return c.protection(ctx, &request{
+		method: http.MethodGet,
+		path:   "/client/api/v1/retention",
+		query:  url.Values{"filename": {name}},
+	})
+}
+
+// SetRetention - срок хранения файла (PUT /client/api/v1/retention).
+// Хранилище без object locking отвечает ErrLockingNotSupported
+func (c *Client) SetRetention(ctx context.Context, name string, retention models.RetentionRequest) (*models.ObjectProtection, error) {
+	req, err := jsonRequest(http.MethodPut, "/client/api/v1/retention", url.Values{"filename": {name}}, retention)
+	if err != nil {
+		return nil, err
+	}
+	return c.protection(ctx, req)
+}
+
+// SetLegalHold - включение и выключение legal hold (PUT /client/api/v1/legal-hold)
+func (c *Client) SetLegalHold(ctx context.Context, name string, enabled bool) (*models.ObjectProtection, error) {
+	req, err := jsonRequest(http.MethodPut, "/client/api/v1/legal-hold", url.Values{"filename": {name}},
+		models.LegalHoldRequest{Enabled: enabled})
+	if err != nil {
+		return nil, err
+	}
+	return c.protection(ctx, req)
+}
+
+func (c *Client) protection(ctx context.Context, req *request) (*models.ObjectProtection, error) {
+	result := &models.ObjectProtection{}
+	if err := c.call(ctx, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"CloudStorageProject-FileServer/pkg/models"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Sync - сверка манифеста локального каталога с хранилищем (POST /api/v2/sync).
+// С request.Presign каждый файл из Upload получает ссылку для UploadSigned
+func (c *Client) Sync(ctx context.Context, request models.SyncRequest) (*models.SyncResponse, error) {
+	req, err := jsonRequest(http.MethodPost, "/api/v2/sync", nil, request)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.SyncResponse{}
+	if err = c.call(ctx, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UploadSigned - загрузка по подписанной ссылке из Sync (PUT /upload/{token}), ключ не нужен.
+// size должен совпадать с размером из манифеста. Повторяется так же, как Upload
+func (c *Client) UploadSigned(ctx context.Context, upload models.SyncUpload, r io.Reader, size int64) (*models.FileInfo, error) {
+	method := upload.Method
+	if method == "" {
+		method = http.MethodPut
+	}
+	req := &request{method: method, path: upload.Url, header: http.Header{}, body: readerBody(r), size: size}
+	for key, value := range upload.Headers {
+		req.header.Set(key, value)
+	}
+	info := &models.FileInfo{}
+	if err := c.call(ctx, req, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Share - ссылка на скачивание файла без ключа (POST /client/api/v1/share).
+// expiresIn = 0 - срок по умолчанию (сутки), не больше 7 дней
+func (c *Client) Share(ctx context.Context, name string, expiresIn time.Duration) (*models.ShareLink, error) {
+	query := url.Values{"filename": {name}}
+	if expiresIn > 0 {
+		query.Set("expires_in", strconv.Itoa(int(expiresIn.Seconds())))
+	}
+	link := &models.ShareLink{}
+	if err := c.call(ctx, &request{method: http.MethodPost, path: "/client/api/v1/share", query: query}, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// DownloadShared - скачивание по ссылке из Share в w (GET /download/{token})
+func (c *Client) DownloadShared(ctx context.Context, link string, w io.Writer) error {
+	resp, err := c.do(ctx, &request{method: http.MethodGet, path: link})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if _, err = io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download shared file: %w", err)
+	}
+	return nil
+}
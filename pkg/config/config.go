@@ -14,6 +14,10 @@ type Config struct {
 	// CPU
 	NumCPU int `env:"NUM_CPU" env-default:"4"`
 
+	// Storage - драйвер хранилища: minio, local или memory
+	StorageDriver   string `env:"STORAGE_DRIVER" env-default:"minio"`
+	StorageLocalDir string `env:"STORAGE_LOCAL_DIR" env-default:"./server_data/files"`
+
 	// MinIO
 	MinIOEndpoint string `env:"MINIO_ENDPOINT" env-default:"minio:9000"`
 	MinIOBucket   string `env:"MINIO_EXAMPLE_BUCKET" env-default:"test"`
@@ -21,6 +25,21 @@ type Config struct {
 	MinIOPassword string `env:"MINIO_ROOT_PASSWORD" env-default:"password"`
 	MinIOUseSSL   bool   `env:"MINIO_USER_SSL" env-default:"false"`
 
+	// Bucket - настройки, с которыми создается хранилище нового пользователя
+	BucketObjectLocking bool   `env:"BUCKET_OBJECT_LOCKING" env-default:"true"`
+	BucketVersioning    bool   `env:"BUCKET_VERSIONING" env-default:"true"`
+	BucketPolicy        string `env:"BUCKET_POLICY" env-default:"private"`
+	BucketQuotaMB       int64  `env:"BUCKET_QUOTA_MB" env-default:"0"`
+
+	// Replica - вторичное S3-совместимое хранилище, репликация включается если задан endpoint
+	ReplicaEndpoint     string `env:"REPLICA_ENDPOINT" env-default:""`
+	ReplicaAccessKey    string `env:"REPLICA_ACCESS_KEY" env-default:""`
+	ReplicaSecretKey    string `env:"REPLICA_SECRET_KEY" env-default:""`
+	ReplicaUseSSL       bool   `env:"REPLICA_USE_SSL" env-default:"false"`
+	ReplicaWorkers      int    `env:"REPLICA_WORKERS" env-default:"4"`
+	ReplicaQueueSize    int    `env:"REPLICA_QUEUE_SIZE" env-default:"10000"`
+	ReplicaReadFailover bool   `env:"REPLICA_READ_FAILOVER" env-default:"false"`
+
 	// Server
 	ServerPort string `env:"SERVER_PORT" env-default:"11682"`
 	ServerIP   string `env:"SERVER_IP" env-default:"0.0.0.0"`
@@ -49,10 +68,74 @@ type Config struct {
 
 	// Logging
 	LogLevel string `env:"LOG_LEVEL" env-default:"INFO"`
+
+	// Lifecycle - как часто (в минутах) фоновый обходчик применяет правила жизненного цикла
+	LifecycleSweepInterval int `env:"LIFECYCLE_SWEEP_INTERVAL" env-default:"60"`
+
+	// Upload - прием файлов: до порога файл держится в памяти, дальше идет потоком в хранилище
+	// (или во временный файл, если включен UPLOAD_SPILL_TO_DISK)
+	UploadSpillThresholdMB int64  `env:"UPLOAD_SPILL_THRESHOLD_MB" env-default:"8"`
+	UploadSpillToDisk      bool   `env:"UPLOAD_SPILL_TO_DISK" env-default:"false"`
+	UploadTempDir          string `env:"UPLOAD_TEMP_DIR" env-default:""`
+	UploadPartSizeMB       uint64 `env:"UPLOAD_PART_SIZE_MB" env-default:"16"`
+
+	// Fetch - загрузка файлов по URL: лимиты и сети, в которые разрешено ходить несмотря на защиту от SSRF
+	FetchMaxSizeMB       int64  `env:"FETCH_MAX_SIZE_MB" env-default:"1024"`
+	FetchTimeout         int    `env:"FETCH_TIMEOUT" env-default:"300"`
+	FetchConcurrency     int    `env:"FETCH_CONCURRENCY" env-default:"4"`
+	FetchQueueSize       int    `env:"FETCH_QUEUE_SIZE" env-default:"100"`
+	FetchAllowedNetworks string `env:"FETCH_ALLOWED_NETWORKS" env-default:""`
+
+	// Changes - журнал изменений для клиентов синхронизации: сколько часов и сколько записей на аккаунт хранить
+	ChangesRetentionHours int `env:"CHANGES_RETENTION_HOURS" env-default:"168"`
+	ChangesMaxPerAccount  int `env:"CHANGES_MAX_PER_ACCOUNT" env-default:"100000"`
+
+	// Presign - секрет подписи ссылок на загрузку и скачивание без API-ключа; пустой - случайный на каждый запуск
+	PresignSecret string `env:"PRESIGN_SECRET" env-default:""`
+
+	// S3 - S3-совместимый шлюз на отдельном порту, выключен, если порт не задан.
+	// Секрет нужен для вывода ключей доступа аккаунтов, без него шлюз не запускается
+	S3GatewayPort   string `env:"S3_GATEWAY_PORT" env-default:""`
+	S3GatewaySecret string `env:"S3_GATEWAY_SECRET" env-default:""`
+	S3GatewayRegion string `env:"S3_GATEWAY_REGION" env-default:"us-east-1"`
+
+	// SFTP - вход по SFTP для партнеров без HTTP-клиента, выключен, если порт не задан.
+	// Ключ хоста создается при первом запуске; пустой путь - новый ключ при каждом запуске
+	SFTPPort    string `env:"SFTP_PORT" env-default:""`
+	SFTPHostKey string `env:"SFTP_HOST_KEY" env-default:""`
+
+	// GRPC - gRPC API для внутренних сервисов, выключен, если порт не задан
+	GRPCPort string `env:"GRPC_PORT" env-default:""`
+
+	// Admin - токен API администратора /admin/api/v1, передается как Authorization: Bearer.
+	// Пустой - API администратора выключен
+	AdminToken string `env:"ADMIN_TOKEN" env-default:""`
+
+	// APIKeyInQuery - принимать ключ из параметра ?api= наравне с заголовками Authorization: Bearer
+	// и X-API-Key. Ключ в адресе оседает в истории браузера, Referer и логах прокси
+	APIKeyInQuery bool `env:"API_KEY_IN_QUERY" env-default:"true"`
 }
 
 func Load(envPath string) (*Config, error) {
-	cfg := &Config{}
+	cfg := &Config{
+		StorageDriver:          "minio",
+		StorageLocalDir:        "./server_data/files",
+		BucketObjectLocking:    true,
+		BucketVersioning:       true,
+		BucketPolicy:           "private",
+		ReplicaWorkers:         4,
+		ReplicaQueueSize:       10000,
+		LifecycleSweepInterval: 60,
+		UploadSpillThresholdMB: 8,
+		UploadPartSizeMB:       16,
+		FetchMaxSizeMB:         1024,
+		FetchTimeout:           300,
+		FetchConcurrency:       4,
+		FetchQueueSize:         100,
+		ChangesRetentionHours:  168,
+		ChangesMaxPerAccount:   100000,
+		S3GatewayRegion:        "us-east-1",
+	}
 
 	if err := godotenv.Load(envPath); err != nil {
 		return nil, fmt.Errorf("error loading .env file: %w", err)
@@ -73,6 +156,14 @@ func (c *Config) loadFromEnv() error {
 		}
 	}
 
+	// Storage
+	if val := os.Getenv("STORAGE_DRIVER"); val != "" {
+		c.StorageDriver = val
+	}
+	if val := os.Getenv("STORAGE_LOCAL_DIR"); val != "" {
+		c.StorageLocalDir = val
+	}
+
 	// MinIO
 	if val := os.Getenv("MINIO_ENDPOINT"); val != "" {
 		c.MinIOEndpoint = val
@@ -90,6 +181,49 @@ func (c *Config) loadFromEnv() error {
 		c.MinIOUseSSL = val == "true" || val == "1" || val == "yes"
 	}
 
+	// Bucket
+	if val := os.Getenv("BUCKET_OBJECT_LOCKING"); val != "" {
+		c.BucketObjectLocking = val == "true" || val == "1" || val == "yes"
+	}
+	if val := os.Getenv("BUCKET_VERSIONING"); val != "" {
+		c.BucketVersioning = val == "true" || val == "1" || val == "yes"
+	}
+	if val := os.Getenv("BUCKET_POLICY"); val != "" {
+		c.BucketPolicy = val
+	}
+	if val := os.Getenv("BUCKET_QUOTA_MB"); val != "" {
+		if num, err := strconv.ParseInt(val, 10, 64); err == nil && num >= 0 {
+			c.BucketQuotaMB = num
+		}
+	}
+
+	// Replica
+	if val := os.Getenv("REPLICA_ENDPOINT"); val != "" {
+		c.ReplicaEndpoint = val
+	}
+	if val := os.Getenv("REPLICA_ACCESS_KEY"); val != "" {
+		c.ReplicaAccessKey = val
+	}
+	if val := os.Getenv("REPLICA_SECRET_KEY"); val != "" {
+		c.ReplicaSecretKey = val
+	}
+	if val := os.Getenv("REPLICA_USE_SSL"); val != "" {
+		c.ReplicaUseSSL = val == "true" || val == "1" || val == "yes"
+	}
+	if val := os.Getenv("REPLICA_WORKERS"); val != "" {
+		if num, err := strconv.Atoi(val); err == nil && num > 0 {
+			c.ReplicaWorkers = num
+		}
+	}
+	if val := os.Getenv("REPLICA_QUEUE_SIZE"); val != "" {
+		if num, err := strconv.Atoi(val); err == nil && num > 0 {
+			c.ReplicaQueueSize = num
+		}
+	}
+	if val := os.Getenv("REPLICA_READ_FAILOVER"); val != "" {
+		c.ReplicaReadFailover = val == "true" || val == "1" || val == "yes"
+	}
+
 	// Server
 	if val := os.Getenv("SERVER_PORT"); val != "" {
 		c.ServerPort = val
@@ -155,6 +289,106 @@ func (c *Config) loadFromEnv() error {
 		c.LogLevel = val
 	}
 
+	// Lifecycle
+	if val := os.Getenv("LIFECYCLE_SWEEP_INTERVAL"); val != "" {
+		if minutes, err := strconv.Atoi(val); err == nil && minutes > 0 {
+			c.LifecycleSweepInterval = minutes
+		}
+	}
+
+	// Upload
+	if val := os.Getenv("UPLOAD_SPILL_THRESHOLD_MB"); val != "" {
+		if num, err := strconv.ParseInt(val, 10, 64); err == nil && num >= 0 {
+			c.UploadSpillThresholdMB = num
+		}
+	}
+	if val := os.Getenv("UPLOAD_SPILL_TO_DISK"); val != "" {
+		c.UploadSpillToDisk = val == "true" || val == "1" || val == "yes"
+	}
+	if val := os.Getenv("UPLOAD_TEMP_DIR"); val != "" {
+		c.UploadTempDir = val
+	}
+	if val := os.Getenv("UPLOAD_PART_SIZE_MB"); val != "" {
+		// у S3 минимальный размер части 5 МБ
+		if num, err := strconv.ParseUint(val, 10, 64); err == nil && num >= 5 {
+			c.UploadPartSizeMB = num
+		}
+	}
+
+	// Fetch
+	if val := os.Getenv("FETCH_MAX_SIZE_MB"); val != "" {
+		if num, err := strconv.ParseInt(val, 10, 64); err == nil && num > 0 {
+			c.FetchMaxSizeMB = num
+		}
+	}
+	if val := os.Getenv("FETCH_TIMEOUT"); val != "" {
+		if seconds, err := strconv.Atoi(val); err == nil && seconds > 0 {
+			c.FetchTimeout = seconds
+		}
+	}
+	if val := os.Getenv("FETCH_CONCURRENCY"); val != "" {
+		if num, err := strconv.Atoi(val); err == nil && num > 0 {
+			c.FetchConcurrency = num
+		}
+	}
+	if val := os.Getenv("FETCH_QUEUE_SIZE"); val != "" {
+		if num, err := strconv.Atoi(val); err == nil && num > 0 {
+			c.FetchQueueSize = num
+		}
+	}
+	if val := os.Getenv("FETCH_ALLOWED_NETWORKS"); val != "" {
+		c.FetchAllowedNetworks = val
+	}
+
+	// Changes
+	if val := os.Getenv("CHANGES_RETENTION_HOURS"); val != "" {
+		if hours, err := strconv.Atoi(val); err == nil && hours > 0 {
+			c.ChangesRetentionHours = hours
+		}
+	}
+	if val := os.Getenv("CHANGES_MAX_PER_ACCOUNT"); val != "" {
+		if num, err := strconv.Atoi(val); err == nil && num > 0 {
+			c.ChangesMaxPerAccount = num
+		}
+	}
+
+	// Presign
+	if val := os.Getenv("PRESIGN_SECRET"); val != "" {
+		c.PresignSecret = val
+	}
+
+	// S3
+	if val := os.Getenv("S3_GATEWAY_PORT"); val != "" {
+		c.S3GatewayPort = val
+	}
+	if val := os.Getenv("S3_GATEWAY_SECRET"); val != "" {
+		c.S3GatewaySecret = val
+	}
+	if val := os.Getenv("S3_GATEWAY_REGION"); val != "" {
+		c.S3GatewayRegion = val
+	}
+
+	// SFTP
+	if val := os.Getenv("SFTP_PORT"); val != "" {
+		c.SFTPPort = val
+	}
+	if val := os.Getenv("SFTP_HOST_KEY"); val != "" {
+		c.SFTPHostKey = val
+	}
+
+	// gRPC
+	if val := os.Getenv("GRPC_PORT"); val != "" {
+		c.GRPCPort = val
+	}
+
+	// Admin
+	if val := os.Getenv("ADMIN_TOKEN"); val != "" {
+		c.AdminToken = val
+	}
+	if val := os.Getenv("API_KEY_IN_QUERY"); val != "" {
+		c.APIKeyInQuery = val == "true" || val == "1" || val == "yes"
+	}
+
 	return nil
 }
 
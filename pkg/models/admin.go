@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Account - аккаунт в ответах API администратора. Значения ключа здесь нет: его показывают
+// только один раз, при создании или смене. По префиксу ключ можно узнать, не раскрывая его
+type Account struct {
+	Id int `json:"id" example:"12"`
+	// KeyPrefix - открытая часть ключа; пусто у ключей старого формата
+	KeyPrefix   string    `json:"key_prefix" example:"3f1c2b0a9d8e"`
+	Email       string    `json:"email" example:"user@example.com"`
+	Status      string    `json:"status" example:"active" enums:"active,suspended,revoked"`
+	Permissions string    `json:"permissions" example:"read,write"`
+	QuotaMB     int64     `json:"quota_mb" example:"10240"`
+	StorageId   string    `json:"storage_id" example:"u-3f1c2b0a9d8e4f7a8b6c5d4e3f2a1b0c"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLogin   time.Time `json:"last_login"`
+}
+
+func NewAccount(account *APIPGS) Account {
+	return Account{
+		Id:          account.Id,
+		KeyPrefix:   account.KeyPrefix,
+		Email:       account.Email,
+		Status:      account.Status,
+		Permissions: account.Permissions,
+		QuotaMB:     account.QuotaMB,
+		StorageId:   account.StorageId,
+		CreatedAt:   account.CreatedAt,
+		LastLogin:   account.LastLogin,
+	}
+}
+
+// AccountKey - аккаунт вместе с ключом, ответ на создание и смену ключа
+type AccountKey struct {
+	Account
+	Key string `json:"key" example:"3f1c2b0a9d8e.9c4f0d3e5b2a41f7a8c6e1d0b3f5a7c9e2d4f6a8b0c1e3d5f7a9c2e4b6d8f0a1"`
+	// Warnings - аккаунт создан, но не все настройки применены, например квота хранилища
+	Warnings []string `json:"warnings,omitempty" example:"storage driver does not support quotas"`
+}
+
+// CreateAccountRequest - тело POST /admin/api/v1/accounts
+type CreateAccountRequest struct {
+	Email string `json:"email" example:"user@example.com"`
+	// Permissions - "read" или "read,write"; пусто - read,write
+	Permissions string `json:"permissions,omitempty" example:"read,write"`
+	// QuotaMB - квота хранилища, 0 - BUCKET_QUOTA_MB
+	QuotaMB int64 `json:"quota_mb,omitempty" example:"10240"`
+}
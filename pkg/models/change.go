@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Типы изменений в журнале хранилища
+const (
+	ChangeCreate    = "create"
+	ChangeOverwrite = "overwrite"
+	ChangeDelete    = "delete"
+	ChangeMove      = "move"
+)
+
+// Change - запись журнала изменений хранилища. Id растет монотонно и служит курсором
+type Change struct {
+	Id      int64     `json:"id" example:"1042"`
+	Bucket  string    `json:"-"`
+	Type    string    `json:"type" enums:"create,overwrite,delete,move" example:"move"`
+	Path    string    `json:"path" example:"photos/alohadance.png"`
+	NewPath string    `json:"new_path,omitempty" example:"photos/2024/alohadance.png"`
+	Size    int64     `json:"size,omitempty" example:"1024"`
+	ETag    string    `json:"etag,omitempty" example:"\"33a64df551425fcc55e4d42a148795d9\""`
+	Time    time.Time `json:"time"`
+}
+
+// ChangeFeed - ответ GET /changes: изменения после курсора и курсор для следующего запроса
+type ChangeFeed struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor" example:"1042"`
+	HasMore bool     `json:"has_more"`
+}
@@ -0,0 +1,40 @@
+package models
+
+// Машиночитаемые коды ошибок API (поле error.code). Клиенты опираются на код, а не на текст
+const (
+	ErrCodeBadRequest          = "bad_request"           // 400 некорректные параметры или тело запроса
+	ErrCodeInvalidName         = "invalid_name"          // 400 недопустимое имя файла
+	ErrCodeChecksumMismatch    = "checksum_mismatch"     // 400 sha256 не совпал с содержимым
+	ErrCodeAPIKeyRequired      = "api_key_required"      // 401 ключ не передан
+	ErrCodeAPIKeyInvalid       = "api_key_invalid"       // 401 ключ не найден
+	ErrCodeUnauthorized        = "unauthorized"          // 401 нет или неверный токен администратора
+	ErrCodeForbidden           = "forbidden"             // 403 доступ запрещен хранилищем
+	ErrCodeObjectLocked        = "object_locked"         // 403/409 файл под retention или legal hold
+	ErrCodeNotFound            = "not_found"             // 404 файл или ресурс не найден
+	ErrCodeStorageNotFound     = "storage_not_found"     // 404 хранилище пользователя не создано
+	ErrCodeMethodNotAllowed    = "method_not_allowed"    // 405
+	ErrCodeConflict            = "conflict"              // 409 конфликт с текущим состоянием
+	ErrCodeLockingNotSupported = "locking_not_supported" // 409 хранилище без object locking
+	ErrCodeCursorExpired       = "cursor_expired"        // 410 журнал изменений сжат, нужна полная синхронизация
+	ErrCodeTooLarge            = "too_large"             // 413 файл больше допустимого
+	ErrCodeQuotaExceeded       = "quota_exceeded"        // 507 квота хранилища исчерпана
+	ErrCodeInternal            = "internal"              // 500 внутренняя ошибка
+	ErrCodeStorageUnavailable  = "storage_unavailable"   // 503 MinIO/S3 недоступен
+	ErrCodeDatabaseUnavailable = "database_unavailable"  // 503 Postgres недоступен
+	ErrCodeCacheUnavailable    = "cache_unavailable"     // 503 Redis недоступен
+	ErrCodeServiceUnavailable  = "service_unavailable"   // 503 сервер останавливается
+	ErrCodeTimeout             = "timeout"               // 504 зависимость не ответила вовремя
+)
+
+// APIError - описание ошибки для клиента
+type APIError struct {
+	Code      string         `json:"code" example:"not_found" enums:"bad_request,invalid_name,checksum_mismatch,api_key_required,api_key_invalid,unauthorized,forbidden,object_locked,not_found,storage_not_found,method_not_allowed,conflict,locking_not_supported,cursor_expired,too_large,quota_exceeded,internal,storage_unavailable,database_unavailable,cache_unavailable,service_unavailable,timeout"`
+	Message   string         `json:"message" example:"file not found"`
+	RequestId string         `json:"request_id" example:"0f8e6c1a9b7d4e52"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// ErrorResponse - единый конверт ошибок всех обработчиков
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Типы событий хранилища, которые рассылаются через SSE
+const (
+	EventUpload = "upload"
+	EventDelete = "delete"
+	EventRename = "rename"
+)
+
+// StorageEvent - событие изменения файлов в хранилище пользователя
+type StorageEvent struct {
+	Type     string    `json:"type" example:"upload"`
+	FileName string    `json:"file_name" example:"alohadance.png"`
+	NewName  string    `json:"new_name,omitempty" example:"alohadance2.png"`
+	Time     time.Time `json:"time"`
+}
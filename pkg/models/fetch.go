@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Состояния задачи загрузки по URL
+const (
+	FetchQueued  = "queued"
+	FetchRunning = "running"
+	FetchDone    = "done"
+	FetchFailed  = "failed"
+)
+
+// FetchRequest - тело POST /upload-url: что скачать и куда положить
+type FetchRequest struct {
+	Url        string `json:"url" example:"https://example.com/files/report.pdf"`
+	Path       string `json:"path,omitempty" example:"docs/report.pdf"`
+	OnConflict string `json:"on_conflict,omitempty" enums:"overwrite,rename,fail" example:"rename"` // проверяется перед записью, одновременную загрузку того же имени не исключает
+}
+
+// FetchJob - задача загрузки файла по URL, хранится в redis и опрашивается клиентом
+type FetchJob struct {
+	Id         string    `json:"id" example:"5f0c3b8e2d9a4c7f"`
+	Url        string    `json:"url" example:"https://example.com/files/report.pdf"`
+	Path       string    `json:"path" example:"docs/report.pdf"` // после on_conflict=rename - новое имя
+	OnConflict string    `json:"on_conflict" example:"rename"`
+	Status     string    `json:"status" enums:"queued,running,done,failed" example:"running"`
+	BytesDone  int64     `json:"bytes_done" example:"1048576"`
+	BytesTotal int64     `json:"bytes_total" example:"4194304"` // -1, если сервер не прислал Content-Length
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
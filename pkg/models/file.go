@@ -5,13 +5,26 @@ import (
 	"time"
 )
 
-// FileInfo просто структура для сваггера, на minio.Object или его алиас жалуется
+// FileInfo - метаданные файла в JSON, те же, что HEAD отдает заголовками
 type FileInfo struct {
 	Name         string `json:"name" example:"alohadance.png"`
 	Size         int64  `json:"size" example:"1024"`
 	ContentType  string `json:"content_type" example:"image/png"`
 	LastModified string `json:"last_modified" example:"2024-01-01T12:00:00Z"`
 	ETag         string `json:"etag" example:"\"33a64df551425fcc55e4d42a148795d9f25f89d4\""`
+	Sha256       string `json:"sha256,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	IsDir        bool   `json:"is_dir,omitempty"`
+}
+
+// FileList - список файлов v2 API
+type FileList struct {
+	Prefix string     `json:"prefix" example:"photos/"`
+	Files  []FileInfo `json:"files"`
+}
+
+// CopyRequest - тело POST /api/v2/files/{path}:copy
+type CopyRequest struct {
+	Destination string `json:"destination" example:"photos/alohadance-copy.png"`
 }
 type FileMinio struct {
 	FileName    string
@@ -19,6 +32,7 @@ type FileMinio struct {
 	Reader      io.Reader // для больших файлов, для стриминга
 	ContentType string    // для стриминга
 	Size        int64
+	Checksum    string // sha256 в hex, если известен до загрузки
 }
 
 type FileWebResponse struct {
@@ -26,6 +40,7 @@ type FileWebResponse struct {
 	FileType    string `json:"file_type"`
 	LastModTime string `json:"create_date"`
 	FileSize    string `json:"file_size"`
+	ExpiresAt   string `json:"expires_at,omitempty"` // ближайшее срабатывание правила жизненного цикла
 }
 
 type FileResponse struct {
@@ -33,6 +48,28 @@ type FileResponse struct {
 	Message       string            `json:"message"`
 	NewFiles      []FileWebResponse `json:"new_files"`
 	UploadedFiles []string          `json:"uploaded_files"`
+	OnConflict    string            `json:"on_conflict,omitempty" example:"rename"`
+	Files         []UploadResult    `json:"files,omitempty"`
+}
+
+// Итог загрузки одного файла
+const (
+	UploadCreated     = "created"
+	UploadOverwritten = "overwritten"
+	UploadRenamed     = "renamed"
+	UploadConflict    = "conflict"
+	UploadLocked      = "locked"
+	UploadInvalidName = "invalid_name"
+	UploadFailed      = "failed"
+)
+
+// UploadResult - что стало с файлом из multipart-загрузки: name - имя от клиента,
+// stored_name - ключ, под которым файл сохранен (после нормализации или переименования)
+type UploadResult struct {
+	Name       string `json:"name" example:"alohadance.png"`
+	StoredName string `json:"stored_name,omitempty" example:"alohadance (1).png"`
+	Status     string `json:"status" enums:"created,overwritten,renamed,conflict,locked,invalid_name,failed" example:"renamed"`
+	Error      string `json:"error,omitempty"`
 }
 
 type HealthResponse struct {
@@ -41,3 +78,20 @@ type HealthResponse struct {
 	Service   string    `json:"service"`
 	Version   string    `json:"version"`
 }
+
+// CompletedPart - часть составной загрузки в запросе на ее завершение
+type CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// ObjectInfo - характеристики объекта в хранилище, общие для всех драйверов
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+	IsDir        bool   // "папка" при нерекурсивном списке
+	Checksum     string // sha256 в hex, пусто если при загрузке он не был известен
+}
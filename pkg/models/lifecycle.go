@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Действия правил жизненного цикла
+const (
+	LifecycleActionDelete = "delete"
+	LifecycleActionTrash  = "trash"
+)
+
+// TrashPrefix - "папка" в бакете пользователя, куда правила переносят файлы вместо удаления
+const TrashPrefix = ".trash/"
+
+// LifecycleRule - правило автоматического удаления/переноса в корзину файлов старше Days дней
+type LifecycleRule struct {
+	Id        int       `json:"id" example:"1"`
+	Bucket    string    `json:"-"`
+	Prefix    string    `json:"prefix" example:"exports/"`
+	TagKey    string    `json:"tag_key,omitempty" example:"temporary"`
+	TagValue  string    `json:"tag_value,omitempty" example:"true"`
+	Action    string    `json:"action" example:"delete"`
+	Days      int       `json:"days" example:"7"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Valid - проверка правила перед сохранением
+func (r *LifecycleRule) Valid() bool {
+	if r.Days <= 0 {
+		return false
+	}
+	if r.Action != LifecycleActionDelete && r.Action != LifecycleActionTrash {
+		return false
+	}
+	if r.TagKey == "" && r.TagValue != "" {
+		return false
+	}
+	// перенос в корзину того, что уже в корзине, дал бы ".trash/.trash/"
+	if r.Action == LifecycleActionTrash && strings.HasPrefix(r.Prefix, TrashPrefix) {
+		return false
+	}
+	return true
+}
+
+// HasTag - правило фильтрует файлы по тегу
+func (r *LifecycleRule) HasTag() bool {
+	return r.TagKey != ""
+}
+
+// Matches - попадает ли файл под правило. Файлы в корзине правила не трогают, кроме правил удаления,
+// явно настроенных на префикс корзины. Правила переноса в корзину их не трогают никогда
+func (r *LifecycleRule) Matches(key string, tags map[string]string) bool {
+	inTrash := strings.HasPrefix(key, TrashPrefix)
+	if inTrash && (r.Action == LifecycleActionTrash || !strings.HasPrefix(r.Prefix, TrashPrefix)) {
+		return false
+	}
+	if !strings.HasPrefix(key, r.Prefix) {
+		return false
+	}
+	if r.HasTag() {
+		value, ok := tags[r.TagKey]
+		if !ok || (r.TagValue != "" && value != r.TagValue) {
+			return false
+		}
+	}
+	return true
+}
+
+// ExpiresAt - когда правило сработает для файла с указанным временем изменения
+func (r *LifecycleRule) ExpiresAt(lastModified time.Time) time.Time {
+	return lastModified.Add(time.Duration(r.Days) * 24 * time.Hour)
+}
+
+// NextExpiry - ближайшее срабатывание среди всех подходящих правил, nil если правил нет
+func NextExpiry(rules []LifecycleRule, key string, lastModified time.Time, tags map[string]string) *time.Time {
+	var next *time.Time
+	for i := range rules {
+		if !rules[i].Matches(key, tags) {
+			continue
+		}
+		expires := rules[i].ExpiresAt(lastModified)
+		if next == nil || expires.Before(*next) {
+			next = &expires
+		}
+	}
+	return next
+}
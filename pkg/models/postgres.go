@@ -1,12 +1,123 @@
 package models
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Состояния ключа. Приостановленный ключ можно вернуть в работу, отозванный - нет
+const (
+	KeyStatusActive    = "active"
+	KeyStatusSuspended = "suspended"
+	KeyStatusRevoked   = "revoked"
+)
+
+// Права ключа. Ключ без записи может только читать и скачивать файлы
+const (
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+	// DefaultPermissions - права нового ключа
+	DefaultPermissions = PermissionRead + "," + PermissionWrite
+)
+
+// apiKeyPrefixLen - длина открытой части ключа вида prefix.secret
+const apiKeyPrefixLen = 12
 
 type APIPGS struct {
-	Id          int
-	KeyName     string
+	Id int
+	// KeyPrefix - открытая часть ключа prefix.secret, по ней ключ ищется в базе. У ключей старого формата пустая
+	KeyPrefix string
+	// KeyHash - HashAPIKey всего ключа; значение ключа нигде не хранится
+	KeyHash string
+	// Key - значение ключа, есть только у только что выданного ключа: после создания или смены
+	Key string
+	// StorageId - неизменяемое имя хранилища аккаунта, не зависит от значения ключа
+	StorageId   string
 	CloudAccess string
 	Email       string
 	CreatedAt   time.Time
 	LastLogin   time.Time
+	// Status - KeyStatusActive, KeyStatusSuspended или KeyStatusRevoked; вход возможен только с действующим ключом
+	Status string
+	// Permissions - права через запятую, например "read,write"
+	Permissions string
+	// QuotaMB - квота хранилища аккаунта; 0 - без отдельной квоты
+	QuotaMB int64
+}
+
+// HashAPIKey - хэш ключа для базы и кэша. Ключи случайные и длинные, поэтому медленный хэш не нужен
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix - открытая часть ключа prefix.secret; false - ключ старого формата, он ищется по хэшу
+func APIKeyPrefix(key string) (string, bool) {
+	prefix, secret, found := strings.Cut(key, ".")
+	if !found || secret == "" || len(prefix) != apiKeyPrefixLen {
+		return "", false
+	}
+	if _, err := hex.DecodeString(prefix); err != nil {
+		return "", false
+	}
+	return prefix, true
+}
+
+// LegacyStorage - хранилище названо значением ключа (аккаунт не перенесен cmd/migrate-storage)
+func (a *APIPGS) LegacyStorage() bool {
+	return HashAPIKey(a.StorageId) == a.KeyHash
+}
+
+// Active - ключ можно использовать для входа
+func (a *APIPGS) Active() bool {
+	return a.Status == KeyStatusActive
+}
+
+// Can - есть ли у ключа право permission
+func (a *APIPGS) Can(permission string) bool {
+	return slices.Contains(strings.Split(a.Permissions, ","), permission)
+}
+
+// CanWrite - ключ может загружать, изменять и удалять файлы
+func (a *APIPGS) CanWrite() bool {
+	return a.Can(PermissionWrite)
+}
+
+// NormalizePermissions - проверяет список прав и приводит его к виду, в котором он хранится в базе
+func NormalizePermissions(permissions string) (string, bool) {
+	var result []string
+	for _, permission := range strings.Split(permissions, ",") {
+		permission = strings.ToLower(strings.TrimSpace(permission))
+		if permission != PermissionRead && permission != PermissionWrite {
+			return "", false
+		}
+		if !slices.Contains(result, permission) {
+			result = append(result, permission)
+		}
+	}
+	// без чтения запись бессмысленна: клиенты не смогут даже посмотреть список файлов
+	if !slices.Contains(result, PermissionRead) {
+		return "", false
+	}
+	slices.Sort(result)
+	return strings.Join(result, ","), true
+}
+
+// AccountFilter - отбор аккаунтов в списке; пустые поля не ограничивают
+type AccountFilter struct {
+	Status string
+	// Email - подстрока адреса без учета регистра
+	Email string
+}
+
+// AccountUsage - занятое аккаунтом место
+type AccountUsage struct {
+	Account    *APIPGS
+	Files      int
+	Bytes      int64
+	TrashFiles int
+	TrashBytes int64
 }
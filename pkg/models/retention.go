@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ObjectProtection - WORM-защита файла: срок хранения (retention) и legal hold
+type ObjectProtection struct {
+	Mode        string     `json:"mode,omitempty" example:"COMPLIANCE"`
+	RetainUntil *time.Time `json:"retain_until,omitempty" example:"2030-01-01T00:00:00Z"`
+	LegalHold   bool       `json:"legal_hold" example:"false"`
+}
+
+// Protected - файл нельзя удалить или перезаписать в момент now
+func (p *ObjectProtection) Protected(now time.Time) bool {
+	if p == nil {
+		return false
+	}
+	if p.LegalHold {
+		return true
+	}
+	return p.Mode != "" && p.RetainUntil != nil && p.RetainUntil.After(now)
+}
+
+// RetentionRequest - тело запроса на установку срока хранения
+type RetentionRequest struct {
+	Mode        string    `json:"mode" example:"GOVERNANCE"`
+	RetainUntil time.Time `json:"retain_until" example:"2030-01-01T00:00:00Z"`
+}
+
+// LegalHoldRequest - тело запроса на включение/выключение legal hold
+type LegalHoldRequest struct {
+	Enabled bool `json:"enabled" example:"true"`
+}
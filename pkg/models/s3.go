@@ -0,0 +1,9 @@
+package models
+
+// S3Credentials - ключи S3-шлюза для хранилища аккаунта
+type S3Credentials struct {
+	AccessKey string `json:"access_key" example:"u-3f1c2b7a9d8e4f60a1b2c3d4e5f60718"`
+	SecretKey string `json:"secret_key" example:"Zk3v9QeT0bJmX1aR7pWc2LhN5sUyD8oGiKfE4tVq"`
+	Bucket    string `json:"bucket" example:"u-3f1c2b7a9d8e4f60a1b2c3d4e5f60718"`
+	Region    string `json:"region" example:"us-east-1"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// ShareLink - ссылка на скачивание файла без API-ключа
+type ShareLink struct {
+	FileName  string    `json:"file_name" example:"photos/alohadance.png"`
+	Url       string    `json:"url" example:"http://localhost:11682/download/7b2262223a22...e1f0"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
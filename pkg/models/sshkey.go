@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// SSHKey - публичный ключ для входа по SFTP
+type SSHKey struct {
+	Id          int       `json:"id" example:"1"`
+	Fingerprint string    `json:"fingerprint" example:"SHA256:ZsbWkbeK0dnAIoUm/uZAAZ3UsNmPjkx2RrH+6eRrV7U"`
+	Type        string    `json:"type" example:"ssh-ed25519"`
+	PublicKey   string    `json:"public_key" example:"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBeQk0Tq7Qb1Ge1Cq8Ei7oZ7X8bqT0v7oXyY9cKqgHkP"`
+	Comment     string    `json:"comment,omitempty" example:"partner@backup-host"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SSHKeyRequest - тело POST /ssh-keys: строка в формате authorized_keys
+type SSHKeyRequest struct {
+	PublicKey string `json:"public_key" example:"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBeQk0Tq7Qb1Ge1Cq8Ei7oZ7X8bqT0v7oXyY9cKqgHkP partner@backup-host"`
+}
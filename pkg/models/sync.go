@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Почему файл из манифеста нужно загрузить
+const (
+	SyncMissing         = "missing"          // файла нет на сервере
+	SyncSizeChanged     = "size"             // размер отличается
+	SyncChecksumChanged = "checksum"         // sha256 отличается
+	SyncChecksumUnknown = "checksum_unknown" // файл загружен без sha256, сравнить нельзя
+)
+
+// ManifestEntry - файл локального каталога клиента
+type ManifestEntry struct {
+	Path   string `json:"path" example:"photos/alohadance.png"`
+	Size   int64  `json:"size" example:"1024"`
+	Sha256 string `json:"sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+// SyncRequest - манифест каталога. Пути в манифесте и в ответе - относительно prefix
+type SyncRequest struct {
+	Prefix    string          `json:"prefix,omitempty" example:"backup/"`
+	Files     []ManifestEntry `json:"files"`
+	Presign   bool            `json:"presign,omitempty"`
+	ExpiresIn int             `json:"expires_in,omitempty" example:"3600"` // срок действия ссылок в секундах
+}
+
+// SyncUpload - файл, который нужно загрузить, и, если просили, подписанная ссылка для загрузки
+type SyncUpload struct {
+	Path      string            `json:"path" example:"photos/alohadance.png"`
+	Reason    string            `json:"reason" enums:"missing,size,checksum,checksum_unknown" example:"missing"`
+	Method    string            `json:"method,omitempty" example:"PUT"`
+	Url       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+}
+
+// SyncResponse - что загрузить, что удалить и что совпадает
+type SyncResponse struct {
+	Prefix    string       `json:"prefix" example:"backup/"`
+	Upload    []SyncUpload `json:"upload"`
+	Delete    []string     `json:"delete"`
+	Unchanged []string     `json:"unchanged"`
+}
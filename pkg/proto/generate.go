@@ -0,0 +1,4 @@
+// Package proto - описания gRPC API и сгенерированный по ним код
+package proto
+
+//go:generate sh -c "cd ../.. && buf generate"